@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/c8121/asset-storage/internal/config"
+	"github.com/c8121/asset-storage/internal/metadata"
+	metadata_db_entity "github.com/c8121/asset-storage/internal/metadata-db-entity"
+	mdsqlite "github.com/c8121/asset-storage/internal/metadata-sqlite"
+	"github.com/c8121/asset-storage/internal/storage"
+	"github.com/c8121/asset-storage/internal/util"
+)
+
+/*
+	Calculate additional digests (MD5, SHA-1, BLAKE3, see storage.DigestAlgorithms) of assets
+	added before digests were calculated at ingest.
+
+	Digests are written to meta-data JSON and database. The SHA-256 content hash is checked
+	while reading, assets with differing content are reported and not changed.
+*/
+
+func main() {
+
+	dryRun := flag.Bool("dry-run", false, "Only report assets without digests")
+
+	config.LoadDefault()
+
+	if !*dryRun {
+		mdsqlite.Open()
+		defer mdsqlite.Close()
+	}
+
+	read := 0
+	missing := 0
+	added := 0
+	failed := 0
+
+	err := filepath.WalkDir(config.AssetMetaDataBaseDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && path == config.AssetMetaDataBaseDir {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), metadata.MetaDataFileExtension) {
+			return nil
+		}
+
+		read++
+		meta, err := metadata.LoadIfExists(path)
+		if err != nil {
+			fmt.Printf("Failed '%s': %s\n", path, err)
+			failed++
+			return nil
+		}
+		if hasAllDigests(meta) {
+			return nil
+		}
+
+		fmt.Printf("%s\n", meta.Hash)
+		missing++
+		if *dryRun {
+			return nil
+		}
+
+		digests, err := computeDigests(meta.Hash)
+		if err == nil {
+			if meta, err = setDigests(meta.Hash, digests); err == nil {
+				err = metadata_db_entity.AddMetaData(meta)
+			}
+		}
+		if err != nil {
+			fmt.Printf("Failed '%s': %s\n", path, err)
+			failed++
+		} else {
+			added++
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("Failed '%s': %s\n", config.AssetMetaDataBaseDir, err)
+		failed++
+	}
+
+	if *dryRun {
+		fmt.Printf("\nRead %d meta-data files, %d without digests, %d failed\n", read, missing, failed)
+	} else {
+		fmt.Printf("\nRead %d meta-data files, added digests to %d, %d failed\n", read, added, failed)
+	}
+
+	if failed > 0 {
+		mdsqlite.Close()
+		os.Exit(1)
+	}
+}
+
+func hasAllDigests(meta *metadata.JsonAssetMetaData) bool {
+	for _, algorithm := range storage.DigestAlgorithms {
+		if meta.Digests[algorithm] == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// setDigests writes digests to meta-data (with history as metadata.SystemUser),
+// unless digests were added meanwhile
+func setDigests(hash string, digests map[string]string) (*metadata.JsonAssetMetaData, error) {
+
+	value, err := json.Marshal(digests)
+	if err != nil {
+		return nil, err
+	}
+
+	return metadata.UpdateMetaDataWith(hash, func(current *metadata.JsonAssetMetaData) map[string]json.RawMessage {
+		if hasAllDigests(current) {
+			return nil
+		}
+		return map[string]json.RawMessage{"Digests": value}
+	}, metadata.SystemUser)
+}
+
+// computeDigests reads stored content, returns DigestAlgorithms (without the content hash)
+func computeDigests(hash string) (map[string]string, error) {
+
+	reader, err := storage.Open(hash)
+	if err != nil {
+		return nil, err
+	}
+	defer util.CloseOrLog(reader)
+
+	digests, err := storage.ComputeDigests(reader)
+	if err != nil {
+		return nil, err
+	}
+	if digests[storage.DigestSHA256] != hash {
+		return nil, fmt.Errorf("content hash is %s", digests[storage.DigestSHA256])
+	}
+	delete(digests, storage.DigestSHA256)
+	return digests, nil
+}
@@ -9,11 +9,15 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/c8121/asset-storage/internal/collections"
 	"github.com/c8121/asset-storage/internal/config"
+	"github.com/c8121/asset-storage/internal/ingest"
 	"github.com/c8121/asset-storage/internal/metadata"
-	metadata_db_entity "github.com/c8121/asset-storage/internal/metadata-db-entity"
+	metadata_db "github.com/c8121/asset-storage/internal/metadata-db"
 	mdsqlite "github.com/c8121/asset-storage/internal/metadata-sqlite"
 	"github.com/c8121/asset-storage/internal/storage"
+	"github.com/c8121/asset-storage/internal/takeout"
+	"github.com/c8121/asset-storage/internal/xmp"
 )
 
 const (
@@ -25,6 +29,7 @@ var (
 	currentUser, currentUserErr = user.Current()
 	recursive                   = flag.Bool("r", false, "Recursively add files")
 	fileNameFilter              = flag.String("name", "", "File name filter (*.jpg for example")
+	takeoutMode                 = flag.Bool("takeout", false, "Google Takeout import: apply sidecar JSON files to media, add albums as collections, do not add sidecars")
 )
 
 func main() {
@@ -49,61 +54,87 @@ func main() {
 		if file[:1] == "-" {
 			continue
 		}
-		addErr := addPath(file)
+		_, addErr := addPath(file)
 		if addErr != nil {
 			fmt.Println(addErr)
 		}
 	}
 }
 
-func addPath(path string) error {
+// addPath adds a file or the files of a directory, returns hashes of files (not of directories)
+func addPath(path string) ([]string, error) {
 
 	stat, err := os.Stat(path)
 	if err != nil {
 		fmt.Printf("Cannot get file stat '%s'\n", path)
-		return err
+		return nil, err
 	}
 
 	if stat.IsDir() {
 		if !*recursive {
 			fmt.Printf("'%s' is a directory, omitting (-r not given)\n", path)
-			return nil
+			return nil, nil
 		}
 		files, err := os.ReadDir(path)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		var added []string
 		for _, file := range files {
 			filePath := filepath.Join(path, file.Name())
-			err = addPath(filePath)
+			hashes, err := addPath(filePath)
 			if err != nil {
 				fmt.Printf("Error, omitting file: '%s': %s\n", filePath, err)
 			}
+			added = append(added, hashes...)
 		}
-		return nil
+		if *takeoutMode {
+			addAlbum(path, added)
+		}
+		return nil, nil
 	}
 
 	if fileNameFilter != nil && *fileNameFilter != "" {
 		matched, err := filepath.Match(*fileNameFilter, stat.Name())
 		if !matched || err != nil {
-			return err
+			return nil, err
 		}
 	}
 
+	if *takeoutMode && takeout.IsSidecar(path) {
+		fmt.Printf("Sidecar, omitting file: '%s'\n", path)
+		return nil, nil
+	}
+	if xmp.IsSidecar(path) {
+		fmt.Printf("XMP sidecar, omitting file: '%s'\n", path)
+		return nil, nil
+	}
+
+	var hashes []string
 	for attempt := 0; attempt < MaxAttemptsPerFile; attempt++ {
-		err = addFileAndMetadata(path, stat)
+		hashes, err = addFileAndMetadata(path, stat)
 		if err == nil {
-			return nil
+			return hashes, nil
 		}
 
 		wait := time.Duration(attempt*MinWaitSecondsAfterFail) * time.Second
 		fmt.Printf("Error, attempt %d/%d, waiting %d: %s\n", attempt+1, MaxAttemptsPerFile, wait, filepath.Base(path))
 		time.Sleep(wait)
 	}
-	return err
+	return nil, err
 }
 
-func addFileAndMetadata(path string, stat os.FileInfo) error {
+// addFileAndMetadata adds a file to storage and its meta-data, returns the hashes of all files added
+// (more than one for archives)
+func addFileAndMetadata(path string, stat os.FileInfo) ([]string, error) {
+
+	var sidecar *takeout.Sidecar
+	if *takeoutMode {
+		var err error
+		if sidecar, err = takeout.FindSidecar(path); err != nil {
+			fmt.Printf("Error reading sidecar of '%s': %s\n", path, err)
+		}
+	}
 
 	//Check hash before adding
 	//Faster only if most of the files already exists as it only calculates the hash in memory.
@@ -112,12 +143,12 @@ func addFileAndMetadata(path string, stat os.FileInfo) error {
 		hash, err := storage.HashFromContent(path)
 		if err != nil {
 			fmt.Printf("Error adding '%s': %s\n", path, err)
-			return err
+			return nil, err
 		}
 		storagePath, err := storage.FindByHash(hash)
 		if !errors.Is(err, os.ErrNotExist) {
 			fmt.Printf("File already exists as '%s': %s\n", storagePath, path)
-			return nil
+			return []string{hash}, nil
 		}
 	}
 
@@ -125,30 +156,102 @@ func addFileAndMetadata(path string, stat os.FileInfo) error {
 	infos, err := storage.AddFile(path)
 	if err != nil {
 		fmt.Printf("Error adding '%s': %s\n", path, err)
-		return err
+		return nil, err
 	}
 
+	hashes := make([]string, 0, len(infos))
 	for _, info := range infos {
+		hashes = append(hashes, info.Hash)
 		if info.IsNewFile || !config.SkipMetaDataIfExists {
+
+			name := filepath.Base(info.SourcePath)
+			fileTime := stat.ModTime()
+			if sidecar != nil && info.SourcePath == path {
+				//File time of takeout files is the time of export
+				if sidecar.Title != "" {
+					name = sidecar.Title
+				}
+				if t := sidecar.TakenTime(); !t.IsZero() {
+					fileTime = t
+				}
+			}
+
 			//Create/Update meta-data
-			meta, err := metadata.AddMetaData(
-				info.Hash,
-				info.MimeType,
-				filepath.Base(info.SourcePath),
+			meta, err := metadata.AddFileMetaData(
+				&info,
+				name,
 				filepath.Dir(info.SourcePath),
 				currentUser.Username,
-				stat.ModTime())
+				fileTime)
 			if err != nil {
 				fmt.Printf("Error adding meta-data '%s': %s\n", path, err)
-				return err
+				return hashes, err
 			}
 
-			//Create/Update meta-data-database
-			err = metadata_db_entity.AddMetaData(meta)
-			if err != nil {
-				fmt.Printf("Error adding meta-data to database '%s': %s\n", path, err)
+			var applySidecars ingest.SidecarFunc
+			if info.SourcePath == path {
+				applySidecars = func(meta *metadata.JsonAssetMetaData) *metadata.JsonAssetMetaData {
+					return applySidecarFiles(meta, path, sidecar)
+				}
 			}
+			ingest.Process(meta, path, applySidecars)
+		}
+	}
+	return hashes, err
+}
+
+// applySidecarFiles applies the takeout sidecar (if not nil) and the XMP sidecar of path (if exists),
+// returns the updated meta-data
+func applySidecarFiles(meta *metadata.JsonAssetMetaData, path string, sidecar *takeout.Sidecar) *metadata.JsonAssetMetaData {
+
+	//Sidecar data, if not available from EXIF
+	if sidecar != nil {
+		if updated, err := takeout.Apply(meta.Hash, sidecar); err != nil {
+			fmt.Printf("Error applying sidecar '%s': %s\n", path, err)
+		} else {
+			meta = updated
 		}
 	}
-	return err
+
+	//Keywords, rating, title and description from XMP sidecar (file.xmp for file.jpg)
+	if xmpPath := xmp.FindSidecar(path); xmpPath != "" {
+		if updated, err := applyXmp(meta.Hash, xmpPath); err != nil {
+			fmt.Printf("Error applying XMP '%s': %s\n", xmpPath, err)
+		} else {
+			meta = updated
+		}
+	}
+	return meta
+}
+
+// applyXmp reads a XMP sidecar and adds its data to meta-data, returns the updated meta-data
+func applyXmp(hash string, xmpPath string) (*metadata.JsonAssetMetaData, error) {
+	data, err := xmp.ReadFile(xmpPath)
+	if err != nil {
+		return nil, err
+	}
+	return xmp.Apply(hash, data)
+}
+
+// addAlbum creates a collection of the files added from a takeout album folder
+func addAlbum(dir string, hashes []string) {
+
+	album, err := takeout.LoadAlbum(dir)
+	if err != nil {
+		fmt.Printf("Error reading album '%s': %s\n", dir, err)
+		return
+	}
+	if album == nil || len(hashes) == 0 {
+		return
+	}
+
+	collection, err := collections.AddCollection(album.Title, album.Description, currentUser.Username, hashes)
+	if err != nil {
+		fmt.Printf("Error adding album '%s': %s\n", dir, err)
+		return
+	}
+	if err = metadata_db.AddCollection(collection); err != nil {
+		fmt.Printf("Error adding album to database '%s': %s\n", dir, err)
+	}
+	fmt.Printf("Added album '%s' (%d files)\n", album.Title, len(hashes))
 }
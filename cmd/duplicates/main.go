@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/c8121/asset-storage/internal/config"
+	metadata_db "github.com/c8121/asset-storage/internal/metadata-db"
+	mdsqlite "github.com/c8121/asset-storage/internal/metadata-sqlite"
+	"github.com/c8121/asset-storage/internal/util"
+)
+
+/*
+	Report groups of images which look similar (resized, re-encoded, stripped EXIF...),
+	determined by perceptual image hashes.
+
+	Image hashes are calculated by cmd/add, use metadata-db-create -image-hash for images added before.
+*/
+
+func main() {
+
+	distance := flag.Int("distance", metadata_db.DefaultImageHashDistance, "Max number of different bits of image hashes (0-64)")
+	showPaths := flag.Bool("paths", false, "Show original paths of each image")
+
+	config.LoadDefault()
+
+	mdsqlite.Open()
+	defer mdsqlite.Close()
+
+	groups, err := metadata_db.FindSimilarImageGroups(*distance)
+	util.PanicOnError(err, "Failed to find similar images")
+
+	count := 0
+	for i, group := range groups {
+		fmt.Printf("\nGroup %d (%d images)\n", i+1, len(group.Items))
+		for _, item := range group.Items {
+			fmt.Printf("  %s  %10d  %s  %s  %s\n",
+				item.Hash, item.Size, item.FileTime.Format("2006-01-02 15:04:05"), item.MimeType, item.Name)
+			if *showPaths {
+				paths, err := metadata_db.ListAssetPaths(item.Id)
+				if err != nil {
+					fmt.Printf("    Failed to load paths: %s\n", err)
+				}
+				for _, path := range paths {
+					fmt.Printf("    %s\n", path.FullPath)
+				}
+			}
+		}
+		count += len(group.Items)
+	}
+
+	fmt.Printf("\nFound %d groups with %d similar images\n", len(groups), count)
+}
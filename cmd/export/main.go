@@ -0,0 +1,145 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/c8121/asset-storage/internal/config"
+	"github.com/c8121/asset-storage/internal/metadata"
+	metadata_db "github.com/c8121/asset-storage/internal/metadata-db"
+	mdsqlite "github.com/c8121/asset-storage/internal/metadata-sqlite"
+	"github.com/c8121/asset-storage/internal/storage"
+	"github.com/c8121/asset-storage/internal/util"
+	"github.com/c8121/asset-storage/internal/xmp"
+)
+
+/*
+	Export assets into a directory, with XMP sidecars (file.xmp for file.jpg) containing
+	tags, rating, title and description to be used with desktop photo tools.
+
+	Files are named like their preferred name or latest origin, names are made unique by appending the hash.
+*/
+
+const (
+	FilePermissions = 0644
+	PageSize        = 1000
+)
+
+var (
+	copyFiles *bool
+	writeXmp  *bool
+)
+
+func main() {
+
+	out := flag.String("out", "", "Destination directory (required)")
+	query := flag.String("query", "", "Search query to select assets (see README), all assets if empty")
+	copyFiles = flag.Bool("files", true, "Copy files")
+	writeXmp = flag.Bool("xmp", true, "Write XMP sidecars")
+
+	config.LoadDefault()
+
+	if *out == "" {
+		fmt.Printf("Destination directory required: %s -out <directory> [-query <query>]\n", filepath.Base(os.Args[0]))
+		os.Exit(1)
+	}
+	util.PanicOnError(os.MkdirAll(*out, 0755), "Failed to create destination directory")
+
+	mdsqlite.Open()
+	defer mdsqlite.Close()
+
+	usedNames := make(map[string]bool) //Lower case names without extension
+	exported := 0
+	failed := 0
+
+	filter := &metadata_db.AssetListFilter{Query: *query, Sort: metadata_db.SortTimeAsc, Count: PageSize}
+	for {
+		list, err := metadata_db.ListAssets(filter)
+		util.PanicOnError(err, "Failed to list assets")
+
+		for _, item := range list.Items {
+			if err := exportAsset(item.Hash, *out, usedNames); err != nil {
+				fmt.Printf("Failed '%s': %s\n", item.Hash, err)
+				failed++
+			} else {
+				exported++
+			}
+		}
+
+		filter.Offset += len(list.Items)
+		if len(list.Items) < PageSize {
+			break
+		}
+	}
+
+	fmt.Printf("\nExported %d assets to '%s', %d failed\n", exported, *out, failed)
+	if failed > 0 {
+		mdsqlite.Close()
+		os.Exit(1)
+	}
+}
+
+// exportAsset copies the file and writes the XMP sidecar
+func exportAsset(hash string, out string, usedNames map[string]bool) error {
+
+	meta, err := metadata.LoadByHash(hash)
+	if err != nil {
+		return err
+	}
+
+	name := hash
+	if displayName := metadata.GetDisplayName(meta); displayName != "" {
+		name = filepath.Base(displayName)
+	}
+	origin := metadata.GetLatestOrigin(meta)
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	if usedNames[strings.ToLower(base)] {
+		//Also keeps XMP sidecars apart: a.jpg and a.png would share a.xmp
+		base = base + "-" + hash[:min(8, len(hash))]
+	}
+	usedNames[strings.ToLower(base)] = true
+
+	if *copyFiles {
+		path := filepath.Join(out, base+ext)
+		if err = copyAsset(hash, path); err != nil {
+			return err
+		}
+		if origin != nil && !origin.FileTime.IsZero() {
+			util.LogError(os.Chtimes(path, origin.FileTime, origin.FileTime))
+		}
+	}
+
+	if *writeXmp {
+		file, err := os.OpenFile(filepath.Join(out, base+xmp.Extension), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, FilePermissions)
+		if err != nil {
+			return err
+		}
+		defer util.CloseOrLog(file)
+		return xmp.Write(file, xmp.FromMetaData(meta))
+	}
+	return nil
+}
+
+// copyAsset writes the content of an asset to path
+func copyAsset(hash string, path string) error {
+
+	reader, err := storage.Open(hash)
+	if err != nil {
+		return err
+	}
+	defer util.CloseOrLog(reader)
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, FilePermissions)
+	if err != nil {
+		return err
+	}
+	defer util.CloseOrLog(file)
+
+	_, err = io.Copy(file, reader)
+	return err
+}
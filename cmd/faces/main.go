@@ -9,19 +9,25 @@ import (
 	"github.com/c8121/asset-storage/internal/faces"
 	mdsqlite "github.com/c8121/asset-storage/internal/metadata-sqlite"
 	"github.com/c8121/asset-storage/internal/storage"
+	"github.com/c8121/asset-storage/internal/util"
 )
 
 func main() {
 
-	command := flag.String("command", "indentify", "Command, either 'identify' or 'similarity'")
+	command := flag.String("command", "indentify", "Command, either 'identify', 'index', 'similarity' or 'cluster'")
 	threshold := flag.Float64("threshold", 0.45, "Minimun similariy threshold")
+	minFaces := flag.Int("min-faces", 3, "Minimum number of similar faces to form a person (cluster)")
 
 	config.LoadDefault()
 	storage.CreateDirectories()
 
 	fmt.Printf("Command: %s\n", *command)
 
-	if strings.HasPrefix(*command, "i") {
+	if *command == "index" {
+
+		updateFaceIndex()
+
+	} else if strings.HasPrefix(*command, "i") {
 
 		handler := func(path string) {
 			hash := storage.HashFromStoragePath(path)
@@ -40,14 +46,38 @@ func main() {
 		mdsqlite.Open()
 		defer mdsqlite.Close()
 
-		embeddings := faces.ReadEmbeddings(config.AssetFacesBaseDir)
-		fmt.Printf("Found %d embeddings\n", len(embeddings))
+		index := updateFaceIndex()
+		faces.CalculateSimilarity(index, *threshold)
+		fmt.Printf("Checked %d faces\n", index.Len())
+
+	} else if strings.HasPrefix(*command, "c") {
 
-		faces.CalculateSimilarity(embeddings, *threshold)
-		fmt.Printf("Checked %d embeddings\n", len(embeddings))
+		mdsqlite.Open()
+		defer mdsqlite.Close()
+
+		index := updateFaceIndex()
+		persons, err := faces.ClusterPersons(index, *threshold, *minFaces)
+		if err != nil {
+			fmt.Printf("Failed to cluster faces: %s\n", err)
+			return
+		}
+
+		assigned := 0
+		for _, person := range persons.Persons {
+			assigned += len(person.Faces)
+		}
+		fmt.Printf("Assigned %d of %d faces to %d persons\n", assigned, index.Len(), len(persons.Persons))
 
 	} else {
 		fmt.Printf("Unknown command: %s\n", *command)
 	}
 
 }
+
+// updateFaceIndex adds faces not indexed yet, exits on error
+func updateFaceIndex() *faces.FaceIndex {
+	index, added, err := faces.UpdateFaceIndex()
+	util.PanicOnError(err, "Failed to update face index")
+	fmt.Printf("Added %d faces, %d faces indexed\n", added, index.Len())
+	return index
+}
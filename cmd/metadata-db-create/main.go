@@ -1,14 +1,28 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/c8121/asset-storage/internal/collections"
 	"github.com/c8121/asset-storage/internal/config"
+	"github.com/c8121/asset-storage/internal/exif"
+	"github.com/c8121/asset-storage/internal/faces"
+	"github.com/c8121/asset-storage/internal/filter"
 	"github.com/c8121/asset-storage/internal/metadata"
+	metadata_db "github.com/c8121/asset-storage/internal/metadata-db"
 	metadata_db_entity "github.com/c8121/asset-storage/internal/metadata-db-entity"
 	mdsqlite "github.com/c8121/asset-storage/internal/metadata-sqlite"
+	text_extractor "github.com/c8121/asset-storage/internal/text-extractor"
 	"github.com/c8121/asset-storage/internal/util"
 )
 
@@ -19,44 +33,369 @@ import (
 	Not required if database is intact, because cmd/add also updates the database.
 */
 
+const (
+	// WatermarkProperty is the start time of the last run, see metadata_db_entity.GetDbProperty
+	WatermarkProperty = "metadata-db-create.watermark"
+	// FailedProperty lists files (JSON array of paths) which failed in the last run, read again by the next incremental run
+	FailedProperty = "metadata-db-create.failed"
+)
+
+var (
+	extractText *bool
+	readExif    *bool
+	imageHash   *bool
+)
+
+// report collects results of a run
+type report struct {
+	mu          sync.Mutex //failures and unchanged are updated while walking directories
+	read        int
+	unchanged   int
+	added       int
+	collections int
+	failures    []failure
+	pruned      *metadata_db_entity.PruneResult
+}
+
+type failure struct {
+	path string
+	err  error
+}
+
+// loaded is the result of reading one meta-data file
+type loaded struct {
+	path string
+	meta *metadata.JsonAssetMetaData
+	err  error
+}
+
 func main() {
 
+	reindex := flag.Bool("reindex", false, "Clear full-text search index before reading meta-data")
+	extractText = flag.Bool("extract-text", false, "Extract text for full-text search, if not done before")
+	readExif = flag.Bool("read-exif", false, "Read capture time and location from EXIF, if not done before")
+	imageHash = flag.Bool("image-hash", false, "Calculate perceptual hash of images (to find similar images), if not done before")
+	rebuildPaths := flag.Bool("rebuild-paths", false, "Rebuild path hierarchy (used to search sub-trees)")
+	incremental := flag.Bool("incremental", false, "Read only meta-data and collection files modified since last run")
+	workers := flag.Int("workers", runtime.NumCPU(), "Number of meta-data files read in parallel")
+	batchSize := flag.Int("batch", 100, "Number of assets written to database in one transaction")
+	prune := flag.Bool("prune", false, "Remove assets and collections without file, and origins, paths, file names not used anymore")
+	rebuildFaces := flag.Bool("faces", false, "Rebuild face similarities from face embeddings")
+	faceThreshold := flag.Float64("face-threshold", 0.45, "Minimum similarity threshold when rebuilding face similarities")
+
 	config.LoadDefault()
 
 	mdsqlite.Open()
 	defer mdsqlite.Close()
 
-	util.PanicOnError(readAllMetaData(config.AssetMetaDataBaseDir), "Failed to read meta-data directory")
-}
+	started := time.Now()
+
+	var since time.Time
+	retry := make(map[string]bool)
+	if *incremental {
+		since = loadWatermark()
+		retry = loadFailed()
+		fmt.Printf("Reading files modified since %s and %d files failed before\n", since, len(retry))
+	}
 
-// readAllMetaData recursively find JSON meta-data an write to SQLite database
-func readAllMetaData(path string) error {
+	if *reindex {
+		fmt.Printf("Clear full-text search index\n")
+		util.PanicOnError(metadata_db_entity.ClearSearchIndex(), "Failed to clear search index")
+	}
+
+	r := &report{}
+	readAllMetaData(config.AssetMetaDataBaseDir, since, retry, max(1, *workers), max(1, *batchSize), r)
+	readAllCollections(config.AssetCollectionsBaseDir, since, retry, r)
+
+	//Persons are stored in a file, see faces.ClusterPersons
+	util.PanicOnError(faces.SyncPersons(), "Failed to copy persons to database")
+
+	if *prune {
+		pruneDatabase(r)
+	}
+
+	if *rebuildPaths {
+		util.PanicOnError(metadata_db_entity.RebuildPathItemClosure(), "Failed to rebuild path hierarchy")
+	}
+
+	if *rebuildFaces {
+		fmt.Printf("Rebuild face similarities\n")
+		util.PanicOnError(metadata_db_entity.ClearFaceSimilarities(), "Failed to clear face similarities")
+		index, added, err := faces.UpdateFaceIndex()
+		util.PanicOnError(err, "Failed to update face index")
+		fmt.Printf("Added %d faces, %d faces indexed\n", added, index.Len())
+		faces.CalculateSimilarity(index, *faceThreshold)
+	}
+
+	//Files which failed will be read again by the next incremental run
+	saveFailed(r)
+	util.PanicOnError(metadata_db_entity.SetDbProperty(WatermarkProperty, started.Format(time.RFC3339Nano)),
+		"Failed to save watermark")
+
+	r.print()
+	if len(r.failures) > 0 {
+		mdsqlite.Close()
+		os.Exit(1)
+	}
+}
 
-	entries, err := os.ReadDir(path)
+// loadWatermark returns the start time of the last run, zero if not available
+func loadWatermark() time.Time {
+	value, err := metadata_db_entity.GetDbProperty(WatermarkProperty)
+	util.PanicOnError(err, "Failed to load watermark")
+	if value == "" {
+		return time.Time{}
+	}
+	watermark, err := time.Parse(time.RFC3339Nano, value)
 	if err != nil {
-		return err
+		fmt.Printf("Invalid watermark '%s', reading all files\n", value)
+		return time.Time{}
+	}
+	return watermark
+}
+
+// loadFailed returns the paths which failed in the last run
+func loadFailed() map[string]bool {
+	retry := make(map[string]bool)
+	value, err := metadata_db_entity.GetDbProperty(FailedProperty)
+	util.PanicOnError(err, "Failed to load failed files")
+	if value == "" {
+		return retry
+	}
+	var paths []string
+	if err = json.Unmarshal([]byte(value), &paths); err != nil {
+		fmt.Printf("Invalid list of failed files: %s\n", err)
+		return retry
+	}
+	for _, path := range paths {
+		retry[path] = true
+	}
+	return retry
+}
+
+// saveFailed replaces the paths which failed (files or directories)
+func saveFailed(r *report) {
+	paths := make([]string, 0, len(r.failures))
+	for _, f := range r.failures {
+		paths = append(paths, f.path)
 	}
-	for _, file := range entries {
-		filePath := filepath.Join(path, file.Name())
-		stat, statErr := os.Stat(filePath)
-		if statErr != nil {
-			return statErr
+	value, err := json.Marshal(paths)
+	util.PanicOnError(err, "Failed to create list of failed files")
+	util.PanicOnError(metadata_db_entity.SetDbProperty(FailedProperty, string(value)), "Failed to save failed files")
+}
+
+// readAllMetaData recursively finds JSON meta-data modified after since (or in retry), reads it in parallel
+// and writes it to database in batches
+func readAllMetaData(path string, since time.Time, retry map[string]bool, workers int, batchSize int, r *report) {
+
+	paths := make(chan string, workers*2)
+	results := make(chan loaded, workers*2)
+
+	go func() {
+		defer close(paths)
+		walkJsonFiles(path, metadata.MetaDataFileExtension, since, retry, paths, r)
+	}()
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for filePath := range paths {
+				meta, err := loadMetaData(filePath)
+				results <- loaded{filePath, meta, err}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	batch := make([]loaded, 0, batchSize)
+	for result := range results {
+		r.read++
+		if result.err != nil {
+			r.fail(result.path, result.err)
+			continue
+		}
+		batch = append(batch, result)
+		if len(batch) == batchSize {
+			writeBatch(batch, r)
+			batch = batch[:0]
 		}
-		if stat.IsDir() {
-			if err = readAllMetaData(filePath); err != nil {
-				return err
+	}
+	writeBatch(batch, r)
+}
+
+// walkJsonFiles sends all files with extension modified after since to paths,
+// and files which failed before (retry, the file or a parent directory),
+// counts unchanged files and records directories which cannot be read
+func walkJsonFiles(path string, extension string, since time.Time, retry map[string]bool, paths chan<- string, r *report) {
+
+	err := filepath.WalkDir(path, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && filePath == path {
+				return nil
 			}
-		} else {
-			if meta, err := metadata.LoadIfExists(filePath); err == nil {
-				if err = metadata_db_entity.AddMetaData(meta); err != nil {
-					return err
-				} else {
-					fmt.Printf("Added '%s'\n", filePath)
-				}
-			} else {
-				return err
+			r.fail(filePath, err)
+			return nil
+		}
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), extension) {
+			return nil
+		}
+		if !since.IsZero() {
+			info, err := entry.Info()
+			if err != nil {
+				r.fail(filePath, err)
+				return nil
+			}
+			if info.ModTime().Before(since) && !isRetry(filePath, path, retry) {
+				r.skip()
+				return nil
 			}
 		}
+		paths <- filePath
+		return nil
+	})
+	if err != nil {
+		r.fail(path, err)
+	}
+}
+
+// isRetry returns true if the file or one of its parent directories (up to root) failed before
+func isRetry(filePath string, root string, retry map[string]bool) bool {
+	for p := filePath; len(p) >= len(root); p = filepath.Dir(p) {
+		if retry[p] {
+			return true
+		}
+		if p == root || p == filepath.Dir(p) {
+			break
+		}
+	}
+	return false
+}
+
+// loadMetaData reads JSON and adds data extracted from content if requested
+func loadMetaData(filePath string) (*metadata.JsonAssetMetaData, error) {
+
+	meta, err := metadata.LoadIfExists(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if meta.Hash == "" {
+		return nil, fmt.Errorf("missing hash")
+	}
+
+	if *readExif {
+		if updated, err := exif.AddExifIfNotExists(meta); err != nil {
+			fmt.Printf("Failed to read EXIF '%s': %s\n", filePath, err)
+		} else {
+			meta = updated
+		}
+	}
+	if *imageHash {
+		if updated, err := filter.AddImageHashIfNotExists(meta); err != nil {
+			fmt.Printf("Failed to calculate image hash '%s': %s\n", filePath, err)
+		} else {
+			meta = updated
+		}
+	}
+	if *extractText {
+		if err = text_extractor.ExtractIfNotExists(meta); err != nil {
+			fmt.Printf("Failed to extract text '%s': %s\n", filePath, err)
+		}
+	}
+	return meta, nil
+}
+
+// writeBatch writes meta-data to database in one transaction
+func writeBatch(batch []loaded, r *report) {
+
+	metas := make([]*metadata.JsonAssetMetaData, len(batch))
+	for i, item := range batch {
+		metas[i] = item.meta
+	}
+
+	for i, err := range metadata_db_entity.AddMetaDataBatch(metas) {
+		if err != nil {
+			r.fail(batch[i].path, err)
+		} else {
+			r.added++
+		}
+	}
+	if len(batch) > 0 {
+		fmt.Printf("Added %d assets\n", r.added)
+	}
+}
+
+// readAllCollections recursively finds collection JSON files modified after since (or in retry) and writes them to database
+func readAllCollections(path string, since time.Time, retry map[string]bool, r *report) {
+
+	paths := make(chan string)
+	go func() {
+		defer close(paths)
+		walkJsonFiles(path, ".json", since, retry, paths, r)
+	}()
+
+	for filePath := range paths {
+		collection, err := collections.LoadIfExists(filePath)
+		if err == nil {
+			err = metadata_db.AddCollection(collection)
+		}
+		if err != nil {
+			r.fail(filePath, err)
+		} else {
+			r.collections++
+		}
+	}
+}
+
+// pruneDatabase removes assets and collections without JSON file and rows not referenced anymore
+func pruneDatabase(r *report) {
+
+	fileExists := func(path string) bool {
+		_, err := os.Stat(path)
+		return !errors.Is(err, fs.ErrNotExist)
+	}
+
+	var err error
+	r.pruned, err = metadata_db_entity.PruneAssets(func(hash string) bool {
+		return fileExists(metadata.GetMetaDataFilePath(hash))
+	})
+	util.PanicOnError(err, "Failed to prune assets")
+
+	r.pruned.Collections, err = metadata_db_entity.PruneCollections(func(hash string) bool {
+		return len(hash) > 2 && fileExists(collections.GetCollectionFilePath(hash))
+	})
+	util.PanicOnError(err, "Failed to prune collections")
+}
+
+func (r *report) fail(path string, err error) {
+	fmt.Printf("Failed '%s': %s\n", path, err)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failures = append(r.failures, failure{path, err})
+}
+
+func (r *report) skip() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.unchanged++
+}
+
+func (r *report) print() {
+
+	fmt.Printf("\nRead %d meta-data files, added %d assets, %d unchanged, %d collections\n",
+		r.read, r.added, r.unchanged, r.collections)
+	if r.pruned != nil {
+		fmt.Printf("Removed %d assets, %d origins, %d paths, %d file names, %d tags, %d mime-types, %d collections\n",
+			r.pruned.Assets, r.pruned.Origins, r.pruned.PathItems, r.pruned.FileNames, r.pruned.Tags, r.pruned.MimeTypes, r.pruned.Collections)
+	}
+	if len(r.failures) > 0 {
+		fmt.Printf("%d failed:\n", len(r.failures))
+		for _, f := range r.failures {
+			fmt.Printf("  %s: %s\n", f.path, f.err)
+		}
 	}
-	return nil
 }
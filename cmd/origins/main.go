@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+
+	"github.com/c8121/asset-storage/internal/config"
+	"github.com/c8121/asset-storage/internal/metadata"
+	metadata_db "github.com/c8121/asset-storage/internal/metadata-db"
+	metadata_db_entity "github.com/c8121/asset-storage/internal/metadata-db-entity"
+	mdsqlite "github.com/c8121/asset-storage/internal/metadata-sqlite"
+	"github.com/c8121/asset-storage/internal/util"
+)
+
+/*
+	List origins of an asset, set the preferred name, hide or remove origins.
+
+	Origins are identified by their index as listed.
+*/
+
+func main() {
+
+	name := flag.String("name", "", "Set preferred name (use '-' to remove it, the latest origin is used then)")
+	hide := flag.Int("hide", -1, "Hide origin (index as listed)")
+	show := flag.Int("show", -1, "Show hidden origin again (index as listed)")
+	remove := flag.Int("remove", -1, "Remove origin (index as listed)")
+
+	config.LoadDefault()
+
+	if flag.NArg() != 1 || len(flag.Arg(0)) < 32 {
+		fmt.Printf("Usage: %s [-name <name>] [-hide <index>] [-show <index>] [-remove <index>] <hash>\n", filepath.Base(os.Args[0]))
+		os.Exit(1)
+	}
+	hash := flag.Arg(0)
+
+	currentUser, err := user.Current()
+	util.PanicOnError(err, "Failed to get current user")
+
+	mdsqlite.Open()
+	defer mdsqlite.Close()
+
+	meta, err := metadata.LoadByHash(hash)
+	exitOnError(err)
+
+	changed := false
+	if *name != "" {
+		preferred := *name
+		if preferred == "-" {
+			preferred = ""
+		}
+		value, _ := json.Marshal(preferred)
+		meta, err = metadata.UpdateMetaData(hash, map[string]json.RawMessage{"Name": value}, currentUser.Username)
+		exitOnError(err)
+		changed = true
+	}
+	if *hide >= 0 {
+		meta, err = metadata.SetOriginHidden(hash, *hide, true, currentUser.Username)
+		exitOnError(err)
+		changed = true
+	}
+	if *show >= 0 {
+		meta, err = metadata.SetOriginHidden(hash, *show, false, currentUser.Username)
+		exitOnError(err)
+		changed = true
+	}
+	if *remove >= 0 {
+		meta, err = metadata.RemoveOrigin(hash, *remove, currentUser.Username)
+		exitOnError(err)
+		changed = true
+	}
+	if changed {
+		exitOnError(metadata_db_entity.AddMetaData(meta))
+	}
+
+	fmt.Printf("Name: %s\n", metadata.GetDisplayName(meta))
+	for _, origin := range metadata_db.ListOrigins(meta) {
+		marker := " "
+		if origin.Hidden {
+			marker = "-"
+		} else if origin.Latest {
+			marker = "*"
+		}
+		fmt.Printf("%s %2d  %s  %-12s  %s\n", marker, origin.Index,
+			origin.FileTime.Format("2006-01-02 15:04:05"), origin.Owner, origin.FullPath)
+	}
+}
+
+func exitOnError(err error) {
+	if err != nil {
+		fmt.Printf("Failed: %s\n", err)
+		mdsqlite.Close()
+		os.Exit(1)
+	}
+}
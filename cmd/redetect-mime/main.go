@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/c8121/asset-storage/internal/config"
+	"github.com/c8121/asset-storage/internal/metadata"
+	metadata_db_entity "github.com/c8121/asset-storage/internal/metadata-db-entity"
+	mdsqlite "github.com/c8121/asset-storage/internal/metadata-sqlite"
+	"github.com/c8121/asset-storage/internal/storage"
+	"github.com/c8121/asset-storage/internal/util"
+)
+
+/*
+	Detect mime-types of all stored assets again (see storage.DetectMimeType), to correct
+	types of assets added with an older detection (docx/xlsx/odt detected as zip for example).
+
+	Changed types are written to meta-data JSON (with history, as metadata.SystemUser) and database,
+	each change is reported.
+*/
+
+func main() {
+
+	dryRun := flag.Bool("dry-run", false, "Only report changes")
+
+	config.LoadDefault()
+
+	if !*dryRun {
+		mdsqlite.Open()
+		defer mdsqlite.Close()
+	}
+
+	read := 0
+	changed := 0
+	failed := 0
+
+	err := filepath.WalkDir(config.AssetMetaDataBaseDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && path == config.AssetMetaDataBaseDir {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			return nil
+		}
+
+		read++
+		meta, mimeType, err := redetect(path)
+		if err != nil {
+			fmt.Printf("Failed '%s': %s\n", path, err)
+			failed++
+			return nil
+		}
+		if mimeType == meta.MimeType {
+			return nil
+		}
+
+		fmt.Printf("%s: '%s' -> '%s'\n", meta.Hash, meta.MimeType, mimeType)
+		changed++
+		if *dryRun {
+			return nil
+		}
+
+		if meta, err = setMimeType(meta, mimeType); err == nil {
+			err = metadata_db_entity.AddMetaData(meta)
+		}
+		if err != nil {
+			fmt.Printf("Failed '%s': %s\n", path, err)
+			failed++
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("Failed '%s': %s\n", config.AssetMetaDataBaseDir, err)
+		failed++
+	}
+
+	if *dryRun {
+		fmt.Printf("\nRead %d meta-data files, %d mime-types would be changed, %d failed\n", read, changed, failed)
+	} else {
+		//Remove mime-types not used anymore
+		pruned, err := metadata_db_entity.PruneOrphans()
+		if err != nil {
+			fmt.Printf("Failed to remove unused mime-types: %s\n", err)
+			failed++
+		} else if pruned.MimeTypes > 0 {
+			fmt.Printf("Removed %d unused mime-types\n", pruned.MimeTypes)
+		}
+		fmt.Printf("\nRead %d meta-data files, changed %d mime-types, %d failed\n", read, changed, failed)
+	}
+
+	if failed > 0 {
+		mdsqlite.Close()
+		os.Exit(1)
+	}
+}
+
+// redetect loads meta-data and detects the mime-type of its asset, named like the latest origin
+func redetect(path string) (*metadata.JsonAssetMetaData, string, error) {
+
+	meta, err := metadata.LoadIfExists(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	reader, err := storage.Open(meta.Hash)
+	if err != nil {
+		return nil, "", err
+	}
+	defer util.CloseOrLog(reader)
+
+	head := make([]byte, storage.MimeTypePeekSize)
+	n, err := io.ReadFull(reader, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, "", err
+	}
+
+	name := ""
+	if origin := metadata.GetLatestOrigin(meta); origin != nil {
+		name = origin.Name
+	}
+	return meta, storage.DetectMimeType(head[:n], name), nil
+}
+
+// setMimeType changes the mime-type of meta-data, if not changed since it was loaded
+func setMimeType(meta *metadata.JsonAssetMetaData, mimeType string) (*metadata.JsonAssetMetaData, error) {
+
+	detectedFrom := meta.MimeType
+	value, err := json.Marshal(mimeType)
+	if err != nil {
+		return nil, err
+	}
+
+	return metadata.UpdateMetaDataWith(meta.Hash, func(current *metadata.JsonAssetMetaData) map[string]json.RawMessage {
+		if current.MimeType != detectedFrom {
+			return nil
+		}
+		return map[string]json.RawMessage{"MimeType": value}
+	}, metadata.SystemUser)
+}
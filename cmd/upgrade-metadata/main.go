@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/c8121/asset-storage/internal/collections"
+	"github.com/c8121/asset-storage/internal/config"
+	"github.com/c8121/asset-storage/internal/metadata"
+)
+
+/*
+	Rewrite all meta-data and collection JSON files of an older schema version to the current version.
+
+	Not required to keep working: older files are upgraded whenever they are loaded.
+	Files are converted in two steps: first all upgraded files are written next to the originals,
+	then (only if all succeeded) the originals are replaced by renaming.
+*/
+
+// StagingExtension is appended to the file name of upgraded files until they replace the original
+const StagingExtension = ".upgrade"
+
+func main() {
+
+	dryRun := flag.Bool("dry-run", false, "Only report files which would be upgraded")
+
+	config.LoadDefault()
+
+	var files []string //Upgraded files, waiting to replace the original
+	failed := 0
+
+	upgrade := func(baseDir string, upgradeFile func(path string) ([]byte, error)) {
+		err := filepath.WalkDir(baseDir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) && path == baseDir {
+					return nil
+				}
+				return err
+			}
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+				return nil
+			}
+
+			buf, err := upgradeFile(path)
+			if err != nil {
+				fmt.Printf("Failed '%s': %s\n", path, err)
+				failed++
+				return nil
+			}
+			if buf == nil {
+				return nil
+			}
+
+			fmt.Printf("Upgrade '%s'\n", path)
+			if !*dryRun {
+				if err = os.WriteFile(path+StagingExtension, buf, metadata.FilePermissions); err != nil {
+					fmt.Printf("Failed '%s': %s\n", path, err)
+					failed++
+					return nil
+				}
+			}
+			files = append(files, path)
+			return nil
+		})
+		if err != nil {
+			fmt.Printf("Failed '%s': %s\n", baseDir, err)
+			failed++
+		}
+	}
+
+	upgrade(config.AssetMetaDataBaseDir, metadata.Upgrade)
+	upgrade(config.AssetCollectionsBaseDir, collections.Upgrade)
+
+	if *dryRun {
+		fmt.Printf("\n%d files would be upgraded to version %d (collections: %d), %d failed\n",
+			len(files), metadata.CurrentSchemaVersion, collections.CurrentSchemaVersion, failed)
+		exitOnFailure(failed)
+		return
+	}
+
+	if failed > 0 {
+		//Keep all originals
+		for _, file := range files {
+			_ = os.Remove(file + StagingExtension)
+		}
+		fmt.Printf("\n%d failed, nothing upgraded\n", failed)
+		exitOnFailure(failed)
+	}
+
+	for _, file := range files {
+		if err := os.Rename(file+StagingExtension, file); err != nil {
+			fmt.Printf("Failed to replace '%s': %s\n", file, err)
+			failed++
+		}
+	}
+
+	fmt.Printf("\nUpgraded %d files to version %d (collections: %d)\n",
+		len(files)-failed, metadata.CurrentSchemaVersion, collections.CurrentSchemaVersion)
+	exitOnFailure(failed)
+}
+
+func exitOnFailure(failed int) {
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/c8121/asset-storage/internal/config"
+	metadata_db "github.com/c8121/asset-storage/internal/metadata-db"
+	mdsqlite "github.com/c8121/asset-storage/internal/metadata-sqlite"
+	"github.com/c8121/asset-storage/internal/storage"
+	"github.com/c8121/asset-storage/internal/util"
+)
+
+/*
+	Verify a checksum manifest (md5sum, sha1sum, sha256sum, b3sum output) against the archive:
+	each listed digest must belong to a stored asset.
+
+	With -content, the stored content of found assets is read and its digest calculated again,
+	to detect damaged files.
+*/
+
+func main() {
+
+	algorithm := flag.String("algorithm", "", "Digest algorithm of untagged lines: md5, sha1, sha256 or blake3 (default: by digest length)")
+	content := flag.Bool("content", false, "Read stored content and compare its digest")
+
+	config.LoadDefault()
+
+	if flag.NArg() != 1 {
+		fmt.Printf("Usage: %s [-algorithm <md5|sha1|sha256|blake3>] [-content] <manifest file>\n", filepath.Base(os.Args[0]))
+		os.Exit(1)
+	}
+
+	file, err := os.Open(flag.Arg(0))
+	util.PanicOnError(err, "Failed to open manifest")
+	entries, err := storage.ParseManifest(file, *algorithm)
+	util.CloseOrLog(file)
+	if err != nil {
+		fmt.Printf("Failed to read manifest '%s': %s\n", flag.Arg(0), err)
+		os.Exit(1)
+	}
+
+	mdsqlite.Open()
+	defer mdsqlite.Close()
+
+	ok := 0
+	missing := 0
+	damaged := 0
+
+	for _, entry := range entries {
+
+		algorithm, items, err := find(entry)
+		if err != nil {
+			fmt.Printf("Failed '%s': %s\n", entry.Name, err)
+			missing++
+			continue
+		}
+		if len(items) == 0 {
+			fmt.Printf("MISSING  %s  %s\n", entry.Digest, entry.Name)
+			missing++
+			continue
+		}
+
+		if *content {
+			if err := verifyContent(items[0].Hash, algorithm, entry.Digest); err != nil {
+				fmt.Printf("DAMAGED  %s  %s: %s\n", items[0].Hash, entry.Name, err)
+				damaged++
+				continue
+			}
+		}
+		fmt.Printf("OK       %s  %s\n", items[0].Hash, entry.Name)
+		ok++
+	}
+
+	fmt.Printf("\nVerified %d entries: %d ok, %d missing, %d damaged\n", len(entries), ok, missing, damaged)
+
+	if missing > 0 || damaged > 0 {
+		mdsqlite.Close()
+		os.Exit(1)
+	}
+}
+
+// find looks up the digest of entry with each possible algorithm, returns the algorithm found
+func find(entry storage.ManifestEntry) (string, []metadata_db.AssetListItem, error) {
+	for _, algorithm := range entry.Algorithms {
+		items, err := metadata_db.FindByDigest(algorithm, entry.Digest)
+		if err != nil || len(items) > 0 {
+			return algorithm, items, err
+		}
+	}
+	return "", nil, nil
+}
+
+// verifyContent reads stored content and compares its digest
+func verifyContent(hash string, algorithm string, digest string) error {
+
+	reader, err := storage.Open(hash)
+	if err != nil {
+		return err
+	}
+	defer util.CloseOrLog(reader)
+
+	digests, err := storage.ComputeDigests(reader)
+	if err != nil {
+		return err
+	}
+	if digests[algorithm] != digest {
+		return fmt.Errorf("%s digest of content is %s", algorithm, digests[algorithm])
+	}
+	return nil
+}
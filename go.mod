@@ -5,6 +5,7 @@ go 1.25.1
 require (
 	github.com/gabriel-vasile/mimetype v1.4.10
 	github.com/gin-gonic/gin v1.11.0
+	lukechampine.com/blake3 v1.4.1
 	modernc.org/sqlite v1.39.0
 )
 
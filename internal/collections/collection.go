@@ -9,24 +9,36 @@ import (
 	"time"
 
 	"github.com/c8121/asset-storage/internal/config"
+	"github.com/c8121/asset-storage/internal/storage"
 	"github.com/c8121/asset-storage/internal/util"
 )
 
 type (
 	JsonCollection struct {
-		Hash        string
-		Name        string
-		Description string
-		Created     time.Time
-		Owner       string
-		Assets      []string //Asset-Hashes
+		SchemaVersion int //See CurrentSchemaVersion, older files are upgraded when loaded
+		Hash          string
+		Name          string
+		Description   string
+		Created       time.Time
+		Owner         string
+		Assets        []string //Asset-Hashes
 	}
 )
 
 const (
 	FilePermissions = 0744
+
+	// CurrentSchemaVersion is written to all collection files, must be len(upgrades)
+	CurrentSchemaVersion = 1
 )
 
+// upgrades[i] converts collection JSON from version i to i+1, see util.UpgradeJson.
+// Never change existing upgrades, append a new one when changing JsonCollection incompatibly.
+var upgrades = []util.JsonUpgrade{
+	//1: Hash is required (was missing in some files), take it from file path
+	storage.UpgradeHashFromStoragePath,
+}
+
 // Init creates required directories
 func Init() {
 	util.CreateDirIfNotExists(config.AssetCollectionsBaseDir, FilePermissions)
@@ -60,12 +72,13 @@ func CreateNew(name string, description string, owner string, assetHashes []stri
 	hash := fmt.Sprintf("%x", hashCreator.Sum(nil))
 
 	collection := &JsonCollection{
-		Hash:        hash,
-		Name:        name,
-		Description: description,
-		Created:     time.Now(),
-		Owner:       owner,
-		Assets:      assetHashes,
+		SchemaVersion: CurrentSchemaVersion,
+		Hash:          hash,
+		Name:          name,
+		Description:   description,
+		Created:       time.Now(),
+		Owner:         owner,
+		Assets:        assetHashes,
 	}
 
 	return collection
@@ -76,6 +89,7 @@ func (collection *JsonCollection) Save(path string) error {
 
 	util.PanicOnError(os.MkdirAll(filepath.Dir(path), FilePermissions), "Failed to create destination directory")
 
+	collection.SchemaVersion = CurrentSchemaVersion
 	jsonBytes, err := json.Marshal(collection)
 	if err != nil {
 		return err
@@ -84,7 +98,7 @@ func (collection *JsonCollection) Save(path string) error {
 	return os.WriteFile(path, jsonBytes, FilePermissions)
 }
 
-// LoadIfExists Load JSON-file, if exists.
+// LoadIfExists Load JSON-file, if exists. Older versions are upgraded (not saved).
 func LoadIfExists(path string) (*JsonCollection, error) {
 
 	buf, err := os.ReadFile(path)
@@ -92,6 +106,11 @@ func LoadIfExists(path string) (*JsonCollection, error) {
 		return nil, err
 	}
 
+	buf, _, err = util.UpgradeJson(buf, path, upgrades)
+	if err != nil {
+		return nil, err
+	}
+
 	var collection = &JsonCollection{}
 	err = json.Unmarshal(buf, collection)
 	if err != nil {
@@ -101,6 +120,28 @@ func LoadIfExists(path string) (*JsonCollection, error) {
 	return collection, err
 }
 
+// Upgrade reads a collection file and converts it to CurrentSchemaVersion.
+// Returns the JSON to save, nil if the file is up to date.
+func Upgrade(path string) ([]byte, error) {
+
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	buf, upgraded, err := util.UpgradeJson(buf, path, upgrades)
+	if err != nil || !upgraded {
+		return nil, err
+	}
+
+	//Make sure the result is valid, write it the way Save does
+	var collection = &JsonCollection{}
+	if err = json.Unmarshal(buf, collection); err != nil {
+		return nil, err
+	}
+	return json.Marshal(collection)
+}
+
 // LoadByHash returns JsonAssetMetaData loaded from JSON-file
 func LoadByHash(collectionHash string) (*JsonCollection, error) {
 	path := GetCollectionFilePath(collectionHash)
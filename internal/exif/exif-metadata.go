@@ -0,0 +1,58 @@
+package exif
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/c8121/asset-storage/internal/metadata"
+	"github.com/c8121/asset-storage/internal/storage"
+	"github.com/c8121/asset-storage/internal/util"
+)
+
+// AddExifIfNotExists reads EXIF of images and saves DateTimeOriginal as capture time
+// and GPS coordinates as location to meta-data (as SystemUser, if not set before).
+// Returns the updated meta-data.
+func AddExifIfNotExists(meta *metadata.JsonAssetMetaData) (*metadata.JsonAssetMetaData, error) {
+
+	if (!meta.CaptureTime.IsZero() && meta.Location != nil) || !strings.HasPrefix(meta.MimeType, "image/") {
+		return meta, nil
+	}
+
+	reader, err := storage.Open(meta.Hash)
+	if err != nil {
+		return nil, err
+	}
+	defer util.CloseOrLog(reader)
+
+	info, err := Read(reader)
+	if errors.Is(err, ErrNoExif) {
+		return meta, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if info.DateTimeOriginal.IsZero() && !info.HasLocation {
+		return meta, nil
+	}
+	captureTime, err := json.Marshal(info.DateTimeOriginal)
+	if err != nil {
+		return nil, err
+	}
+	location, err := json.Marshal(&metadata.JsonLocation{Latitude: info.Latitude, Longitude: info.Longitude})
+	if err != nil {
+		return nil, err
+	}
+
+	//Meta-data might have been changed while reading EXIF
+	return metadata.UpdateMetaDataWith(meta.Hash, func(current *metadata.JsonAssetMetaData) map[string]json.RawMessage {
+		changes := make(map[string]json.RawMessage)
+		if current.CaptureTime.IsZero() && !info.DateTimeOriginal.IsZero() {
+			changes["CaptureTime"] = captureTime
+		}
+		if current.Location == nil && info.HasLocation {
+			changes["Location"] = location
+		}
+		return changes
+	}, metadata.SystemUser)
+}
@@ -0,0 +1,331 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// Exif contains the EXIF fields used by asset-storage
+type Exif struct {
+	DateTimeOriginal time.Time //Zero if not available
+	DateTime         time.Time //Modification time, zero if not available
+	HasLocation      bool      //True if GPS coordinates are available
+	Latitude         float64   //Degrees, negative is south
+	Longitude        float64   //Degrees, negative is west
+}
+
+const (
+	tagExifIfd            = 0x8769
+	tagGpsIfd             = 0x8825
+	tagDateTime           = 0x0132
+	tagDateTimeOriginal   = 0x9003
+	tagOffsetTimeOriginal = 0x9011
+
+	tagGpsLatitudeRef  = 0x0001
+	tagGpsLatitude     = 0x0002
+	tagGpsLongitudeRef = 0x0003
+	tagGpsLongitude    = 0x0004
+
+	typeAscii    = 2
+	typeShort    = 3
+	typeLong     = 4
+	typeRational = 5
+)
+
+var (
+	// MaxHeaderSize is the max number of bytes read to find EXIF data
+	MaxHeaderSize int64 = 1024 * 1024 * 4
+
+	ErrNoExif = errors.New("no EXIF data found")
+
+	exifHeader = []byte("Exif\x00\x00")
+)
+
+// Read finds EXIF data in JPEG, TIFF, PNG or WebP files
+func Read(reader io.Reader) (*Exif, error) {
+
+	data, err := io.ReadAll(io.LimitReader(reader, MaxHeaderSize))
+	if err != nil {
+		return nil, err
+	}
+
+	tiff := findTiff(data)
+	if tiff == nil {
+		return nil, ErrNoExif
+	}
+
+	return parseTiff(tiff)
+}
+
+// findTiff returns the TIFF structure containing EXIF data, depending on file type
+func findTiff(data []byte) []byte {
+
+	switch {
+	case bytes.HasPrefix(data, []byte{0xff, 0xd8}):
+		return findJpegTiff(data)
+	case bytes.HasPrefix(data, []byte("II*\x00")) || bytes.HasPrefix(data, []byte("MM\x00*")):
+		return data
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return findPngTiff(data)
+	case len(data) > 12 && bytes.HasPrefix(data, []byte("RIFF")) && string(data[8:12]) == "WEBP":
+		return findWebpTiff(data)
+	}
+	return nil
+}
+
+// findJpegTiff searches APP1 segment starting with "Exif"
+func findJpegTiff(data []byte) []byte {
+
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xff {
+			return nil
+		}
+		marker := data[pos+1]
+		if marker == 0xd8 || (marker >= 0xd0 && marker <= 0xd7) || marker == 0x01 || marker == 0xff {
+			pos++
+			continue
+		}
+		if marker == 0xda || marker == 0xd9 {
+			//Start of scan / end of image: no more meta-data
+			return nil
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		start := pos + 4
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return nil
+		}
+		if marker == 0xe1 && bytes.HasPrefix(data[start:end], exifHeader) {
+			return data[start+len(exifHeader) : end]
+		}
+		pos = end
+	}
+	return nil
+}
+
+// findPngTiff searches eXIf chunk
+func findPngTiff(data []byte) []byte {
+
+	pos := 8
+	for pos+8 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		chunkType := string(data[pos+4 : pos+8])
+		start := pos + 8
+		end := start + length
+		if length < 0 || end > len(data) {
+			return nil
+		}
+		if chunkType == "eXIf" {
+			return bytes.TrimPrefix(data[start:end], exifHeader)
+		}
+		if chunkType == "IDAT" || chunkType == "IEND" {
+			return nil
+		}
+		pos = end + 4 //CRC
+	}
+	return nil
+}
+
+// findWebpTiff searches EXIF chunk
+func findWebpTiff(data []byte) []byte {
+
+	pos := 12
+	for pos+8 <= len(data) {
+		chunkType := string(data[pos : pos+4])
+		length := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		start := pos + 8
+		end := start + length
+		if length < 0 || end > len(data) {
+			return nil
+		}
+		if chunkType == "EXIF" {
+			return bytes.TrimPrefix(data[start:end], exifHeader)
+		}
+		pos = end + length%2 //Padding
+	}
+	return nil
+}
+
+// tiffReader reads IFD entries of a TIFF structure
+type tiffReader struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+type ifdEntry struct {
+	tag       uint16
+	valueType uint16
+	count     uint32
+	value     []byte //Value or offset (4 bytes)
+}
+
+func parseTiff(data []byte) (*Exif, error) {
+
+	if len(data) < 8 {
+		return nil, ErrNoExif
+	}
+
+	t := &tiffReader{data: data}
+	switch string(data[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return nil, ErrNoExif
+	}
+
+	ifd0, err := t.readIfd(t.order.Uint32(data[4:]))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Exif{}
+	result.DateTime = parseDateTime(t.stringValue(ifd0[tagDateTime]), "")
+
+	if e, ok := ifd0[tagExifIfd]; ok {
+		if exifIfd, err := t.readIfd(t.uintValue(e)); err == nil {
+			result.DateTimeOriginal = parseDateTime(
+				t.stringValue(exifIfd[tagDateTimeOriginal]),
+				t.stringValue(exifIfd[tagOffsetTimeOriginal]))
+		}
+	}
+
+	if e, ok := ifd0[tagGpsIfd]; ok {
+		if gpsIfd, err := t.readIfd(t.uintValue(e)); err == nil {
+			t.readLocation(gpsIfd, result)
+		}
+	}
+
+	return result, nil
+}
+
+// readLocation reads latitude and longitude from GPS-IFD
+func (t *tiffReader) readLocation(gpsIfd map[uint16]ifdEntry, result *Exif) {
+
+	latitude, ok1 := t.degreesValue(gpsIfd[tagGpsLatitude])
+	longitude, ok2 := t.degreesValue(gpsIfd[tagGpsLongitude])
+	if !ok1 || !ok2 || latitude > 90 || longitude > 180 {
+		return
+	}
+	if t.stringValue(gpsIfd[tagGpsLatitudeRef]) == "S" {
+		latitude = -latitude
+	}
+	if t.stringValue(gpsIfd[tagGpsLongitudeRef]) == "W" {
+		longitude = -longitude
+	}
+	if latitude == 0 && longitude == 0 {
+		//Written by some devices without GPS fix
+		return
+	}
+
+	result.HasLocation = true
+	result.Latitude = latitude
+	result.Longitude = longitude
+}
+
+// degreesValue returns degrees from three RATIONAL values (degrees, minutes, seconds)
+func (t *tiffReader) degreesValue(e ifdEntry) (float64, bool) {
+
+	if e.valueType != typeRational || e.count != 3 {
+		return 0, false
+	}
+	offset := uint64(t.order.Uint32(e.value))
+	if offset+24 > uint64(len(t.data)) {
+		return 0, false
+	}
+
+	var values [3]float64
+	for i := range values {
+		numerator := t.order.Uint32(t.data[offset+uint64(i)*8:])
+		denominator := t.order.Uint32(t.data[offset+uint64(i)*8+4:])
+		if denominator == 0 {
+			if numerator != 0 {
+				return 0, false
+			}
+			continue
+		}
+		values[i] = float64(numerator) / float64(denominator)
+	}
+	return values[0] + values[1]/60 + values[2]/3600, true
+}
+
+// readIfd reads all entries of the IFD at offset
+func (t *tiffReader) readIfd(offset uint32) (map[uint16]ifdEntry, error) {
+
+	if uint64(offset)+2 > uint64(len(t.data)) {
+		return nil, ErrNoExif
+	}
+	count := int(t.order.Uint16(t.data[offset:]))
+	pos := int(offset) + 2
+
+	entries := make(map[uint16]ifdEntry, count)
+	for i := 0; i < count && pos+12 <= len(t.data); i++ {
+		e := ifdEntry{
+			tag:       t.order.Uint16(t.data[pos:]),
+			valueType: t.order.Uint16(t.data[pos+2:]),
+			count:     t.order.Uint32(t.data[pos+4:]),
+			value:     t.data[pos+8 : pos+12],
+		}
+		entries[e.tag] = e
+		pos += 12
+	}
+	return entries, nil
+}
+
+// stringValue returns ASCII values, empty string if type does not match
+func (t *tiffReader) stringValue(e ifdEntry) string {
+
+	if e.valueType != typeAscii || e.count == 0 {
+		return ""
+	}
+
+	var b []byte
+	if e.count <= 4 {
+		b = e.value[:e.count]
+	} else {
+		offset := uint64(t.order.Uint32(e.value))
+		if offset+uint64(e.count) > uint64(len(t.data)) {
+			return ""
+		}
+		b = t.data[offset : offset+uint64(e.count)]
+	}
+	return strings.TrimRight(string(b), "\x00 ")
+}
+
+// uintValue returns SHORT or LONG values
+func (t *tiffReader) uintValue(e ifdEntry) uint32 {
+	switch e.valueType {
+	case typeShort:
+		return uint32(t.order.Uint16(e.value))
+	case typeLong:
+		return t.order.Uint32(e.value)
+	}
+	return 0
+}
+
+// parseDateTime parses "YYYY:MM:DD HH:MM:SS" with optional offset "+HH:MM".
+// Without offset, local time is assumed (EXIF does not define a time zone).
+func parseDateTime(s string, offset string) time.Time {
+
+	if len(s) < 19 || strings.HasPrefix(s, "0000") {
+		return time.Time{}
+	}
+
+	if len(offset) == 6 {
+		if t, err := time.Parse("2006:01:02 15:04:05-07:00", s[:19]+offset); err == nil {
+			return t
+		}
+	}
+
+	t, err := time.ParseInLocation("2006:01:02 15:04:05", s[:19], time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
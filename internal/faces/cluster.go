@@ -0,0 +1,103 @@
+package faces
+
+import (
+	"math"
+)
+
+// NeighborFinder returns the indexes of all embeddings similar to embedding i (including i itself)
+type NeighborFinder func(i int) []int
+
+const (
+	noise      = -1
+	unassigned = -2
+)
+
+// DBSCAN assigns each of count embeddings to a cluster (0, 1, ...), -1 if it is noise (not part of a cluster).
+// An embedding having at least minPoints neighbors (including itself) is a core point,
+// clusters are all embeddings reachable through core points.
+func DBSCAN(count int, neighbors NeighborFinder, minPoints int) []int {
+
+	labels := make([]int, count)
+	for i := range labels {
+		labels[i] = unassigned
+	}
+
+	cluster := 0
+	for i := range count {
+		if labels[i] != unassigned {
+			continue
+		}
+
+		found := neighbors(i)
+		if len(found) < minPoints {
+			labels[i] = noise
+			continue
+		}
+
+		labels[i] = cluster
+		queue := append([]int{}, found...)
+		for len(queue) > 0 {
+			j := queue[0]
+			queue = queue[1:]
+
+			if labels[j] == noise {
+				labels[j] = cluster //Border point
+			}
+			if labels[j] != unassigned {
+				continue
+			}
+			labels[j] = cluster
+
+			if expand := neighbors(j); len(expand) >= minPoints {
+				queue = append(queue, expand...)
+			}
+		}
+		cluster++
+	}
+
+	return labels
+}
+
+// IndexNeighbors finds faces (keys[i]) using the index: up to SimilarFacesCount faces with a similarity of at least minSimilarity.
+// Faces not in keys are skipped.
+func IndexNeighbors(index *FaceIndex, keys []string, minSimilarity float64) NeighborFinder {
+
+	positions := make(map[string]int, len(keys))
+	for i, key := range keys {
+		positions[key] = i
+	}
+
+	return func(i int) []int {
+		found := []int{i}
+		for _, match := range index.SearchFace(keys[i], SimilarFacesCount) {
+			if match.Similarity < minSimilarity {
+				break
+			}
+			if j, ok := positions[match.Face]; ok {
+				found = append(found, j)
+			}
+		}
+		return found
+	}
+}
+
+// normalize returns the embedding scaled to length 1, the dot product is the cosine similarity then
+func normalize(e Embedding) Embedding {
+	norm := math.Sqrt(dot(e, e))
+	n := make(Embedding, len(e))
+	if norm == 0 {
+		return n
+	}
+	for i := range e {
+		n[i] = e[i] / norm
+	}
+	return n
+}
+
+func dot(a, b Embedding) float64 {
+	var sum float64
+	for i := range min(len(a), len(b)) {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
@@ -0,0 +1,311 @@
+package faces
+
+import (
+	"bufio"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/c8121/asset-storage/internal/config"
+	metadata_db_entity "github.com/c8121/asset-storage/internal/metadata-db-entity"
+	"github.com/c8121/asset-storage/internal/util"
+)
+
+// The index is stored in FaceIndexFileName (snapshot, see UpdateFaceIndex).
+// Faces extracted since are appended to FaceIndexLogFileName and added when the index is loaded,
+// so other processes (the REST server for example) see new faces without reading all embeddings again.
+const (
+	FaceIndexFileName    = "faces.hnsw"
+	FaceIndexLogFileName = "faces.hnsw.log"
+)
+
+var (
+	SimilarFacesCount     = 100  //Max. number of similar faces used by FindSimilarFaces callers (face search)
+	SimilarFacesThreshold = 0.45 //Min. similarity used by FindSimilarFaces callers (face search)
+
+	ErrNoFaceIndex = errors.New("no face index")
+
+	faceIndexLock  sync.Mutex //Guards faceIndexState and the index files
+	faceIndexState struct {
+		index        *FaceIndex
+		dir          string    //AssetFacesBaseDir the index was loaded from
+		snapshotTime time.Time //Modification time of the snapshot loaded
+		logOffset    int64     //Bytes of the log added to index
+	}
+)
+
+// faceIndexLogEntry is one line of FaceIndexLogFileName
+type faceIndexLogEntry struct {
+	Face      string
+	Embedding Embedding
+}
+
+// GetFaceIndex returns the index, loaded on first use. Faces added by other processes since are added.
+func GetFaceIndex() (*FaceIndex, error) {
+
+	faceIndexLock.Lock()
+	defer faceIndexLock.Unlock()
+
+	return refreshFaceIndex()
+}
+
+// UpdateFaceIndex adds all faces not indexed yet (reading embedding files), saves a snapshot and clears the log.
+// Returns the number of faces added.
+func UpdateFaceIndex() (*FaceIndex, int, error) {
+
+	faceIndexLock.Lock()
+	defer faceIndexLock.Unlock()
+
+	index, err := refreshFaceIndex()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	added := 0
+	walkEmbeddingFiles(config.AssetFacesBaseDir, func(key string, path string) {
+		if index.Contains(key) {
+			return
+		}
+		embedding, err := readEmbedding(path)
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			return
+		}
+		if index.Add(key, embedding) {
+			added++
+		}
+	})
+
+	if err = saveFaceIndex(index); err != nil {
+		return nil, 0, err
+	}
+	return index, added, nil
+}
+
+// FindSimilarFaces returns up to count faces with a similarity of at least minSimilarity
+// to the face ("<hash>/<index>"), most similar first. The face itself is not included.
+// Returns ErrNoFaceIndex if no faces were indexed.
+func FindSimilarFaces(face string, count int, minSimilarity float64) ([]FaceMatch, error) {
+
+	index, err := GetFaceIndex()
+	if err != nil {
+		return nil, err
+	}
+	if index.Len() == 0 {
+		return nil, ErrNoFaceIndex
+	}
+
+	var matches []FaceMatch
+	if index.Contains(face) {
+		matches = index.SearchFace(face, count)
+	} else {
+		//Not indexed (yet), search by embedding file
+		embedding, err := loadEmbedding(face)
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range index.Search(embedding, count+1) {
+			if match.Face != face {
+				matches = append(matches, match)
+			}
+		}
+	}
+
+	result := make([]FaceMatch, 0, len(matches))
+	for _, match := range matches {
+		if match.Similarity >= minSimilarity && len(result) < count {
+			result = append(result, match)
+		}
+	}
+	return result, nil
+}
+
+// addToFaceIndex appends a face to the log, the index is updated when used next time
+func addToFaceIndex(face string, embedding Embedding) {
+
+	faceIndexLock.Lock()
+	defer faceIndexLock.Unlock()
+
+	line, err := json.Marshal(faceIndexLogEntry{Face: face, Embedding: embedding})
+	if err != nil {
+		fmt.Printf("Failed to create json: %s\n", err)
+		return
+	}
+
+	file, err := os.OpenFile(getFaceIndexPath(FaceIndexLogFileName), os.O_CREATE|os.O_APPEND|os.O_WRONLY, FilePermissions)
+	if err != nil {
+		fmt.Printf("Failed to open face index log: %s\n", err)
+		return
+	}
+	defer util.CloseOrLog(file)
+
+	if _, err = file.Write(append(line, '\n')); err != nil {
+		fmt.Printf("Failed to write face index log: %s\n", err)
+	}
+}
+
+// refreshFaceIndex loads the index if not loaded or if the snapshot was replaced, adds new entries of the log.
+// faceIndexLock must be held.
+func refreshFaceIndex() (*FaceIndex, error) {
+
+	state := &faceIndexState
+
+	snapshotTime := time.Time{}
+	if stat, err := os.Stat(getFaceIndexPath(FaceIndexFileName)); err == nil {
+		snapshotTime = stat.ModTime()
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	if state.index == nil || state.dir != config.AssetFacesBaseDir || !snapshotTime.Equal(state.snapshotTime) {
+		index, err := loadFaceIndexSnapshot()
+		if err != nil {
+			return nil, err
+		}
+		state.index = index
+		state.dir = config.AssetFacesBaseDir
+		state.snapshotTime = snapshotTime
+		state.logOffset = 0
+	}
+
+	offset, err := replayFaceIndexLog(state.index, state.logOffset)
+	if err != nil {
+		return nil, err
+	}
+	state.logOffset = offset
+
+	return state.index, nil
+}
+
+// loadFaceIndexSnapshot reads FaceIndexFileName, returns an empty index if not existing
+func loadFaceIndexSnapshot() (*FaceIndex, error) {
+
+	file, err := os.Open(getFaceIndexPath(FaceIndexFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return NewFaceIndex(), nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer util.CloseOrLog(file)
+
+	index := NewFaceIndex()
+	if err = gob.NewDecoder(bufio.NewReader(file)).Decode(index); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", FaceIndexFileName, err)
+	}
+	index.rebuildKeys()
+	return index, nil
+}
+
+// replayFaceIndexLog adds all faces logged after offset, returns the new offset.
+// An incomplete last line (being written) is left for the next time.
+func replayFaceIndexLog(index *FaceIndex, offset int64) (int64, error) {
+
+	file, err := os.Open(getFaceIndexPath(FaceIndexLogFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	} else if err != nil {
+		return offset, err
+	}
+	defer util.CloseOrLog(file)
+
+	if stat, err := file.Stat(); err != nil {
+		return offset, err
+	} else if stat.Size() < offset {
+		offset = 0 //Log was cleared by another process, faces already added are skipped
+	}
+
+	if _, err = file.Seek(offset, io.SeekStart); err != nil {
+		return offset, err
+	}
+
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return offset, nil
+		} else if err != nil {
+			return offset, err
+		}
+		offset += int64(len(line))
+
+		var entry faceIndexLogEntry
+		if err = json.Unmarshal(line, &entry); err != nil {
+			fmt.Printf("Invalid face index log entry: %s\n", err)
+			continue
+		}
+		index.Add(entry.Face, entry.Embedding)
+	}
+}
+
+// saveFaceIndex writes the snapshot (to a temp-file first, replaces the file when complete) and clears the log.
+// faceIndexLock must be held.
+func saveFaceIndex(index *FaceIndex) error {
+
+	path := getFaceIndexPath(FaceIndexFileName)
+	if err := os.MkdirAll(filepath.Dir(path), FilePermissions); err != nil {
+		return err
+	}
+
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+
+	index.mu.RLock()
+	err = gob.NewEncoder(writer).Encode(index)
+	index.mu.RUnlock()
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err = os.Rename(path+".tmp", path); err != nil {
+		return err
+	}
+
+	if err = os.Truncate(getFaceIndexPath(FaceIndexLogFileName), 0); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	faceIndexState.index = index
+	faceIndexState.dir = config.AssetFacesBaseDir
+	faceIndexState.snapshotTime = stat.ModTime()
+	faceIndexState.logOffset = 0
+	return nil
+}
+
+// loadEmbedding reads the embedding file of a face ("<hash>/<index>")
+func loadEmbedding(face string) (Embedding, error) {
+
+	hash, idx, err := metadata_db_entity.ParseFaceKey(face)
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(getFacesDir(hash, false), strconv.Itoa(idx)+FaceEmbeddingExtension)
+	embedding, err := readEmbedding(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrFaceNotFound, face)
+	}
+	return embedding, err
+}
+
+func getFaceIndexPath(name string) string {
+	return filepath.Join(config.AssetFacesBaseDir, name)
+}
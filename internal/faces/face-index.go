@@ -0,0 +1,318 @@
+package faces
+
+import (
+	"container/heap"
+	"hash/fnv"
+	"math"
+	"sort"
+	"sync"
+)
+
+// FaceIndex is an approximate nearest-neighbour index of face embeddings (HNSW: hierarchical navigable small world graph).
+// Each face is a node, linked to its most similar faces on level 0 and, for fewer nodes, on higher levels.
+// Searching starts at the top level and descends towards the query, so only a small part of all faces is compared.
+type FaceIndex struct {
+	M              int //Max. links per node and level (2*M on level 0)
+	EfConstruction int //Candidates considered when inserting
+	Entry          int //Node to start searching, -1 if empty
+	MaxLevel       int
+	Nodes          []FaceIndexNode
+
+	mu   sync.RWMutex
+	keys map[string]int //Face key -> node
+}
+
+// FaceIndexNode is one face, Links are node indexes per level
+type FaceIndexNode struct {
+	Face   string    //"<hash>/<index>", see keyFromFacePath
+	Vector []float32 //Normalized embedding, the dot product is the cosine similarity
+	Links  [][]int32
+}
+
+// FaceMatch is a face found by FaceIndex.Search
+type FaceMatch struct {
+	Face       string
+	Similarity float64
+}
+
+const (
+	DefaultIndexM              = 16
+	DefaultIndexEfConstruction = 100
+	DefaultIndexEfSearch       = 64
+)
+
+// NewFaceIndex creates an empty index
+func NewFaceIndex() *FaceIndex {
+	return &FaceIndex{
+		M:              DefaultIndexM,
+		EfConstruction: DefaultIndexEfConstruction,
+		Entry:          -1,
+		keys:           make(map[string]int),
+	}
+}
+
+// Len returns the number of faces
+func (index *FaceIndex) Len() int {
+	index.mu.RLock()
+	defer index.mu.RUnlock()
+	return len(index.Nodes)
+}
+
+// Contains returns true if the face was added
+func (index *FaceIndex) Contains(face string) bool {
+	index.mu.RLock()
+	defer index.mu.RUnlock()
+	_, ok := index.keys[face]
+	return ok
+}
+
+// Faces returns all face keys, in order of insertion
+func (index *FaceIndex) Faces() []string {
+	index.mu.RLock()
+	defer index.mu.RUnlock()
+	faces := make([]string, len(index.Nodes))
+	for i, node := range index.Nodes {
+		faces[i] = node.Face
+	}
+	return faces
+}
+
+// Add inserts a face, returns false if it was added before
+func (index *FaceIndex) Add(face string, embedding Embedding) bool {
+
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	if _, ok := index.keys[face]; ok {
+		return false
+	}
+
+	level := index.randomLevel(face)
+	id := len(index.Nodes)
+	index.Nodes = append(index.Nodes, FaceIndexNode{
+		Face:   face,
+		Vector: toVector(embedding),
+		Links:  make([][]int32, level+1),
+	})
+	index.keys[face] = id
+
+	if index.Entry < 0 {
+		index.Entry = id
+		index.MaxLevel = level
+		return true
+	}
+
+	query := index.Nodes[id].Vector
+	entry := index.Entry
+	for l := index.MaxLevel; l > level; l-- {
+		entry = index.searchLayer(query, entry, 1, l)[0].node
+	}
+
+	for l := min(level, index.MaxLevel); l >= 0; l-- {
+		found := index.searchLayer(query, entry, index.EfConstruction, l)
+		links := index.selectLinks(found, index.maxLinks(l))
+		index.Nodes[id].Links[l] = links
+
+		for _, link := range links {
+			neighbour := &index.Nodes[link]
+			neighbour.Links[l] = append(neighbour.Links[l], int32(id))
+			if len(neighbour.Links[l]) > index.maxLinks(l) {
+				index.shrinkLinks(int(link), l)
+			}
+		}
+		entry = found[0].node
+	}
+
+	if level > index.MaxLevel {
+		index.Entry = id
+		index.MaxLevel = level
+	}
+	return true
+}
+
+// Search returns up to count faces most similar to the embedding, most similar first
+func (index *FaceIndex) Search(embedding Embedding, count int) []FaceMatch {
+	return index.search(toVector(embedding), count)
+}
+
+// SearchFace returns up to count faces most similar to a face of the index (not including the face itself).
+// Returns nil if the face is not in the index.
+func (index *FaceIndex) SearchFace(face string, count int) []FaceMatch {
+
+	index.mu.RLock()
+	id, ok := index.keys[face]
+	var vector []float32
+	if ok {
+		vector = index.Nodes[id].Vector
+	}
+	index.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	matches := make([]FaceMatch, 0, count)
+	for _, match := range index.search(vector, count+1) {
+		if match.Face != face && len(matches) < count {
+			matches = append(matches, match)
+		}
+	}
+	return matches
+}
+
+func (index *FaceIndex) search(query []float32, count int) []FaceMatch {
+
+	index.mu.RLock()
+	defer index.mu.RUnlock()
+
+	if index.Entry < 0 || count <= 0 {
+		return []FaceMatch{}
+	}
+
+	entry := index.Entry
+	for l := index.MaxLevel; l > 0; l-- {
+		entry = index.searchLayer(query, entry, 1, l)[0].node
+	}
+	found := index.searchLayer(query, entry, max(DefaultIndexEfSearch, count), 0)
+
+	matches := make([]FaceMatch, 0, min(count, len(found)))
+	for _, c := range found[:min(count, len(found))] {
+		matches = append(matches, FaceMatch{Face: index.Nodes[c.node].Face, Similarity: 1 - c.distance})
+	}
+	return matches
+}
+
+// searchLayer finds the ef nodes closest to query on one level, closest first (greedy search from entry)
+func (index *FaceIndex) searchLayer(query []float32, entry int, ef int, level int) []candidate {
+
+	visited := map[int]bool{entry: true}
+	first := candidate{entry, index.distance(query, entry)}
+	candidates := &candidateHeap{items: []candidate{first}}
+	results := &candidateHeap{items: []candidate{first}, farthestFirst: true}
+
+	for candidates.Len() > 0 {
+		closest := heap.Pop(candidates).(candidate)
+		if closest.distance > results.items[0].distance && results.Len() >= ef {
+			break
+		}
+
+		links := index.Nodes[closest.node].Links
+		if level >= len(links) {
+			continue
+		}
+		for _, link := range links[level] {
+			node := int(link)
+			if visited[node] {
+				continue
+			}
+			visited[node] = true
+
+			d := index.distance(query, node)
+			if results.Len() < ef || d < results.items[0].distance {
+				heap.Push(candidates, candidate{node, d})
+				heap.Push(results, candidate{node, d})
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	found := results.items
+	sort.Slice(found, func(i, j int) bool { return found[i].distance < found[j].distance })
+	return found
+}
+
+// selectLinks returns the closest count candidates (candidates are sorted, closest first)
+func (index *FaceIndex) selectLinks(candidates []candidate, count int) []int32 {
+	links := make([]int32, 0, count)
+	for _, c := range candidates[:min(count, len(candidates))] {
+		links = append(links, int32(c.node))
+	}
+	return links
+}
+
+// shrinkLinks keeps the closest maxLinks links of a node
+func (index *FaceIndex) shrinkLinks(node int, level int) {
+
+	vector := index.Nodes[node].Vector
+	links := index.Nodes[node].Links[level]
+	candidates := make([]candidate, len(links))
+	for i, link := range links {
+		candidates[i] = candidate{int(link), index.distance(vector, int(link))}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].distance < candidates[j].distance })
+	index.Nodes[node].Links[level] = index.selectLinks(candidates, index.maxLinks(level))
+}
+
+func (index *FaceIndex) maxLinks(level int) int {
+	if level == 0 {
+		return 2 * index.M
+	}
+	return index.M
+}
+
+// randomLevel returns the level of a new node: exponentially decaying probability,
+// derived from the face key so that replaying inserts creates the same index
+func (index *FaceIndex) randomLevel(face string) int {
+	h := fnv.New64a()
+	h.Write([]byte(face))
+	u := (float64(h.Sum64()>>11) + 0.5) / float64(1<<53)
+	return int(-math.Log(u) / math.Log(float64(index.M)))
+}
+
+// distance is 1 - cosine similarity
+func (index *FaceIndex) distance(query []float32, node int) float64 {
+	var sum float64
+	vector := index.Nodes[node].Vector
+	for i := range min(len(query), len(vector)) {
+		sum += float64(query[i]) * float64(vector[i])
+	}
+	return 1 - sum
+}
+
+// rebuildKeys restores the face key -> node map (not persisted)
+func (index *FaceIndex) rebuildKeys() {
+	index.keys = make(map[string]int, len(index.Nodes))
+	for i, node := range index.Nodes {
+		index.keys[node.Face] = i
+	}
+}
+
+func toVector(embedding Embedding) []float32 {
+	normalized := normalize(embedding)
+	vector := make([]float32, len(normalized))
+	for i, v := range normalized {
+		vector[i] = float32(v)
+	}
+	return vector
+}
+
+type candidate struct {
+	node     int
+	distance float64
+}
+
+// candidateHeap is a heap of candidates, closest on top (or farthest if farthestFirst)
+type candidateHeap struct {
+	items         []candidate
+	farthestFirst bool
+}
+
+func (h *candidateHeap) Len() int { return len(h.items) }
+
+func (h *candidateHeap) Less(i, j int) bool {
+	if h.farthestFirst {
+		return h.items[i].distance > h.items[j].distance
+	}
+	return h.items[i].distance < h.items[j].distance
+}
+
+func (h *candidateHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *candidateHeap) Push(x any) { h.items = append(h.items, x.(candidate)) }
+
+func (h *candidateHeap) Pop() any {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
@@ -2,63 +2,65 @@ package faces
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"math"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 
 	metadata_db_entity "github.com/c8121/asset-storage/internal/metadata-db-entity"
 	"github.com/c8121/asset-storage/internal/util"
 )
 
-func CalculateSimilarity(embeddings map[string]Embedding, threshold float64) {
+const similarityBatchSize = 1000 //Faces per transaction
 
-	cnt := len(embeddings)
-	paths := make([]string, cnt)
-	embeds := make([]Embedding, cnt)
+// CalculateSimilarity stores all pairs of faces with a similarity of at least threshold (see FinderByFace),
+// up to SimilarFacesCount per face, found using the index
+func CalculateSimilarity(index *FaceIndex, threshold float64) {
 
-	i := 0
-	for path, emb := range embeddings {
-		paths[i] = path
-		embeds[i] = emb
-		i++
-	}
+	keys := index.Faces()
+	cnt := len(keys)
+	stored := make(map[[2]string]bool)
 
-	for i := range cnt {
+	var tx *sql.Tx
+	for i, key := range keys {
 
-		ctx := context.Background()
-		tx, err := metadata_db_entity.GetDatabase().BeginTx(ctx, nil)
-		if err != nil {
-			fmt.Printf("Begin tx err: %v\n", err)
-			return
+		if tx == nil {
+			var err error
+			tx, err = metadata_db_entity.GetDatabase().BeginTx(context.Background(), nil)
+			if err != nil {
+				fmt.Printf("Begin tx err: %v\n", err)
+				return
+			}
 		}
 
-		for j := i + 1; j < cnt; j++ {
-			sim := CosineSimilarity(embeds[i], embeds[j])
-			if sim >= threshold {
-				//fmt.Printf("Similarity %s <-> %s = %f\n", paths[i], paths[j], sim)
-				p := strings.Index(paths[i], "/")
-				hashA := paths[i][:p]
-				faceA, _ := strconv.Atoi(paths[i][p+1:])
-				hashB := paths[j][:p]
-				faceB, _ := strconv.Atoi(paths[j][p+1:])
-
-				err := metadata_db_entity.AddFaceSimilarityTx(tx, hashA, faceA, hashB, faceB, sim)
-				if err != nil {
-					fmt.Println(err)
-				}
+		for _, match := range index.SearchFace(key, SimilarFacesCount) {
+			if match.Similarity < threshold {
+				break
 			}
-		}
+			pair := [2]string{min(key, match.Face), max(key, match.Face)}
+			if stored[pair] {
+				continue
+			}
+			stored[pair] = true
 
-		err = util.CommitOrLog(tx)
-		if err != nil {
-			fmt.Println(err)
+			hashA, faceA, _ := metadata_db_entity.ParseFaceKey(pair[0])
+			hashB, faceB, _ := metadata_db_entity.ParseFaceKey(pair[1])
+			err := metadata_db_entity.AddFaceSimilarityTx(tx, hashA, faceA, hashB, faceB, match.Similarity)
+			if err != nil {
+				fmt.Println(err)
+			}
 		}
 
-		fmt.Printf("%d/%d\n", i, cnt)
+		if (i+1)%similarityBatchSize == 0 || i == cnt-1 {
+			if err := util.CommitOrLog(tx); err != nil {
+				fmt.Println(err)
+			}
+			tx = nil
+			fmt.Printf("%d/%d\n", i+1, cnt)
+		}
 	}
 }
 
@@ -74,17 +76,8 @@ func CosineSimilarity(a, b Embedding) float64 {
 	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
 }
 
-func ReadEmbeddings(baseDir string) map[string]Embedding {
-
-	embedding := make(map[string]Embedding, 0)
-
-	doReadEmbeddings(baseDir, embedding)
-
-	return embedding
-
-}
-
-func doReadEmbeddings(dir string, into map[string]Embedding) {
+// walkEmbeddingFiles calls handler for each embedding file below dir, with the face key ("<hash>/<index>")
+func walkEmbeddingFiles(dir string, handler func(key string, path string)) {
 
 	entries, err := os.ReadDir(dir)
 	if err != nil {
@@ -101,27 +94,25 @@ func doReadEmbeddings(dir string, into map[string]Embedding) {
 		}
 
 		if stat.IsDir() {
-			doReadEmbeddings(path, into)
-		} else if strings.HasSuffix(e.Name(), FaceEmbeddingExtension) {
-
-			data, err := os.ReadFile(path)
-			if err != nil {
-				fmt.Printf("%s\n", err)
-				continue
-			}
+			walkEmbeddingFiles(path, handler)
+		} else if strings.HasSuffix(e.Name(), FaceEmbeddingExtension) && e.Name() != PersonsFileName {
+			handler(keyFromFacePath(path), path)
+		}
+	}
+}
 
-			embedding := Embedding{}
-			err = json.Unmarshal(data, &embedding)
-			if err != nil {
-				fmt.Printf("%s\n", err)
-				continue
-			}
+func readEmbedding(path string) (Embedding, error) {
 
-			key := keyFromFacePath(path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
 
-			into[key] = embedding
-		}
+	embedding := Embedding{}
+	if err = json.Unmarshal(data, &embedding); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
 	}
+	return embedding, nil
 }
 
 // Extract hash from .../hash[:2]/hash[2:]/faceIdx.ext
@@ -209,6 +209,8 @@ func saveExtractedFaces(sourceHash string, faces *Faces) {
 			err = os.WriteFile(embeddingFileName, embeddingJson, FilePermissions)
 			if err != nil {
 				fmt.Printf("Failed to write: %s\n", err)
+			} else {
+				addToFaceIndex(sourceHash+"/"+strconv.Itoa(idx), face.Embedding)
 			}
 		}
 	}
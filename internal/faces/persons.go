@@ -0,0 +1,314 @@
+package faces
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/c8121/asset-storage/internal/config"
+	metadata_db_entity "github.com/c8121/asset-storage/internal/metadata-db-entity"
+)
+
+type (
+	// JsonPersons is the content of PersonsFileName, the database is updated from it (see SyncPersons)
+	JsonPersons struct {
+		NextId  int64 //Ids are not reused
+		Persons []JsonPerson
+	}
+
+	JsonPerson struct {
+		Id    int64
+		Name  string `json:",omitempty"`
+		Faces []JsonPersonFace
+	}
+
+	JsonPersonFace struct {
+		Face  string //"<hash>/<index>", see FaceIndexNode
+		Fixed bool   `json:",omitempty"` //Assigned by user (merge, split), kept when clustering again
+	}
+)
+
+const (
+	PersonsFileName   = "persons.json"
+	MaxPersonNameSize = 128
+)
+
+var (
+	ErrPersonNotFound = errors.New("person not found")
+	ErrFaceNotFound   = errors.New("face not found")
+
+	personsLock sync.Mutex //Serializes load-change-save of PersonsFileName
+)
+
+// LoadPersons reads all persons, empty if no clustering was done yet
+func LoadPersons() (*JsonPersons, error) {
+
+	persons := &JsonPersons{NextId: 1, Persons: make([]JsonPerson, 0)}
+
+	buf, err := os.ReadFile(getPersonsFilePath())
+	if errors.Is(err, os.ErrNotExist) {
+		return persons, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(buf, persons); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", PersonsFileName, err)
+	}
+	return persons, nil
+}
+
+// GetPerson returns one person
+func (persons *JsonPersons) GetPerson(id int64) (*JsonPerson, error) {
+	for i := range persons.Persons {
+		if persons.Persons[i].Id == id {
+			return &persons.Persons[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %d", ErrPersonNotFound, id)
+}
+
+// SyncPersons copies all persons from PersonsFileName to database (after the database was rebuilt for example)
+func SyncPersons() error {
+	persons, err := LoadPersons()
+	if err != nil {
+		return err
+	}
+	return persons.updateDatabase()
+}
+
+// ClusterPersons assigns all faces of the index to persons by clustering their embeddings (see DBSCAN),
+// faces need a similarity of at least minSimilarity, a person at least minFaces faces.
+// Faces assigned by the user are kept. A cluster becomes the person most of its faces were assigned to before,
+// so names are kept when clustering again. Faces not part of a cluster are unassigned.
+func ClusterPersons(index *FaceIndex, minSimilarity float64, minFaces int) (*JsonPersons, error) {
+
+	keys := index.Faces()
+	sort.Strings(keys)
+
+	return changePersons(func(persons *JsonPersons) error {
+
+		labels := DBSCAN(len(keys), IndexNeighbors(index, keys, minSimilarity), max(1, minFaces))
+
+		//Previous assignments
+		previous := make(map[string]JsonPersonFace)
+		assignedTo := make(map[string]int64)
+		for _, person := range persons.Persons {
+			for _, face := range person.Faces {
+				previous[face.Face] = face
+				assignedTo[face.Face] = person.Id
+			}
+		}
+
+		//Cluster -> person most faces were assigned to (lowest id if equal), 0 for a new person
+		votes := make(map[int]map[int64]int)
+		for i, label := range labels {
+			if label == noise {
+				continue
+			}
+			if votes[label] == nil {
+				votes[label] = make(map[int64]int)
+			}
+			if id, ok := assignedTo[keys[i]]; ok {
+				votes[label][id]++
+			}
+		}
+		clusterPerson := make(map[int]int64)
+		for label := 0; label < len(votes); label++ {
+			var best int64
+			for id, count := range votes[label] {
+				if best == 0 || count > votes[label][best] || (count == votes[label][best] && id < best) {
+					best = id
+				}
+			}
+			if best == 0 {
+				best = persons.NextId
+				persons.NextId++
+				persons.Persons = append(persons.Persons, JsonPerson{Id: best})
+			}
+			clusterPerson[label] = best
+		}
+
+		faces := make(map[int64][]JsonPersonFace)
+		for i, key := range keys {
+			if face, ok := previous[key]; ok && face.Fixed {
+				faces[assignedTo[key]] = append(faces[assignedTo[key]], face)
+			} else if labels[i] != noise {
+				id := clusterPerson[labels[i]]
+				faces[id] = append(faces[id], JsonPersonFace{Face: key})
+			}
+		}
+
+		for i := range persons.Persons {
+			persons.Persons[i].Faces = faces[persons.Persons[i].Id]
+		}
+		return nil
+	})
+}
+
+// RenamePerson sets the name of a person (empty to remove it)
+func RenamePerson(id int64, name string) (*JsonPerson, error) {
+
+	name = strings.TrimSpace(name)
+	if len(name) > MaxPersonNameSize {
+		return nil, fmt.Errorf("name is too long (max. %d bytes)", MaxPersonNameSize)
+	}
+
+	var result JsonPerson
+	_, err := changePersons(func(persons *JsonPersons) error {
+		person, err := persons.GetPerson(id)
+		if err != nil {
+			return err
+		}
+		person.Name = name
+		result = *person
+		return nil
+	})
+	return &result, err
+}
+
+// MergePersons moves all faces of other to the person id, other is removed.
+// The name of other is used if the person has no name.
+func MergePersons(id int64, other int64) (*JsonPerson, error) {
+
+	if id == other {
+		return nil, fmt.Errorf("cannot merge person %d with itself", id)
+	}
+
+	var result JsonPerson
+	_, err := changePersons(func(persons *JsonPersons) error {
+		person, err := persons.GetPerson(id)
+		if err != nil {
+			return err
+		}
+		merged, err := persons.GetPerson(other)
+		if err != nil {
+			return err
+		}
+
+		if person.Name == "" {
+			person.Name = merged.Name
+		}
+		//Fixed, otherwise clustering would separate them again
+		person.Faces = append(person.Faces, merged.Faces...)
+		for i := range person.Faces {
+			person.Faces[i].Fixed = true
+		}
+		merged.Faces = nil
+		merged.Name = ""
+
+		result = *person
+		return nil
+	})
+	return &result, err
+}
+
+// SplitPerson moves faces of a person to a new person with the given name
+func SplitPerson(id int64, faces []string, name string) (*JsonPerson, error) {
+
+	name = strings.TrimSpace(name)
+	if len(faces) == 0 {
+		return nil, fmt.Errorf("no faces to split")
+	}
+	if len(name) > MaxPersonNameSize {
+		return nil, fmt.Errorf("name is too long (max. %d bytes)", MaxPersonNameSize)
+	}
+
+	var result JsonPerson
+	_, err := changePersons(func(persons *JsonPersons) error {
+		person, err := persons.GetPerson(id)
+		if err != nil {
+			return err
+		}
+
+		split := JsonPerson{Id: persons.NextId, Name: name}
+		for _, face := range faces {
+			i := slices.IndexFunc(person.Faces, func(f JsonPersonFace) bool { return f.Face == face })
+			if i < 0 {
+				return fmt.Errorf("%w: %s (person %d)", ErrFaceNotFound, face, id)
+			}
+			//Fixed, otherwise clustering would join them again
+			split.Faces = append(split.Faces, JsonPersonFace{Face: face, Fixed: true})
+			person.Faces = append(person.Faces[:i], person.Faces[i+1:]...)
+		}
+
+		persons.NextId++
+		persons.Persons = append(persons.Persons, split)
+		result = split
+		return nil
+	})
+	return &result, err
+}
+
+// changePersons loads persons, calls change, saves and updates the database.
+// Persons without faces and without name are removed.
+func changePersons(change func(persons *JsonPersons) error) (*JsonPersons, error) {
+
+	personsLock.Lock()
+	defer personsLock.Unlock()
+
+	persons, err := LoadPersons()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = change(persons); err != nil {
+		return nil, err
+	}
+
+	kept := make([]JsonPerson, 0, len(persons.Persons))
+	for _, person := range persons.Persons {
+		if len(person.Faces) == 0 && person.Name == "" {
+			continue
+		}
+		sort.Slice(person.Faces, func(i, j int) bool { return person.Faces[i].Face < person.Faces[j].Face })
+		kept = append(kept, person)
+	}
+	persons.Persons = kept
+
+	if err = persons.save(); err != nil {
+		return nil, err
+	}
+	return persons, persons.updateDatabase()
+}
+
+// save writes PersonsFileName (to a temp-file first, replaces the file when complete)
+func (persons *JsonPersons) save() error {
+
+	buf, err := json.MarshalIndent(persons, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := getPersonsFilePath()
+	if err = os.MkdirAll(filepath.Dir(path), FilePermissions); err != nil {
+		return err
+	}
+	if err = os.WriteFile(path+".tmp", buf, FilePermissions); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func (persons *JsonPersons) updateDatabase() error {
+
+	dbPersons := make([]metadata_db_entity.Person, 0, len(persons.Persons))
+	faces := make(map[int64][]string)
+	for _, person := range persons.Persons {
+		dbPersons = append(dbPersons, metadata_db_entity.Person{Id: person.Id, Name: person.Name})
+		for _, face := range person.Faces {
+			faces[person.Id] = append(faces[person.Id], face.Face)
+		}
+	}
+	return metadata_db_entity.ReplacePersons(dbPersons, faces)
+}
+
+func getPersonsFilePath() string {
+	return filepath.Join(config.AssetFacesBaseDir, PersonsFileName)
+}
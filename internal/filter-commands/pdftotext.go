@@ -0,0 +1,25 @@
+package filter_commands
+
+import (
+	"github.com/c8121/asset-storage/internal/util"
+)
+
+var (
+	PdfToTextBinPaths = []string{
+		"/usr/bin/pdftotext",
+		"/usr/local/bin/pdftotext",
+		"C:/Program Files/poppler*/Library/bin/pdftotext.exe",
+	}
+
+	PdfToTextBinPath = ""
+)
+
+// FindPdfToTextBin checks if one of PdfToTextBinPaths exists
+func FindPdfToTextBin() string {
+
+	if PdfToTextBinPath != "" {
+		return PdfToTextBinPath
+	}
+	PdfToTextBinPath = util.FindFile(PdfToTextBinPaths)
+	return PdfToTextBinPath
+}
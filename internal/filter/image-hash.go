@@ -0,0 +1,88 @@
+package filter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"math/bits"
+	"strconv"
+	"strings"
+
+	"github.com/c8121/asset-storage/internal/metadata"
+	"github.com/c8121/asset-storage/internal/storage"
+	"github.com/c8121/asset-storage/internal/util"
+	"golang.org/x/image/draw"
+)
+
+// DHash calculates the difference hash of an image:
+// Image is scaled to 9x8 gray pixels, each bit is set if a pixel is brighter than its right neighbour.
+// Similar images have hashes with a small Hamming distance.
+func DHash(img image.Image) uint64 {
+
+	gray := image.NewGray(image.Rect(0, 0, 9, 8))
+	draw.BiLinear.Scale(gray, gray.Bounds(), img, img.Bounds(), draw.Src, nil)
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if gray.GrayAt(x, y).Y > gray.GrayAt(x+1, y).Y {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// HammingDistance returns the number of different bits
+func HammingDistance(a uint64, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// FormatImageHash returns the hash as 16 hex digits
+func FormatImageHash(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
+
+// ParseImageHash parses 16 hex digits
+func ParseImageHash(s string) (uint64, error) {
+	return strconv.ParseUint(s, 16, 64)
+}
+
+// AddImageHashIfNotExists calculates the DHash of images and saves it to meta-data (as SystemUser, if not set before).
+// Returns the updated meta-data.
+func AddImageHashIfNotExists(meta *metadata.JsonAssetMetaData) (*metadata.JsonAssetMetaData, error) {
+
+	if meta.ImageHash != "" || !strings.HasPrefix(strings.ToLower(meta.MimeType), "image/") {
+		return meta, nil
+	}
+
+	reader, err := storage.Open(meta.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load asset: %w", err)
+	}
+	defer util.CloseOrLog(reader)
+
+	img, _, err := image.Decode(reader)
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) {
+			//Not supported by decoders, see image.go
+			return meta, nil
+		}
+		return nil, fmt.Errorf("failed to decode asset: %w", err)
+	}
+
+	imageHash, err := json.Marshal(FormatImageHash(DHash(img)))
+	if err != nil {
+		return nil, err
+	}
+
+	//Meta-data might have been changed while decoding
+	return metadata.UpdateMetaDataWith(meta.Hash, func(current *metadata.JsonAssetMetaData) map[string]json.RawMessage {
+		if current.ImageHash != "" {
+			return nil
+		}
+		return map[string]json.RawMessage{"ImageHash": imageHash}
+	}, metadata.SystemUser)
+}
@@ -0,0 +1,186 @@
+package geo
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/c8121/asset-storage/internal/config"
+	"github.com/c8121/asset-storage/internal/util"
+)
+
+// Place is a city used for offline reverse geocoding
+type Place struct {
+	Name        string
+	CountryCode string //ISO 3166-1 alpha-2
+	Country     string //Name of country, CountryCode if unknown
+	Latitude    float64
+	Longitude   float64
+}
+
+const (
+	EarthRadius = 6371.0 //km
+
+	// PlacesFileName is an optional file in config directory which replaces the bundled places,
+	// format of GeoNames "cities500.txt", "cities15000.txt" etc. (https://download.geonames.org/export/dump/)
+	PlacesFileName = "cities.txt"
+)
+
+var (
+	// MaxPlaceDistance is the max distance in km from a location to the nearest place
+	MaxPlaceDistance = 50.0
+
+	//go:embed cities.csv
+	bundledCities []byte
+
+	//go:embed countries.csv
+	bundledCountries []byte
+
+	placesIndex map[gridCell][]*Place
+	loadPlaces  sync.Once
+)
+
+// gridCell is a 1x1 degree cell of the places index
+type gridCell struct {
+	lat int
+	lon int
+}
+
+// Distance returns the great-circle distance in km (haversine formula)
+func Distance(lat1 float64, lon1 float64, lat2 float64, lon2 float64) float64 {
+	dLat := (lat2 - lat1) * math.Pi / 180
+	dLon := (lon2 - lon1) * math.Pi / 180
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*math.Pi/180)*math.Cos(lat2*math.Pi/180)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// FindPlace returns the nearest place within MaxPlaceDistance, nil if there is none
+func FindPlace(latitude float64, longitude float64) *Place {
+
+	loadPlaces.Do(func() {
+		placesIndex = readPlaces()
+	})
+
+	//Cells to check in each direction (longitude cells get smaller towards the poles)
+	latCells := int(math.Ceil(MaxPlaceDistance / 111.0))
+	lonCells := 180
+	if c := math.Cos(latitude * math.Pi / 180); c > 0.01 {
+		lonCells = min(180, int(math.Ceil(MaxPlaceDistance/(111.0*c))))
+	}
+
+	var nearest *Place
+	nearestDistance := MaxPlaceDistance
+	center := cellOf(latitude, longitude)
+	for lat := center.lat - latCells; lat <= center.lat+latCells; lat++ {
+		for lon := center.lon - lonCells; lon <= center.lon+lonCells; lon++ {
+			//Wrap at antimeridian
+			cell := gridCell{lat, ((lon+180)%360+360)%360 - 180}
+			for _, place := range placesIndex[cell] {
+				if d := Distance(latitude, longitude, place.Latitude, place.Longitude); d <= nearestDistance {
+					nearest = place
+					nearestDistance = d
+				}
+			}
+		}
+	}
+	return nearest
+}
+
+func cellOf(latitude float64, longitude float64) gridCell {
+	return gridCell{int(math.Floor(latitude)), int(math.Floor(longitude))}
+}
+
+// readPlaces reads places from config directory if available, bundled places otherwise
+func readPlaces() map[gridCell][]*Place {
+
+	countries := make(map[string]string)
+	if err := readCsv(bytes.NewReader(bundledCountries), 2, func(fields []string) error {
+		countries[fields[0]] = fields[1]
+		return nil
+	}); err != nil {
+		util.LogError(fmt.Errorf("failed to read bundled countries: %w", err))
+	}
+
+	index := make(map[gridCell][]*Place)
+	add := func(name string, countryCode string, latitude string, longitude string) error {
+		place := &Place{Name: name, CountryCode: countryCode, Country: countryCode}
+		if country, ok := countries[countryCode]; ok {
+			place.Country = country
+		}
+		var err error
+		if place.Latitude, err = strconv.ParseFloat(latitude, 64); err != nil {
+			return err
+		}
+		if place.Longitude, err = strconv.ParseFloat(longitude, 64); err != nil {
+			return err
+		}
+		cell := cellOf(place.Latitude, place.Longitude)
+		index[cell] = append(index[cell], place)
+		return nil
+	}
+
+	placesFile := filepath.Join(config.AssetStorageConfigDir, PlacesFileName)
+	if file, err := os.Open(placesFile); err == nil {
+		defer util.CloseOrLog(file)
+		fmt.Printf("Reading places from %s\n", placesFile)
+		//GeoNames: geonameid, name, asciiname, alternatenames, latitude, longitude, feature class, feature code, country code, ...
+		err = readTsv(file, 9, func(fields []string) error {
+			return add(fields[1], fields[8], fields[4], fields[5])
+		})
+		if err == nil {
+			return index
+		}
+		util.LogError(fmt.Errorf("failed to read %s, using bundled places: %w", placesFile, err))
+		index = make(map[gridCell][]*Place)
+	}
+
+	if err := readCsv(bytes.NewReader(bundledCities), 4, func(fields []string) error {
+		return add(fields[0], fields[1], fields[2], fields[3])
+	}); err != nil {
+		util.LogError(fmt.Errorf("failed to read bundled places: %w", err))
+	}
+	return index
+}
+
+// readCsv reads semicolon separated lines with header
+func readCsv(reader io.Reader, minFields int, handler func(fields []string) error) error {
+	return readLines(reader, ";", true, minFields, handler)
+}
+
+// readTsv reads tab separated lines without header
+func readTsv(reader io.Reader, minFields int, handler func(fields []string) error) error {
+	return readLines(reader, "\t", false, minFields, handler)
+}
+
+func readLines(reader io.Reader, separator string, skipHeader bool, minFields int, handler func(fields []string) error) error {
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if line == 1 && skipHeader {
+			continue
+		}
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		fields := strings.Split(scanner.Text(), separator)
+		if len(fields) < minFields {
+			return fmt.Errorf("line %d: expected %d fields, got %d", line, minFields, len(fields))
+		}
+		if err := handler(fields); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,49 @@
+package ingest
+
+import (
+	"fmt"
+
+	"github.com/c8121/asset-storage/internal/exif"
+	"github.com/c8121/asset-storage/internal/filter"
+	"github.com/c8121/asset-storage/internal/metadata"
+	metadata_db_entity "github.com/c8121/asset-storage/internal/metadata-db-entity"
+	text_extractor "github.com/c8121/asset-storage/internal/text-extractor"
+)
+
+// SidecarFunc applies data of sidecar files to meta-data, returns the updated meta-data
+type SidecarFunc func(meta *metadata.JsonAssetMetaData) *metadata.JsonAssetMetaData
+
+// Process completes the meta-data of an added file (cmd/add, upload, ssh-server) and writes it to database:
+// capture time and location from EXIF, data of sidecar files (applySidecars, nil if none, after EXIF which is preferred),
+// perceptual hash of images and text for full-text search.
+// Errors are logged (source is the file name used in messages).
+func Process(meta *metadata.JsonAssetMetaData, source string, applySidecars SidecarFunc) {
+
+	//Read capture time and location from EXIF
+	if updated, err := exif.AddExifIfNotExists(meta); err != nil {
+		fmt.Printf("Error reading EXIF '%s': %s\n", source, err)
+	} else {
+		meta = updated
+	}
+
+	if applySidecars != nil {
+		meta = applySidecars(meta)
+	}
+
+	//Perceptual hash to find similar images
+	if updated, err := filter.AddImageHashIfNotExists(meta); err != nil {
+		fmt.Printf("Error calculating image hash '%s': %s\n", source, err)
+	} else {
+		meta = updated
+	}
+
+	//Extract text for full-text search
+	if err := text_extractor.ExtractIfNotExists(meta); err != nil {
+		fmt.Printf("Error extracting text '%s': %s\n", source, err)
+	}
+
+	//Create/Update meta-data-database
+	if err := metadata_db_entity.AddMetaData(meta); err != nil {
+		fmt.Printf("Error adding meta-data to database '%s': %s\n", source, err)
+	}
+}
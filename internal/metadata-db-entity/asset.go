@@ -5,18 +5,33 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"os"
+	"strconv"
 	"time"
 
+	"github.com/c8121/asset-storage/internal/config"
+	"github.com/c8121/asset-storage/internal/geo"
 	"github.com/c8121/asset-storage/internal/metadata"
+	"github.com/c8121/asset-storage/internal/storage"
 	"github.com/c8121/asset-storage/internal/util"
 )
 
 type Asset struct {
-	Id       int64
-	Hash     string
-	MimeType int64
-	FileTime time.Time //Max of all origins
-	Name     int64     //Latest name
+	Id          int64
+	Hash        string
+	MimeType    int64
+	FileTime    time.Time //Max of all origins
+	Name        int64     //Latest name
+	Size        int64
+	Period      string        //Storage time-period, see storage.TimePeriodName
+	CaptureTime time.Time     //From EXIF, FileTime if not available
+	ImageHash   sql.NullInt64 //Perceptual hash (bits of uint64), see filter.DHash
+	Latitude    sql.NullFloat64
+	Longitude   sql.NullFloat64
+	City        string //Nearest place of location, see geo.FindPlace
+	Country     string
+	Rating      int
+	Deleted     bool
 }
 
 // AddMetaData adds/updates meta-data in database
@@ -29,14 +44,60 @@ func AddMetaData(jsonMeta *metadata.JsonAssetMetaData) error {
 	defer util.RollbackOrLog(tx)
 
 	err = AddMetaDataTx(tx, jsonMeta)
+	if err == nil {
+		err = util.CommitOrLog(tx)
+	}
 	if err != nil {
-		return err
+		//Rows cached during the failed transaction were rolled back
+		clearCaches()
+	}
+	return err
+}
+
+// AddMetaDataBatch adds/updates meta-data of many assets in one transaction.
+// If the transaction fails, each asset is added in a separate transaction to find the failing ones.
+// Returns one error per asset (nil if added successfully).
+func AddMetaDataBatch(jsonMetas []*metadata.JsonAssetMetaData) []error {
+
+	errs := make([]error, len(jsonMetas))
+	if len(jsonMetas) == 0 {
+		return errs
 	}
 
-	return util.CommitOrLog(tx)
+	err := func() error {
+		ctx := context.Background()
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer util.RollbackOrLog(tx)
+
+		for _, jsonMeta := range jsonMetas {
+			if err = AddMetaDataTx(tx, jsonMeta); err != nil {
+				return err
+			}
+		}
+		return util.CommitOrLog(tx)
+	}()
+
+	if err == nil {
+		return errs
+	}
+
+	//Rows cached during the failed transaction were rolled back
+	clearCaches()
+	if len(jsonMetas) == 1 {
+		errs[0] = err
+		return errs
+	}
+	for i, jsonMeta := range jsonMetas {
+		errs[i] = AddMetaData(jsonMeta)
+	}
+	return errs
 }
 
-// AddMetaDataTx adds/updates meta-data in database
+// AddMetaDataTx adds/updates meta-data in database.
+// Times are stored in UTC, so they can be compared as text (see metadata_db.DbTimeFormat).
 func AddMetaDataTx(tx *sql.Tx, jsonMeta *metadata.JsonAssetMetaData) error {
 
 	var asset = &Asset{Hash: jsonMeta.Hash}
@@ -51,13 +112,56 @@ func AddMetaDataTx(tx *sql.Tx, jsonMeta *metadata.JsonAssetMetaData) error {
 	}
 
 	asset.MimeType = mimeType.Id
+	asset.Size = jsonMeta.Size
+
+	if storagePath, err := storage.FindByHash(jsonMeta.Hash); err == nil {
+		asset.Period = storage.TimePeriodFromStoragePath(storagePath)
+		if asset.Size == 0 && !config.UseGzip {
+			//Meta-data created before size was added
+			if stat, err := os.Stat(storagePath); err == nil {
+				asset.Size = stat.Size()
+			}
+		}
+	}
 
 	latestOrigin := metadata.GetLatestOrigin(jsonMeta)
 	if latestOrigin != nil {
-		asset.FileTime = latestOrigin.FileTime
-		asset.Name = GetFileNameIdTx(tx, latestOrigin.Name, true)
+		asset.FileTime = latestOrigin.FileTime.UTC()
+	}
+	if name := metadata.GetDisplayName(jsonMeta); name != "" {
+		asset.Name = GetFileNameIdTx(tx, name, true)
+	}
+
+	asset.CaptureTime = jsonMeta.CaptureTime.UTC()
+	if asset.CaptureTime.IsZero() {
+		asset.CaptureTime = asset.FileTime
 	}
 
+	asset.ImageHash = sql.NullInt64{}
+	if jsonMeta.ImageHash != "" {
+		if hash, err := strconv.ParseUint(jsonMeta.ImageHash, 16, 64); err == nil {
+			asset.ImageHash = sql.NullInt64{Int64: int64(hash), Valid: true}
+		} else {
+			fmt.Printf("Invalid image hash '%s': %s\n", jsonMeta.ImageHash, err)
+		}
+	}
+
+	asset.Latitude = sql.NullFloat64{}
+	asset.Longitude = sql.NullFloat64{}
+	asset.City = ""
+	asset.Country = ""
+	if jsonMeta.Location != nil {
+		asset.Latitude = sql.NullFloat64{Float64: jsonMeta.Location.Latitude, Valid: true}
+		asset.Longitude = sql.NullFloat64{Float64: jsonMeta.Location.Longitude, Valid: true}
+		if place := geo.FindPlace(jsonMeta.Location.Latitude, jsonMeta.Location.Longitude); place != nil {
+			asset.City = place.Name
+			asset.Country = place.Country
+		}
+	}
+
+	asset.Rating = jsonMeta.Rating
+	asset.Deleted = jsonMeta.Deleted
+
 	err = SaveTx(tx, asset)
 	if err != nil {
 		return err
@@ -70,12 +174,16 @@ func AddMetaDataTx(tx *sql.Tx, jsonMeta *metadata.JsonAssetMetaData) error {
 
 	for _, jsonOrigin := range jsonMeta.Origins {
 
+		if jsonOrigin.Hidden {
+			continue
+		}
+
 		var origin = &Origin{
 			Asset:    asset.Id,
 			Name:     GetFileNameIdTx(tx, jsonOrigin.Name, true),
 			Path:     GetPathItemIdTx(tx, jsonOrigin.Path, true),
 			Owner:    GetOwnerIdTx(tx, jsonOrigin.Owner, true),
-			FileTime: jsonOrigin.FileTime,
+			FileTime: jsonOrigin.FileTime.UTC(),
 		}
 		err = SaveTx(tx, origin)
 		if err != nil {
@@ -83,7 +191,22 @@ func AddMetaDataTx(tx *sql.Tx, jsonMeta *metadata.JsonAssetMetaData) error {
 		}
 	}
 
-	return nil
+	err = SetAssetTagsTx(tx, asset, jsonMeta.Tags)
+	if err != nil {
+		return err
+	}
+
+	err = SetAssetRelationsTx(tx, asset, jsonMeta.Relations)
+	if err != nil {
+		return err
+	}
+
+	err = SetAssetDigestsTx(tx, asset, jsonMeta.Digests)
+	if err != nil {
+		return err
+	}
+
+	return UpdateSearchIndexTx(tx, asset, jsonMeta)
 }
 
 // GetAssetId gets Asset-ID from db
@@ -133,7 +256,7 @@ func (a *Asset) Get(insertIfNotExists bool) error {
 }
 
 func (a *Asset) GetSelectQuery() string {
-	return "SELECT id, hash, mimeType, fileTime, name FROM asset WHERE hash = ?;"
+	return "SELECT id, hash, mimeType, fileTime, name, size, period, captureTime, imageHash, latitude, longitude, city, country, rating, deleted FROM asset WHERE hash = ?;"
 }
 
 func (a *Asset) GetSelectQueryArgs() []any {
@@ -141,35 +264,60 @@ func (a *Asset) GetSelectQueryArgs() []any {
 }
 
 func (a *Asset) Scan(rows *sql.Rows) error {
-	return rows.Scan(&a.Id, &a.Hash, &a.MimeType, &a.FileTime, &a.Name)
+	return rows.Scan(&a.Id, &a.Hash, &a.MimeType, &a.FileTime, &a.Name, &a.Size, &a.Period, &a.CaptureTime, &a.ImageHash, &a.Latitude, &a.Longitude, &a.City, &a.Country, &a.Rating, &a.Deleted)
 }
 
 func (a *Asset) GetInsertQuery() string {
-	return "INSERT INTO asset(hash, mimeType, fileTime, name) VALUES(?,?,?,?);"
+	return "INSERT INTO asset(hash, mimeType, fileTime, name, size, period, captureTime, imageHash, latitude, longitude, city, country, rating, deleted) VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?);"
 }
 
 func (a *Asset) GetUpdateQuery() string {
-	return "UPDATE asset SET hash=?, mimeType=?, fileTime=?, name=? WHERE id = ?;"
+	return "UPDATE asset SET hash=?, mimeType=?, fileTime=?, name=?, size=?, period=?, captureTime=?, imageHash=?, latitude=?, longitude=?, city=?, country=?, rating=?, deleted=? WHERE id = ?;"
 }
 
 func (a *Asset) GetUpdateQueryArgs() []any {
-	return []any{&a.Hash, &a.MimeType, &a.FileTime, &a.Name, &a.Id}
+	return []any{&a.Hash, &a.MimeType, &a.FileTime, &a.Name, &a.Size, &a.Period, &a.CaptureTime, &a.ImageHash, &a.Latitude, &a.Longitude, &a.City, &a.Country, &a.Rating, &a.Deleted, &a.Id}
 }
 
 func (a *Asset) Exec(stmt *sql.Stmt) (sql.Result, error) {
-	return stmt.Exec(&a.Hash, &a.MimeType, &a.FileTime, &a.Name, &a.Id)
+	return stmt.Exec(&a.Hash, &a.MimeType, &a.FileTime, &a.Name, &a.Size, &a.Period, &a.CaptureTime, &a.ImageHash, &a.Latitude, &a.Longitude, &a.City, &a.Country, &a.Rating, &a.Deleted, &a.Id)
 }
 
 func (a *Asset) SetId(id int64) {
 	a.Id = id
 }
 
+func (a *Asset) GetMigrations() []Migration {
+	return []Migration{
+		addColumnMigration(1, "asset", "size", "integer DEFAULT 0"),
+		addColumnMigration(2, "asset", "period", "TEXT(16) DEFAULT ''"),
+		addColumnMigration(3, "asset", "captureTime", "DATETIME",
+			"UPDATE asset SET captureTime = fileTime;"),
+		addColumnMigration(4, "asset", "imageHash", "integer"),
+		addColumnMigration(5, "asset", "latitude", "REAL"),
+		addColumnMigration(6, "asset", "longitude", "REAL"),
+		addColumnMigration(7, "asset", "city", "TEXT(128) DEFAULT ''"),
+		addColumnMigration(8, "asset", "country", "TEXT(128) DEFAULT ''"),
+		addColumnMigration(9, "asset", "rating", "integer DEFAULT 0"),
+		addColumnMigration(10, "asset", "deleted", "integer DEFAULT 0"),
+	}
+}
+
 func (a *Asset) GetCreateQueries() []string {
 	return []string{
-		"CREATE TABLE IF NOT EXISTS asset(id integer PRIMARY KEY, hash TEXT(64), mimeType integer, fileTime DATETIME, name integer);",
+		"CREATE TABLE IF NOT EXISTS asset(id integer PRIMARY KEY, hash TEXT(64), mimeType integer, fileTime DATETIME, name integer, size integer DEFAULT 0, period TEXT(16) DEFAULT '', captureTime DATETIME, imageHash integer, latitude REAL, longitude REAL, city TEXT(128) DEFAULT '', country TEXT(128) DEFAULT '', rating integer DEFAULT 0, deleted integer DEFAULT 0);",
 		"CREATE INDEX IF NOT EXISTS idx_asset_hash on asset(hash);",
 		"CREATE INDEX IF NOT EXISTS idx_asset_mimeType on asset(mimeType);",
 		"CREATE INDEX IF NOT EXISTS idx_asset_fileTime on asset(fileTime);",
 		"CREATE INDEX IF NOT EXISTS idx_asset_name on asset(name);",
+		"CREATE INDEX IF NOT EXISTS idx_asset_size on asset(size);",
+		"CREATE INDEX IF NOT EXISTS idx_asset_period on asset(period);",
+		"CREATE INDEX IF NOT EXISTS idx_asset_captureTime on asset(captureTime);",
+		"CREATE INDEX IF NOT EXISTS idx_asset_imageHash on asset(imageHash);",
+		"CREATE INDEX IF NOT EXISTS idx_asset_location on asset(latitude, longitude);",
+		"CREATE INDEX IF NOT EXISTS idx_asset_city on asset(city);",
+		"CREATE INDEX IF NOT EXISTS idx_asset_country on asset(country);",
+		"CREATE INDEX IF NOT EXISTS idx_asset_rating on asset(rating);",
+		"CREATE INDEX IF NOT EXISTS idx_asset_deleted on asset(deleted);",
 	}
 }
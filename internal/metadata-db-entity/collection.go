@@ -45,11 +45,11 @@ func (c *Collection) GetInsertQuery() string {
 }
 
 func (c *Collection) GetUpdateQuery() string {
-	return "UPDATE asset SET hash=?, name=?, created=? WHERE id = ?;"
+	return "UPDATE collection SET hash=?, name=?, created=? WHERE id = ?;"
 }
 
 func (c *Collection) GetUpdateQueryArgs() []any {
-	return []any{&c.Hash, &c.Name, &c.Created}
+	return []any{&c.Hash, &c.Name, &c.Created, &c.Id}
 }
 
 func (c *Collection) Exec(stmt *sql.Stmt) (sql.Result, error) {
@@ -0,0 +1,32 @@
+package metadata_db_entity
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// DbProperty is a named value describing the state of the database (like time of last update)
+type DbProperty struct {
+}
+
+// GetDbProperty returns the value of a property, empty string if not set
+func GetDbProperty(name string) (string, error) {
+	var value string
+	err := db.QueryRow("SELECT value FROM dbProperty WHERE name = ?;", name).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return value, err
+}
+
+// SetDbProperty creates or replaces the value of a property
+func SetDbProperty(name string, value string) error {
+	_, err := db.Exec("INSERT OR REPLACE INTO dbProperty(name, value) VALUES(?, ?);", name, value)
+	return err
+}
+
+func (p *DbProperty) GetCreateQueries() []string {
+	return []string{
+		"CREATE TABLE IF NOT EXISTS dbProperty(name TEXT(256) PRIMARY KEY, value TEXT);",
+	}
+}
@@ -0,0 +1,64 @@
+package metadata_db_entity
+
+import (
+	"database/sql"
+	"sort"
+
+	"github.com/c8121/asset-storage/internal/util"
+)
+
+// AssetDigest is an additional content digest of an asset (md5, sha1, blake3), see storage.DigestAlgorithms.
+// The SHA-256 content hash is asset.hash.
+type AssetDigest struct {
+	Asset     int64
+	Algorithm string
+	Digest    string
+}
+
+// SetAssetDigestsTx replaces all digests of an asset (algorithm -> hex digest)
+func SetAssetDigestsTx(tx *sql.Tx, asset *Asset, digests map[string]string) error {
+
+	stmt, err := tx.Prepare("DELETE FROM assetDigest WHERE asset = ?;")
+	if err != nil {
+		return err
+	}
+	defer util.CloseOrLog(stmt)
+
+	if _, err = stmt.Exec(asset.Id); err != nil {
+		return err
+	}
+
+	algorithms := make([]string, 0, len(digests))
+	for algorithm := range digests {
+		algorithms = append(algorithms, algorithm)
+	}
+	sort.Strings(algorithms)
+
+	for _, algorithm := range algorithms {
+		err = InsertTx(tx, &AssetDigest{Asset: asset.Id, Algorithm: algorithm, Digest: digests[algorithm]})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *AssetDigest) GetInsertQuery() string {
+	return "INSERT INTO assetDigest(asset, algorithm, digest) VALUES(?,?,?);"
+}
+
+func (d *AssetDigest) Exec(stmt *sql.Stmt) (sql.Result, error) {
+	return stmt.Exec(&d.Asset, &d.Algorithm, &d.Digest)
+}
+
+func (d *AssetDigest) SetId(id int64) {
+}
+
+func (d *AssetDigest) GetCreateQueries() []string {
+	return []string{
+		"CREATE TABLE IF NOT EXISTS assetDigest(asset integer, algorithm TEXT(16), digest TEXT(128));",
+		"CREATE INDEX IF NOT EXISTS idx_assetDigest_asset on assetDigest(asset);",
+		"CREATE INDEX IF NOT EXISTS idx_assetDigest_digest on assetDigest(algorithm, digest);",
+	}
+}
@@ -53,6 +53,13 @@ func AddFaceSimilarityTx(tx *sql.Tx, hashA string, faceA int, hashB string, face
 	return nil
 }
 
+// ClearFaceSimilarities removes all face-similarities.
+// Can be rebuilt from face embeddings, see faces.CalculateSimilarity
+func ClearFaceSimilarities() error {
+	_, err := db.Exec("DELETE FROM faceSimilarity;")
+	return err
+}
+
 func (f *FaceSimilarity) GetId() int64 {
 	return f.Id
 }
@@ -70,7 +77,7 @@ func (f *FaceSimilarity) GetSelectQueryArgs() []any {
 }
 
 func (f *FaceSimilarity) Scan(rows *sql.Rows) error {
-	return rows.Scan(&f.Id, &f.AssetA, &f.FaceA, &f.AssetB, &f.FaceB)
+	return rows.Scan(&f.Id, &f.AssetA, &f.FaceA, &f.AssetB, &f.FaceB, &f.Score)
 }
 
 func (f *FaceSimilarity) GetInsertQuery() string {
@@ -78,7 +85,7 @@ func (f *FaceSimilarity) GetInsertQuery() string {
 }
 
 func (f *FaceSimilarity) Exec(stmt *sql.Stmt) (sql.Result, error) {
-	return stmt.Exec(&f.AssetA, &f.FaceA, &f.AssetB, &f.FaceB, &f.Score, &f.Id)
+	return stmt.Exec(&f.AssetA, &f.FaceA, &f.AssetB, &f.FaceB, &f.Score)
 }
 
 func (f *FaceSimilarity) SetId(id int64) {
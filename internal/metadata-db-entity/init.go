@@ -1,19 +1,44 @@
 package metadata_db_entity
 
-func AutoCreateEntities() {
+import "github.com/c8121/asset-storage/internal/util"
 
-	var autoCreateables = []AutoCreatable{
+var (
+	//All entities, in order of creation
+	entities = []AutoCreatable{
 		&MimeType{},
 		&FileName{},
 		&PathItem{},
+		&PathItemClosure{},
 		&Owner{},
 		&Asset{},
 		&Origin{},
 		&Collection{},
 		&FaceSimilarity{},
+		&SearchIndex{},
+		&Tag{},
+		&AssetTag{},
+		&AssetRelation{},
+		&AssetDigest{},
+		&Person{},
+		&PersonFace{},
+		&DbProperty{},
 	}
-	for _, autoCreateable := range autoCreateables {
-		AutoCreate(autoCreateable)
-	}
+)
+
+// Migrate creates all entities or migrates them to the latest version, see MigrateEntities
+func Migrate() error {
+	return MigrateEntities(entities...)
+}
+
+// AutoCreateEntities is Migrate, panics on error
+func AutoCreateEntities() {
+	util.PanicOnError(Migrate(), "Failed to init entities")
+}
 
+// clearCaches removes all cached rows (ids), required if they might have been removed or rolled back
+func clearCaches() {
+	clear(tagCache)
+	clear(mimeTypeCache)
+	clear(pathItemCache)
+	clear(OwnerCache)
 }
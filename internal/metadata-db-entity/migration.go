@@ -0,0 +1,149 @@
+package metadata_db_entity
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/c8121/asset-storage/internal/util"
+)
+
+// Migration changes the schema of an entity from Version-1 to Version
+type Migration struct {
+	Version     int
+	Description string
+	Apply       func(tx *sql.Tx) error
+}
+
+// Migratable entities have ordered migrations, Version starting with 1.
+// GetCreateQueries always creates the latest version, migrations are required for existing databases only.
+type Migratable interface {
+	AutoCreatable
+	GetMigrations() []Migration
+}
+
+var (
+	ErrSchemaTooNew = errors.New("database schema is newer than supported by this program")
+)
+
+// MigrateEntities applies all missing migrations and creates missing tables and indexes,
+// one transaction per entity. Fails with ErrSchemaTooNew if the database was migrated by a newer program.
+func MigrateEntities(entities ...AutoCreatable) error {
+
+	if _, err := db.Exec("CREATE TABLE IF NOT EXISTS schema_version(entity TEXT(64) PRIMARY KEY, version integer);"); err != nil {
+		return err
+	}
+
+	for _, entity := range entities {
+		if err := migrate(entity); err != nil {
+			return fmt.Errorf("failed to migrate %s: %w", entityName(entity), err)
+		}
+	}
+	return nil
+}
+
+// migrate applies migrations of one entity
+func migrate(entity AutoCreatable) error {
+
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer util.RollbackOrLog(tx)
+
+	name := entityName(entity)
+
+	var migrations []Migration
+	if migratable, ok := entity.(Migratable); ok {
+		migrations = migratable.GetMigrations()
+	}
+	latest := 0
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			return fmt.Errorf("migration %d has version %d, versions must start with 1 without gaps", i+1, migration.Version)
+		}
+		latest = migration.Version
+	}
+
+	var version int
+	err = tx.QueryRow("SELECT version FROM schema_version WHERE entity = ?;", name).Scan(&version)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if version > latest {
+		return fmt.Errorf("%w: %s has version %d, supported up to %d", ErrSchemaTooNew, name, version, latest)
+	}
+
+	for _, migration := range migrations[version:] {
+		fmt.Printf("Migrate %s to version %d: %s\n", name, migration.Version, migration.Description)
+		if err = migration.Apply(tx); err != nil {
+			return fmt.Errorf("version %d: %w", migration.Version, err)
+		}
+	}
+
+	for _, query := range entity.GetCreateQueries() {
+		if _, err = tx.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	if _, err = tx.Exec("INSERT OR REPLACE INTO schema_version(entity, version) VALUES(?, ?);", name, latest); err != nil {
+		return err
+	}
+
+	return util.CommitOrLog(tx)
+}
+
+// GetSchemaVersion returns the version of an entity stored in database (0 if unknown)
+func GetSchemaVersion(entity AutoCreatable) (int, error) {
+	var version int
+	err := db.QueryRow("SELECT version FROM schema_version WHERE entity = ?;", entityName(entity)).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return version, err
+}
+
+// entityName returns the type name of an entity, used as key in schema_version
+func entityName(entity AutoCreatable) string {
+	t := reflect.TypeOf(entity)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+// addColumnMigration adds a column to an existing table and runs updates to fill it.
+// Does nothing if the table does not exist yet (will be created by GetCreateQueries)
+// or the column exists already (database created before migrations were introduced).
+func addColumnMigration(version int, table string, column string, definition string, updates ...string) Migration {
+	return Migration{
+		Version:     version,
+		Description: fmt.Sprintf("Add column %s.%s", table, column),
+		Apply: func(tx *sql.Tx) error {
+
+			var tableCount, columnCount int
+			err := tx.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?;", table).Scan(&tableCount)
+			if err != nil || tableCount == 0 {
+				return err
+			}
+			err = tx.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?;", table, column).Scan(&columnCount)
+			if err != nil || columnCount > 0 {
+				return err
+			}
+
+			if _, err = tx.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + definition + ";"); err != nil {
+				return err
+			}
+			for _, update := range updates {
+				if _, err = tx.Exec(update); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,78 @@
+package metadata_db_entity
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/c8121/asset-storage/internal/util"
+)
+
+// PathItemClosure contains one row for each PathItem and each of its ancestors (including itself with depth 0).
+// Used to find all assets below a PathItem.
+type PathItemClosure struct {
+}
+
+// addPathItemClosureTx adds a new PathItem to the closure table, parent must exist already
+func addPathItemClosureTx(tx *sql.Tx, pathItem *PathItem) error {
+
+	stmt, err := tx.Prepare("INSERT INTO pathItemClosure(ancestor, descendant, depth) " +
+		"SELECT ancestor, ?, depth + 1 FROM pathItemClosure WHERE descendant = ? " +
+		"UNION ALL SELECT ?, ?, 0;")
+	if err != nil {
+		return err
+	}
+	defer util.CloseOrLog(stmt)
+
+	_, err = stmt.Exec(pathItem.Id, pathItem.Parent, pathItem.Id, pathItem.Id)
+	return err
+}
+
+// RebuildPathItemClosure re-creates the closure table from all PathItems
+func RebuildPathItemClosure() error {
+
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer util.RollbackOrLog(tx)
+
+	if _, err = tx.Exec("DELETE FROM pathItemClosure;"); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec("INSERT INTO pathItemClosure(ancestor, descendant, depth) " +
+		"WITH RECURSIVE c(ancestor, descendant, depth) AS (" +
+		"SELECT id, id, 0 FROM pathItem " +
+		"UNION ALL " +
+		"SELECT c.ancestor, p.id, c.depth + 1 FROM c INNER JOIN pathItem p ON p.parent = c.descendant" +
+		") SELECT ancestor, descendant, depth FROM c;")
+	if err != nil {
+		return err
+	}
+
+	count, _ := result.RowsAffected()
+	fmt.Printf("Rebuilt path-item closure: %d rows\n", count)
+
+	return util.CommitOrLog(tx)
+}
+
+// RebuildPathItemClosureIfEmpty rebuilds the closure table if it was not filled before (database created by older version)
+func RebuildPathItemClosureIfEmpty() error {
+
+	var missing bool
+	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM pathItem) " +
+		"AND NOT EXISTS(SELECT 1 FROM pathItemClosure);").Scan(&missing)
+	if err != nil || !missing {
+		return err
+	}
+	return RebuildPathItemClosure()
+}
+
+func (c *PathItemClosure) GetCreateQueries() []string {
+	return []string{
+		"CREATE TABLE IF NOT EXISTS pathItemClosure(ancestor integer, descendant integer, depth integer, PRIMARY KEY(ancestor, descendant));",
+		"CREATE INDEX IF NOT EXISTS idx_pathItemClosure_descendant on pathItemClosure(descendant);",
+	}
+}
@@ -106,9 +106,12 @@ func GetPathItemTx(tx *sql.Tx, path string, createIfNotExists bool) (*PathItem,
 		cachedItem, ok := pathItemCache[cacheKey]
 		if !ok {
 			pathItem = &PathItem{Parent: parent, Name: name}
-			err := GetTx(tx, createIfNotExists, pathItem)
-			if errors.Is(err, ErrNotFound) {
-				return nil, err
+			err := LoadTx(tx, pathItem)
+			if errors.Is(err, ErrNotFound) && createIfNotExists {
+				//Insert and keep closure table up to date
+				if err = InsertTx(tx, pathItem); err == nil {
+					err = addPathItemClosureTx(tx, pathItem)
+				}
 			}
 			if err != nil {
 				return nil, err
@@ -0,0 +1,122 @@
+package metadata_db_entity
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/c8121/asset-storage/internal/util"
+)
+
+// Person is a cluster of faces, see faces.ClusterPersons.
+// Persons are stored in a file (faces.PersonsFileName) and copied to database by ReplacePersons.
+type Person struct {
+	Id   int64
+	Name string
+}
+
+// PersonFace assigns a face (index within the faces of an asset) to a person
+type PersonFace struct {
+	Person int64
+	Asset  int64
+	Face   int
+}
+
+// ReplacePersons replaces all persons and their faces.
+// faces maps person id to face keys ("<hash>/<index>"), faces of assets not in database are skipped.
+func ReplacePersons(persons []Person, faces map[int64][]string) error {
+
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer util.RollbackOrLog(tx)
+
+	if _, err = tx.Exec("DELETE FROM personFace;"); err != nil {
+		return err
+	}
+	if _, err = tx.Exec("DELETE FROM person;"); err != nil {
+		return err
+	}
+
+	assetIds := make(map[string]int64)
+	for _, person := range persons {
+		if err = InsertTx(tx, &person); err != nil {
+			return err
+		}
+
+		for _, key := range faces[person.Id] {
+			hash, face, err := ParseFaceKey(key)
+			if err != nil {
+				return err
+			}
+			assetId, ok := assetIds[hash]
+			if !ok {
+				assetId = GetAssetIdTx(tx, hash)
+				assetIds[hash] = assetId
+			}
+			if assetId == 0 {
+				continue
+			}
+			if err = InsertTx(tx, &PersonFace{Person: person.Id, Asset: assetId, Face: face}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return util.CommitOrLog(tx)
+}
+
+// ParseFaceKey splits "<hash>/<index>" (see faces.FaceIndexNode)
+func ParseFaceKey(key string) (string, int, error) {
+	p := strings.Index(key, "/")
+	if p < 32 {
+		return "", 0, fmt.Errorf("invalid face '%s'", key)
+	}
+	face, err := strconv.Atoi(key[p+1:])
+	if err != nil || face < 0 {
+		return "", 0, fmt.Errorf("invalid face '%s'", key)
+	}
+	return key[:p], face, nil
+}
+
+func (p *Person) GetInsertQuery() string {
+	return "INSERT INTO person(id, name) VALUES(?,?);"
+}
+
+func (p *Person) Exec(stmt *sql.Stmt) (sql.Result, error) {
+	return stmt.Exec(&p.Id, &p.Name)
+}
+
+func (p *Person) SetId(id int64) {
+	p.Id = id
+}
+
+func (p *Person) GetCreateQueries() []string {
+	return []string{
+		"CREATE TABLE IF NOT EXISTS person(id integer PRIMARY KEY, name TEXT(128) DEFAULT '');",
+		"CREATE INDEX IF NOT EXISTS idx_person_name on person(name);",
+	}
+}
+
+func (f *PersonFace) GetInsertQuery() string {
+	return "INSERT INTO personFace(person, asset, face) VALUES(?,?,?);"
+}
+
+func (f *PersonFace) Exec(stmt *sql.Stmt) (sql.Result, error) {
+	return stmt.Exec(&f.Person, &f.Asset, &f.Face)
+}
+
+func (f *PersonFace) SetId(id int64) {
+}
+
+func (f *PersonFace) GetCreateQueries() []string {
+	return []string{
+		"CREATE TABLE IF NOT EXISTS personFace(person integer, asset integer, face integer);",
+		"CREATE INDEX IF NOT EXISTS idx_personFace_person on personFace(person);",
+		"CREATE INDEX IF NOT EXISTS idx_personFace_asset on personFace(asset);",
+	}
+}
@@ -0,0 +1,183 @@
+package metadata_db_entity
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/c8121/asset-storage/internal/util"
+)
+
+// PruneResult contains the number of removed rows
+type PruneResult struct {
+	Assets      int64
+	Origins     int64
+	PathItems   int64
+	FileNames   int64
+	Tags        int64
+	MimeTypes   int64
+	Collections int64
+}
+
+var (
+	//Removes rows not referenced anymore, in order
+	pruneOrphanQueries = []struct {
+		count func(r *PruneResult) *int64 //Counter to add removed rows to, nil if not counted
+		query string
+	}{
+		{func(r *PruneResult) *int64 { return &r.Origins },
+			"DELETE FROM origin WHERE asset NOT IN (SELECT id FROM asset);"},
+		{nil,
+			"DELETE FROM assetTag WHERE asset NOT IN (SELECT id FROM asset);"},
+		{nil,
+			"DELETE FROM assetRelation WHERE asset NOT IN (SELECT id FROM asset);"},
+		{nil,
+			"DELETE FROM assetDigest WHERE asset NOT IN (SELECT id FROM asset);"},
+		{nil,
+			"DELETE FROM assetSearch WHERE rowid NOT IN (SELECT id FROM asset);"},
+		{nil,
+			"DELETE FROM personFace WHERE asset NOT IN (SELECT id FROM asset);"},
+		{nil,
+			"DELETE FROM faceSimilarity WHERE asset_a NOT IN (SELECT id FROM asset) OR asset_b NOT IN (SELECT id FROM asset);"},
+		{func(r *PruneResult) *int64 { return &r.Tags },
+			"DELETE FROM tag WHERE id NOT IN (SELECT tag FROM assetTag);"},
+		{func(r *PruneResult) *int64 { return &r.MimeTypes },
+			"DELETE FROM mimeType WHERE id NOT IN (SELECT mimeType FROM asset);"},
+		{func(r *PruneResult) *int64 { return &r.FileNames },
+			"DELETE FROM fileName WHERE id NOT IN (SELECT name FROM origin) AND id NOT IN (SELECT name FROM asset);"},
+		//Keep path items used by an origin and all their parents (see PathItemClosure)
+		{func(r *PruneResult) *int64 { return &r.PathItems },
+			"DELETE FROM pathItem WHERE id NOT IN (SELECT c.ancestor FROM pathItemClosure c INNER JOIN origin o ON o.path = c.descendant);"},
+		{nil,
+			"DELETE FROM pathItemClosure WHERE ancestor NOT IN (SELECT id FROM pathItem) OR descendant NOT IN (SELECT id FROM pathItem);"},
+	}
+)
+
+// PruneAssets removes all assets for which exists returns false (meta-data file was deleted),
+// then all rows not referenced anymore (see PruneOrphansTx).
+func PruneAssets(exists func(hash string) bool) (*PruneResult, error) {
+
+	ids, err := findAssetsToPrune(exists)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer util.RollbackOrLog(tx)
+
+	result := &PruneResult{}
+	for _, id := range ids {
+		if _, err = tx.Exec("DELETE FROM asset WHERE id = ?;", id); err != nil {
+			return nil, err
+		}
+		result.Assets++
+	}
+
+	if err = PruneOrphansTx(tx, result); err != nil {
+		return nil, err
+	}
+
+	return result, util.CommitOrLog(tx)
+}
+
+// findAssetsToPrune returns ids of all assets for which exists returns false
+func findAssetsToPrune(exists func(hash string) bool) ([]int64, error) {
+
+	rows, err := db.Query("SELECT id, hash FROM asset;")
+	if err != nil {
+		return nil, err
+	}
+	defer util.CloseOrLog(rows)
+
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		var hash string
+		if err := rows.Scan(&id, &hash); err != nil {
+			return nil, err
+		}
+		if !exists(hash) {
+			fmt.Printf("Remove asset %s\n", hash)
+			ids = append(ids, id)
+		}
+	}
+	return ids, rows.Err()
+}
+
+// PruneOrphans removes all rows not referenced anymore, see PruneOrphansTx
+func PruneOrphans() (*PruneResult, error) {
+
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer util.RollbackOrLog(tx)
+
+	result := &PruneResult{}
+	if err = PruneOrphansTx(tx, result); err != nil {
+		return nil, err
+	}
+
+	return result, util.CommitOrLog(tx)
+}
+
+// PruneOrphansTx removes origins, tags, relations, search index entries and face-similarities of removed assets,
+// mime-types, path items and file names which are not used anymore. Adds the number of removed rows to result.
+func PruneOrphansTx(tx *sql.Tx, result *PruneResult) error {
+
+	//Cached rows might be removed
+	clearCaches()
+
+	for _, prune := range pruneOrphanQueries {
+		r, err := tx.Exec(prune.query)
+		if err != nil {
+			return err
+		}
+		if prune.count != nil {
+			n, _ := r.RowsAffected()
+			*prune.count(result) += n
+		}
+	}
+	return nil
+}
+
+// PruneCollections removes all collections for which exists returns false (collection file was deleted)
+func PruneCollections(exists func(hash string) bool) (int64, error) {
+
+	rows, err := db.Query("SELECT id, hash FROM collection;")
+	if err != nil {
+		return 0, err
+	}
+
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		var hash string
+		if err := rows.Scan(&id, &hash); err != nil {
+			util.CloseOrLog(rows)
+			return 0, err
+		}
+		if !exists(hash) {
+			fmt.Printf("Remove collection %s\n", hash)
+			ids = append(ids, id)
+		}
+	}
+	util.CloseOrLog(rows)
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var count int64
+	for _, id := range ids {
+		if _, err = db.Exec("DELETE FROM collection WHERE id = ?;", id); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
@@ -0,0 +1,58 @@
+package metadata_db_entity
+
+import (
+	"database/sql"
+
+	"github.com/c8121/asset-storage/internal/metadata"
+	"github.com/c8121/asset-storage/internal/util"
+)
+
+// AssetRelation relates an asset to another asset (attachment-of a message for example).
+// Related is the hash, because the related asset might not be added yet.
+type AssetRelation struct {
+	Asset   int64
+	Type    string
+	Related string
+}
+
+// SetAssetRelationsTx replaces all relations of an asset
+func SetAssetRelationsTx(tx *sql.Tx, asset *Asset, relations []metadata.JsonRelation) error {
+
+	stmt, err := tx.Prepare("DELETE FROM assetRelation WHERE asset = ?;")
+	if err != nil {
+		return err
+	}
+	defer util.CloseOrLog(stmt)
+
+	if _, err = stmt.Exec(asset.Id); err != nil {
+		return err
+	}
+
+	for _, relation := range relations {
+		err = InsertTx(tx, &AssetRelation{Asset: asset.Id, Type: relation.Type, Related: relation.Hash})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *AssetRelation) GetInsertQuery() string {
+	return "INSERT INTO assetRelation(asset, type, related) VALUES(?,?,?);"
+}
+
+func (r *AssetRelation) Exec(stmt *sql.Stmt) (sql.Result, error) {
+	return stmt.Exec(&r.Asset, &r.Type, &r.Related)
+}
+
+func (r *AssetRelation) SetId(id int64) {
+}
+
+func (r *AssetRelation) GetCreateQueries() []string {
+	return []string{
+		"CREATE TABLE IF NOT EXISTS assetRelation(asset integer, type TEXT(32), related TEXT(64));",
+		"CREATE INDEX IF NOT EXISTS idx_assetRelation_asset on assetRelation(asset);",
+		"CREATE INDEX IF NOT EXISTS idx_assetRelation_related on assetRelation(related);",
+	}
+}
@@ -0,0 +1,91 @@
+package metadata_db_entity
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/c8121/asset-storage/internal/metadata"
+	"github.com/c8121/asset-storage/internal/util"
+)
+
+// SearchIndex is the FTS5 full-text index of assets, rowid is the Asset.Id
+type SearchIndex struct {
+}
+
+var (
+	// SearchIndexRankWeights are used by bm25(...), in order of columns:
+	// fileNames, pathNames, description (title, description, comments), tags, content
+	SearchIndexRankWeights = []float64{10.0, 4.0, 3.0, 8.0, 1.0}
+)
+
+// UpdateSearchIndexTx replaces the full-text index entry of an asset
+func UpdateSearchIndexTx(tx *sql.Tx, asset *Asset, jsonMeta *metadata.JsonAssetMetaData) error {
+
+	err := RemoveFromSearchIndexTx(tx, asset.Id)
+	if err != nil {
+		return err
+	}
+
+	fileNames := make([]string, 0, len(jsonMeta.Origins)+1)
+	pathNames := make([]string, 0, len(jsonMeta.Origins))
+	descriptions := []string{jsonMeta.Title, jsonMeta.Description}
+	if jsonMeta.Name != "" {
+		fileNames = append(fileNames, jsonMeta.Name)
+	}
+	for _, origin := range jsonMeta.Origins {
+		if origin.Hidden {
+			continue
+		}
+		fileNames = append(fileNames, origin.Name)
+		pathNames = append(pathNames, strings.Join(SplitPath(origin.Path), " "))
+		//Sender and subject of messages, see storage.unpackMail
+		descriptions = append(descriptions, origin.Subject, origin.From)
+	}
+	for _, comment := range jsonMeta.Comments {
+		descriptions = append(descriptions, comment.Text)
+	}
+
+	//Text extracted from content, see text_extractor.ExtractIfNotExists
+	content, _ := metadata.LoadText(jsonMeta.Hash)
+
+	stmt, err := tx.Prepare("INSERT INTO assetSearch(rowid, fileNames, pathNames, description, tags, content) VALUES(?,?,?,?,?,?);")
+	if err != nil {
+		return err
+	}
+	defer util.CloseOrLog(stmt)
+
+	_, err = stmt.Exec(asset.Id,
+		strings.Join(fileNames, "\n"),
+		strings.Join(pathNames, "\n"),
+		strings.TrimSpace(strings.Join(descriptions, "\n")),
+		strings.Join(jsonMeta.Tags, "\n"),
+		content)
+	return err
+}
+
+// RemoveFromSearchIndexTx removes the full-text index entry of an asset
+func RemoveFromSearchIndexTx(tx *sql.Tx, assetId int64) error {
+
+	stmt, err := tx.Prepare("DELETE FROM assetSearch WHERE rowid = ?;")
+	if err != nil {
+		return err
+	}
+	defer util.CloseOrLog(stmt)
+
+	_, err = stmt.Exec(assetId)
+	return err
+}
+
+// ClearSearchIndex removes all entries from full-text index.
+// Index can be rebuilt by adding all meta-data again (see cmd/metadata-db-create)
+func ClearSearchIndex() error {
+	_, err := db.Exec("DELETE FROM assetSearch;")
+	return err
+}
+
+func (s *SearchIndex) GetCreateQueries() []string {
+	return []string{
+		"CREATE VIRTUAL TABLE IF NOT EXISTS assetSearch USING fts5(fileNames, pathNames, description, tags, content, " +
+			"tokenize = 'unicode61 remove_diacritics 2', prefix = '2 3');",
+	}
+}
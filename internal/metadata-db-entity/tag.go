@@ -0,0 +1,175 @@
+package metadata_db_entity
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/c8121/asset-storage/internal/util"
+)
+
+type Tag struct {
+	Id   int64
+	Name string
+}
+
+type AssetTag struct {
+	Asset int64
+	Tag   int64
+}
+
+var (
+	tagCache map[string]*Tag
+)
+
+func init() {
+	tagCache = make(map[string]*Tag)
+}
+
+// NormalizeTagName trims and lower-cases tag names
+func NormalizeTagName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func GetTagIdTx(tx *sql.Tx, name string, createIfNotExists bool) int64 {
+	tag, err := GetTagTx(tx, name, createIfNotExists)
+	if err != nil {
+		fmt.Println(err)
+		return 0
+	}
+	return tag.Id
+}
+
+func GetTag(name string, createIfNotExists bool) (*Tag, error) {
+
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer util.RollbackOrLog(tx)
+
+	tag, err := GetTagTx(tx, name, createIfNotExists)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = util.CommitOrLog(tx); err != nil {
+		return nil, err
+	}
+
+	return tag, nil
+}
+
+func GetTagTx(tx *sql.Tx, name string, createIfNotExists bool) (*Tag, error) {
+
+	name = NormalizeTagName(name)
+	tag, ok := tagCache[name]
+	if ok {
+		return tag, nil
+	}
+
+	tag = &Tag{Name: name}
+	err := GetTx(tx, createIfNotExists, tag)
+	if err == nil {
+		tagCache[name] = tag
+	}
+
+	return tag, err
+}
+
+// SetAssetTagsTx replaces all tags of an asset
+func SetAssetTagsTx(tx *sql.Tx, asset *Asset, tags []string) error {
+
+	stmt, err := tx.Prepare("DELETE FROM assetTag WHERE asset = ?;")
+	if err != nil {
+		return err
+	}
+	defer util.CloseOrLog(stmt)
+
+	if _, err = stmt.Exec(asset.Id); err != nil {
+		return err
+	}
+
+	for _, name := range tags {
+		if len(NormalizeTagName(name)) == 0 {
+			continue
+		}
+		tag, err := GetTagTx(tx, name, true)
+		if err != nil {
+			return err
+		}
+		if err = InsertTx(tx, &AssetTag{Asset: asset.Id, Tag: tag.Id}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *Tag) GetId() int64 {
+	return t.Id
+}
+
+func (t *Tag) Save() error {
+	return Save(t)
+}
+
+func (t *Tag) GetSelectQuery() string {
+	return "SELECT id, name FROM tag WHERE name = ?;"
+}
+
+func (t *Tag) GetSelectQueryArgs() []any {
+	return []any{t.Name}
+}
+
+func (t *Tag) Scan(rows *sql.Rows) error {
+	return rows.Scan(&t.Id, &t.Name)
+}
+
+func (t *Tag) GetInsertQuery() string {
+	return "INSERT INTO tag(name) VALUES(?);"
+}
+
+func (t *Tag) GetUpdateQuery() string {
+	return "UPDATE tag SET name=? WHERE id = ?;"
+}
+
+func (t *Tag) GetUpdateQueryArgs() []any {
+	return []any{&t.Name, &t.Id}
+}
+
+func (t *Tag) Exec(stmt *sql.Stmt) (sql.Result, error) {
+	return stmt.Exec(&t.Name, &t.Id)
+}
+
+func (t *Tag) SetId(id int64) {
+	t.Id = id
+}
+
+func (t *Tag) GetCreateQueries() []string {
+	return []string{
+		"CREATE TABLE IF NOT EXISTS tag(id integer PRIMARY KEY, name TEXT(256));",
+		"CREATE INDEX IF NOT EXISTS idx_tag_name on tag(name);",
+	}
+}
+
+func (a *AssetTag) GetInsertQuery() string {
+	return "INSERT INTO assetTag(asset, tag) VALUES(?,?);"
+}
+
+func (a *AssetTag) Exec(stmt *sql.Stmt) (sql.Result, error) {
+	return stmt.Exec(&a.Asset, &a.Tag)
+}
+
+func (a *AssetTag) SetId(id int64) {
+}
+
+func (a *AssetTag) GetCreateQueries() []string {
+	return []string{
+		"CREATE TABLE IF NOT EXISTS assetTag(asset integer, tag integer);",
+		"CREATE INDEX IF NOT EXISTS idx_assetTag_asset on assetTag(asset);",
+		"CREATE INDEX IF NOT EXISTS idx_assetTag_tag on assetTag(tag);",
+	}
+}
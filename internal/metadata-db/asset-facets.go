@@ -0,0 +1,141 @@
+package metadata_db
+
+import (
+	"fmt"
+
+	"github.com/c8121/asset-storage/internal/util"
+)
+
+// FacetValue is one value of a facet with the number of matching assets.
+// Id is set if the value can be used as filter by id (mime-type, owner, tag, path-item).
+type FacetValue struct {
+	Id    int64
+	Value string
+	Count int
+}
+
+// AssetFacets contains grouped counts of the assets matching an AssetListFilter
+type AssetFacets struct {
+	Total    int
+	MimeType []FacetValue
+	Owner    []FacetValue
+	Year     []FacetValue
+	Month    []FacetValue
+	Tag      []FacetValue
+	PathRoot []FacetValue
+}
+
+var (
+	// MaxFacetValues limits the number of values per facet (values with the highest count are returned)
+	MaxFacetValues = 100
+
+	//Query to select id, value and count (of matching assets), 'matches' contains the result of the filter
+	facetQueries = map[string]string{
+		"MimeType": "SELECT m.id, m.name, COUNT(*) FROM matches x " +
+			"INNER JOIN asset a ON a.id = x.id " +
+			"INNER JOIN mimeType m ON m.id = a.mimeType " +
+			"GROUP BY m.id",
+		"Owner": "SELECT w.id, w.name, COUNT(DISTINCT o.asset) FROM matches x " +
+			"INNER JOIN origin o ON o.asset = x.id " +
+			"INNER JOIN owner w ON w.id = o.owner " +
+			"GROUP BY w.id",
+		//Years and months in UTC, as file-times are stored (see DbTimeFormat)
+		"Year": "SELECT 0, substr(a.fileTime, 1, 4) AS y, COUNT(*) FROM matches x " +
+			"INNER JOIN asset a ON a.id = x.id " +
+			"GROUP BY y",
+		"Month": "SELECT 0, substr(a.fileTime, 1, 7) AS ym, COUNT(*) FROM matches x " +
+			"INNER JOIN asset a ON a.id = x.id " +
+			"GROUP BY ym",
+		"Tag": "SELECT t.id, t.name, COUNT(DISTINCT at.asset) FROM matches x " +
+			"INNER JOIN assetTag at ON at.asset = x.id " +
+			"INNER JOIN tag t ON t.id = at.tag " +
+			"GROUP BY t.id",
+		//Walk up from all paths of matching assets to the root (parent = 0)
+		"PathRoot": "SELECT r.id, r.name, COUNT(DISTINCT o.asset) FROM matches x " +
+			"INNER JOIN origin o ON o.asset = x.id " +
+			"INNER JOIN pathRoot pr ON pr.path = o.path " +
+			"INNER JOIN pathItem r ON r.id = pr.root " +
+			"GROUP BY r.id",
+	}
+
+	pathRootQuery = "pathUp(path, id, parent) AS (" +
+		"SELECT p.id, p.id, p.parent FROM pathItem p " +
+		"WHERE p.id IN (SELECT DISTINCT o.path FROM matches x INNER JOIN origin o ON o.asset = x.id) " +
+		"UNION ALL " +
+		"SELECT u.path, p.id, p.parent FROM pathUp u INNER JOIN pathItem p ON p.id = u.parent), " +
+		"pathRoot(path, root) AS (SELECT path, id FROM pathUp WHERE parent = 0) "
+)
+
+// ListFacets counts assets matching the filter grouped by mime-type, owner, year, month, tag and path root.
+// Offset, Count and Sort of the filter are ignored.
+func ListFacets(filter *AssetListFilter) (*AssetFacets, error) {
+
+	matches, err := FindAssets(filter)
+	if err != nil {
+		return nil, err
+	}
+	if matches == nil {
+		matches = existingAssetsQuery()
+	}
+
+	facets := &AssetFacets{}
+	if facets.Total, err = matches.Count(); err != nil {
+		return nil, err
+	}
+
+	targets := map[string]*[]FacetValue{
+		"MimeType": &facets.MimeType,
+		"Owner":    &facets.Owner,
+		"Year":     &facets.Year,
+		"Month":    &facets.Month,
+		"Tag":      &facets.Tag,
+		"PathRoot": &facets.PathRoot,
+	}
+
+	for name, target := range targets {
+		values, err := loadFacet(matches, name, facetQueries[name])
+		if err != nil {
+			return nil, fmt.Errorf("failed to load facet %s: %w", name, err)
+		}
+		*target = values
+	}
+
+	return facets, nil
+}
+
+// loadFacet runs a facet query using matches as sub-query
+func loadFacet(matches *FinderQuery, name string, facetQuery string) ([]FacetValue, error) {
+
+	var query = "WITH RECURSIVE matches(id, score) AS (" + matches.Sql + ") "
+	if name == "PathRoot" {
+		query += ", " + pathRootQuery
+	}
+	query += facetQuery + " ORDER BY 3 DESC, 2 ASC LIMIT ?;"
+
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer util.CloseOrLog(stmt)
+
+	params := make([]any, 0, len(matches.Args)+1)
+	params = append(params, matches.Args...)
+	params = append(params, MaxFacetValues)
+
+	rows, err := stmt.Query(params...)
+	if err != nil {
+		return nil, err
+	}
+	defer util.CloseOrLog(rows)
+
+	values := make([]FacetValue, 0)
+	for rows.Next() {
+		var value FacetValue
+		if err := rows.Scan(&value.Id, &value.Value, &value.Count); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	return values, rows.Err()
+}
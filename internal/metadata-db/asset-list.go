@@ -2,123 +2,282 @@ package metadata_db
 
 import (
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/c8121/asset-storage/internal/util"
 )
 
 type AssetListItem struct {
-	Id       int64
-	Hash     string
-	Name     string
-	MimeType string
-	FileTime time.Time
+	Id          int64
+	Hash        string
+	Name        string
+	MimeType    string
+	FileTime    time.Time
+	Size        int64
+	CaptureTime time.Time
+	Rating      int
+}
+
+// AssetList is one page of assets, Total is the number of all matching assets
+type AssetList struct {
+	Total int
+	Items []AssetListItem
 }
 
 type AssetListFilter struct {
-	PathId   int64
-	MimeType string
-	FileName string
-	PathName string
-	Face     string
-	Offset   int
-	Count    int
+	PathId           int64
+	MimeType         string
+	FileName         string
+	PathName         string
+	PathPrefix       string //Full path, finds all assets below
+	PathTree         int64  //PathItem id, finds all assets below
+	Face             string
+	Person           string //Person id or name, see FinderByPerson
+	Owner            string
+	FileTimeFrom     string //YYYY, YYYY-MM, YYYY-MM-DD or RFC3339, inclusive
+	FileTimeTo       string //YYYY, YYYY-MM, YYYY-MM-DD or RFC3339, inclusive (up to the end of the given period)
+	MinSize          int64
+	MaxSize          int64
+	Period           string //Storage time-period, see storage.TimePeriodName
+	SimilarTo        string //Hash of an image, finds similar images. Optional "/<max distance>", see FinderBySimilarImage
+	BoundingBox      string //west,south,east,north (degrees), see ParseBoundingBox
+	Near             string //latitude,longitude,radius (km), see ParseGeoRadius
+	Place            string //City or country, see geo.FindPlace
+	MinRating        int
+	Deleted          bool   //Find deleted assets only (trash), deleted assets are excluded otherwise
+	CollapseVersions bool   //Only the latest versions: exclude assets having a derived version, see ListVersions
+	Query            string //Search query expression, see ParseQuery
+	Sort             string //One of the Sort* constants, default is SortRelevance if filtered, SortTimeDesc otherwise
+	Offset           int
+	Count            int
+}
+
+// FilterError describes an invalid value of an AssetListFilter field
+type FilterError struct {
+	Field string
+	Err   error
+}
+
+func (e *FilterError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Err)
+}
+
+func (e *FilterError) Unwrap() error {
+	return e.Err
+}
+
+const (
+	SortTimeDesc  = "time-desc"
+	SortTimeAsc   = "time-asc"
+	SortName      = "name"
+	SortSize      = "size"
+	SortRelevance = "relevance"
+
+	SortCaptureTimeDesc = "capture-desc"
+	SortCaptureTimeAsc  = "capture-asc"
+)
+
+var (
+	//Sort -> ORDER BY clause
+	sortOrders = map[string]string{
+		SortTimeDesc:        "a.fileTime DESC, a.hash ASC",
+		SortTimeAsc:         "a.fileTime ASC, a.hash ASC",
+		SortName:            "f.name COLLATE NOCASE ASC, a.hash ASC",
+		SortSize:            "a.size DESC, a.hash ASC",
+		SortCaptureTimeDesc: "a.captureTime DESC, a.hash ASC",
+		SortCaptureTimeAsc:  "a.captureTime ASC, a.hash ASC",
+		//Only if filtered, see ListAssets
+		SortRelevance: "x.score DESC, a.fileTime DESC, a.hash ASC",
+	}
+)
+
+// ListAssets returns one page of assets matching the filter
+func ListAssets(filter *AssetListFilter) (*AssetList, error) {
+
+	if _, ok := sortOrders[filter.Sort]; !ok && filter.Sort != "" {
+		return nil, &FilterError{"Sort", fmt.Errorf("unknown sort order '%s'", filter.Sort)}
+	}
+
+	matches, err := FindAssets(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return listAssets(matches, filter.Sort, filter.Offset, filter.Count)
+}
+
+// listAssets loads one page of assets selected by matches (all assets not deleted if nil)
+func listAssets(matches *FinderQuery, sort string, offset int, count int) (*AssetList, error) {
+
+	var query = "SELECT a.id, a.hash, m.name as mimeType, a.fileTime, f.name, a.size, a.captureTime, a.rating, COUNT(*) OVER() AS total"
+	var where = ""
+	var params = make([]any, 0)
+
+	if matches != nil {
+		query = "WITH matches(id, score) AS (" + matches.Sql + ") " + query +
+			" FROM matches x INNER JOIN asset a ON a.id = x.id "
+		params = append(params, matches.Args...)
+		if sort == "" {
+			sort = SortRelevance
+		}
+	} else {
+		//Nothing filtered
+		query += " FROM asset a "
+		where = " WHERE a.deleted = 0 "
+		if sort == "" || sort == SortRelevance {
+			sort = SortTimeDesc
+		}
+	}
+
+	query += " INNER JOIN mimeType m ON a.mimeType = m.id " +
+		" INNER JOIN fileName f ON a.name = f.id " + where +
+		" ORDER BY " + sortOrders[sort] + " LIMIT ? OFFSET ?;"
+	params = append(params, count)
+	params = append(params, offset)
+
+	list, err := loadAssetList(query, params...)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(list.Items) == 0 && offset > 0 {
+		//Offset behind last item, total is not available from the page query
+		if matches == nil {
+			matches = existingAssetsQuery()
+		}
+		if list.Total, err = matches.Count(); err != nil {
+			return nil, err
+		}
+	}
+
+	return list, nil
 }
 
-func ListAssets(filter *AssetListFilter) ([]AssetListItem, error) {
+// FindAssets combines all Finders used by the filter (including the search query) into one query,
+// returns nil if nothing is filtered (use existingAssetsQuery then).
+// Deleted assets are excluded unless filter.Deleted is set, which selects deleted assets only.
+func FindAssets(filter *AssetListFilter) (*FinderQuery, error) {
 
-	//Asset.Id -> Score
-	var ids ScoredIdMap = nil
+	timeRange, err := getFileTimeRange(filter.FileTimeFrom, filter.FileTimeTo)
+	if err != nil {
+		return nil, err
+	}
+
+	if filter.MinSize < 0 || (filter.MaxSize > 0 && filter.MaxSize < filter.MinSize) {
+		return nil, &FilterError{"MinSize/MaxSize", fmt.Errorf("invalid size range %d - %d", filter.MinSize, filter.MaxSize)}
+	}
+
+	var bbox *BoundingBox
+	if len(filter.BoundingBox) > 0 {
+		if bbox, err = ParseBoundingBox(filter.BoundingBox); err != nil {
+			return nil, &FilterError{"BoundingBox", err}
+		}
+	}
+
+	var radius *GeoRadius
+	if len(filter.Near) > 0 {
+		if radius, err = ParseGeoRadius(filter.Near); err != nil {
+			return nil, &FilterError{"Near", err}
+		}
+	}
 
 	//Finder -> value to use
 	finders := map[Finder]any{
-		FinderByPathId{}:   filter.PathId,
-		FinderByMimeType{}: filter.MimeType,
-		FinderByFileName{}: filter.FileName,
-		FinderByPathName{}: filter.PathName,
-		FinderByFace{}:     filter.Face,
+		FinderByPathId{}:       filter.PathId,
+		FinderByMimeType{}:     filter.MimeType,
+		FinderByFileName{}:     filter.FileName,
+		FinderByPathName{}:     filter.PathName,
+		FinderByPathPrefix{}:   filter.PathPrefix,
+		FinderByPathTree{}:     filter.PathTree,
+		FinderByFace{}:         filter.Face,
+		FinderByPerson{}:       filter.Person,
+		FinderByOwner{}:        filter.Owner,
+		FinderByFileTime{}:     timeRange,
+		FinderBySize{}:         SizeRange{Min: filter.MinSize, Max: filter.MaxSize},
+		FinderByPeriod{}:       filter.Period,
+		FinderBySimilarImage{}: filter.SimilarTo,
+		FinderByBoundingBox{}:  bbox,
+		FinderByRadius{}:       radius,
+		FinderByPlace{}:        filter.Place,
+		FinderByRating{}:       filter.MinRating,
 	}
 
+	includes := make([]*FinderQuery, 0)
 	for finder, value := range finders {
-		foundIds, err := finder.Find(value)
+		query, err := finder.Find(value)
 		if err != nil {
 			return nil, err
 		}
-		if foundIds != nil {
-			fmt.Printf("Found %d assets using %T with '%v'\n", len(foundIds), finder, value)
-			if ids != nil {
-				ids.Reduce(foundIds)
-			} else {
-				ids = foundIds
-			}
+		if query != nil {
+			fmt.Printf("Using %T with '%v'\n", finder, value)
+			includes = append(includes, query)
 		}
 	}
 
-	var query = "SELECT a.id, a.hash, m.name as mimeType, a.fileTime, f.name" +
-		" FROM asset a " +
-		" INNER JOIN mimeType m ON a.mimeType = m.id " +
-		" INNER JOIN fileName f ON a.name = f.id "
+	if len(filter.Query) > 0 {
+		node, err := ParseQuery(filter.Query)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("Query: %s\n", node)
+		query, err := node.Find()
+		if err != nil {
+			return nil, err
+		}
+		includes = append(includes, query)
+	}
 
-	var params = make([]any, 0)
+	excludes := make([]*FinderQuery, 0)
+	if filter.CollapseVersions {
+		excludes = append(excludes, supersededVersionsQuery())
+	}
 
-	if ids != nil {
-		sorted := ids.Sort()
-		endIdx := filter.Offset + filter.Count
-		if endIdx >= len(sorted) {
-			endIdx = len(sorted)
-		}
-		if filter.Offset < len(sorted) && endIdx > 0 {
-			slice := sorted[filter.Offset:endIdx]
-			//fmt.Printf("Found %d (%d - %d) items\n", len(slice), filter.Offset, endIdx)
-			query += " WHERE a.id in(" +
-				strings.Repeat("?,", len(slice)-1) + "?" +
-				");"
-			for _, id := range slice {
-				//fmt.Printf("Found %d %f\n", id.Id, id.Score)
-				params = append(params, id.Id)
-			}
-			items, err := loadAssetList(query, params...)
-			if err != nil {
-				return nil, err
-			}
-			//Retain sorting
-			mapById := listToMap(items)
-			list := make([]AssetListItem, 0, len(items))
-			for _, id := range slice {
-				item, ok := mapById[id.Id]
-				if ok {
-					list = append(list, item)
-				}
-			}
-			return list, nil
+	if filter.Deleted {
+		includes = append(includes, deletedAssetsQuery())
+		return intersectQueries(includes, excludes), nil
+	}
+	if len(includes) == 0 && len(excludes) == 0 {
+		return nil, nil
+	}
+	excludes = append(excludes, deletedAssetsQuery())
+	return intersectQueries(includes, excludes), nil
+}
 
-		} else {
-			//Nothing found
-			empty := []AssetListItem{}
-			return empty, nil
-		}
-	} else {
-		//Nothing filtered
-		query += "ORDER BY a.fileTime DESC, a.hash ASC LIMIT ? OFFSET ?;"
-		params = append(params, filter.Count)
-		params = append(params, filter.Offset)
+// getFileTimeRange parses FileTimeFrom and FileTimeTo of AssetListFilter
+func getFileTimeRange(from string, to string) (FileTimeRange, error) {
 
-		return loadAssetList(query, params...)
+	r := FileTimeRange{}
+	if len(from) > 0 {
+		start, _, err := parseFilterTime(from)
+		if err != nil {
+			return r, &FilterError{"FileTimeFrom", err}
+		}
+		r.From = start
+	}
+	if len(to) > 0 {
+		_, end, err := parseFilterTime(to)
+		if err != nil {
+			return r, &FilterError{"FileTimeTo", err}
+		}
+		r.To = end
+	}
+	if !r.From.IsZero() && !r.To.IsZero() && !r.From.Before(r.To) {
+		return r, &FilterError{"FileTimeFrom/FileTimeTo", fmt.Errorf("'%s' is after '%s'", from, to)}
 	}
+	return r, nil
 }
 
-// listToMap converts list of AssetListItem to a map with ID as key.
-func listToMap(items []AssetListItem) map[int64]AssetListItem {
-	mapById := make(map[int64]AssetListItem, len(items))
-	for _, item := range items {
-		mapById[item.Id] = item
+// parseFilterTime accepts RFC3339 timestamps in addition to ParseDatePeriod
+func parseFilterTime(s string) (time.Time, time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		t = t.Local()
+		return t, t.Add(time.Second), nil
 	}
-	return mapById
+	return ParseDatePeriod(s)
 }
 
 // loadAssetList queries the database
-func loadAssetList(query string, params ...any) ([]AssetListItem, error) {
+func loadAssetList(query string, params ...any) (*AssetList, error) {
 
 	fmt.Printf("Query: %s\n", query)
 	stmt, err := db.Prepare(query)
@@ -127,21 +286,21 @@ func loadAssetList(query string, params ...any) ([]AssetListItem, error) {
 	}
 	defer util.CloseOrLog(stmt)
 
-	var items []AssetListItem
+	list := &AssetList{Items: make([]AssetListItem, 0)}
 
 	if rows, err := stmt.Query(params...); err == nil {
 		defer util.CloseOrLog(rows)
 		for rows.Next() {
 			var item AssetListItem
-			if err := rows.Scan(&item.Id, &item.Hash, &item.MimeType, &item.FileTime, &item.Name); err != nil {
+			if err := rows.Scan(&item.Id, &item.Hash, &item.MimeType, &item.FileTime, &item.Name, &item.Size, &item.CaptureTime, &item.Rating, &list.Total); err != nil {
 				return nil, err
 			}
-			items = append(items, item)
+			list.Items = append(list.Items, item)
 		}
 
 	} else {
 		return nil, err
 	}
 
-	return items, nil
+	return list, nil
 }
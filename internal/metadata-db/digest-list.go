@@ -0,0 +1,34 @@
+package metadata_db
+
+import (
+	"github.com/c8121/asset-storage/internal/storage"
+)
+
+// FindByDigest returns all assets having the given content digest (see storage.ParseDigest), oldest first.
+// Deleted assets (trash) are included, because their content is still stored.
+func FindByDigest(algorithm string, digest string) ([]AssetListItem, error) {
+
+	digest, err := storage.ParseDigest(algorithm, digest)
+	if err != nil {
+		return nil, &FilterError{"Digest", err}
+	}
+
+	var query = "SELECT a.id, a.hash, m.name as mimeType, a.fileTime, f.name, a.size, a.captureTime, a.rating, COUNT(*) OVER() AS total FROM asset a " +
+		"INNER JOIN mimeType m ON a.mimeType = m.id " +
+		"INNER JOIN fileName f ON a.name = f.id "
+	var params []any
+	if algorithm == storage.DigestSHA256 {
+		query += "WHERE a.hash = ? "
+		params = append(params, digest)
+	} else {
+		query += "WHERE a.id IN (SELECT d.asset FROM assetDigest d WHERE d.algorithm = ? AND d.digest = ?) "
+		params = append(params, algorithm, digest)
+	}
+
+	list, err := loadAssetList(query+
+		"ORDER BY a.fileTime ASC, a.hash ASC;", params...)
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
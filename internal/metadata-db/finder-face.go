@@ -1,18 +1,22 @@
 package metadata_db
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 
+	"github.com/c8121/asset-storage/internal/faces"
 	metadata_db_entity "github.com/c8121/asset-storage/internal/metadata-db-entity"
 )
 
 type FinderByFace struct {
 }
 
-// Find searches all assets having the given face
-func (f FinderByFace) Find(face any) (ScoredIdMap, error) {
+// Find searches all assets having the given face.
+// Similar faces are found using the face index (see faces.FindSimilarFaces),
+// face similarities stored in database are used if there is no index.
+func (f FinderByFace) Find(face any) (*FinderQuery, error) {
 
 	var sFace = face.(string)
 	if len(sFace) == 0 {
@@ -27,19 +31,49 @@ func (f FinderByFace) Find(face any) (ScoredIdMap, error) {
 	assetId := metadata_db_entity.GetAssetId(hash)
 	faceIdx, _ := strconv.Atoi(sFace[p+1:])
 
-	var query = "SELECT a.id, 2.0 as score FROM asset a WHERE id = ? " +
+	fmt.Printf("findAssetIdsByFace: %s\n", sFace)
+
+	matches, err := faces.FindSimilarFaces(hash+"/"+strconv.Itoa(faceIdx), faces.SimilarFacesCount, faces.SimilarFacesThreshold)
+	if errors.Is(err, faces.ErrNoFaceIndex) {
+		return findByFaceSimilarity(assetId, faceIdx), nil
+	} else if errors.Is(err, faces.ErrFaceNotFound) {
+		matches = nil //No embedding, only the asset itself
+	} else if err != nil {
+		return nil, err
+	}
+
+	//The asset itself (score 2.0) and all assets having a similar face
+	var query = "SELECT id, SUM(score) AS score FROM (" +
+		"SELECT ? AS id, 2.0 AS score"
+	args := []any{assetId}
+	if len(matches) > 0 {
+		query += " UNION ALL " +
+			"SELECT asset.id, similar.score FROM (" +
+			"SELECT ? AS hash, ? AS score" + strings.Repeat(" UNION ALL SELECT ?, ?", len(matches)-1) +
+			") similar INNER JOIN asset ON asset.hash = similar.hash"
+		for _, match := range matches {
+			matchHash, _, _ := metadata_db_entity.ParseFaceKey(match.Face)
+			args = append(args, matchHash, match.Similarity)
+		}
+	}
+	query += ") GROUP BY id"
+
+	return newFinderQuery(query, args...), nil
+}
+
+// findByFaceSimilarity uses face similarities stored in database (see faces.CalculateSimilarity)
+func findByFaceSimilarity(assetId int64, faceIdx int) *FinderQuery {
+
+	//The asset itself (score 2.0) and all assets having a similar face
+	var query = "SELECT id, SUM(score) AS score FROM (" +
+		"SELECT ? AS id, 2.0 AS score " +
 		"UNION " +
 		"SELECT asset_b, score FROM faceSimilarity " +
 		"WHERE asset_a = ? AND face_a = ? " +
 		"UNION " +
 		"SELECT asset_a, score FROM faceSimilarity " +
-		"WHERE asset_b = ? AND face_b = ?;"
-
-	fmt.Printf("findAssetIdsByFace: %s\n", sFace)
-
-	return findAssetIds(func(id int64, match any, idMap *ScoredIdMap) {
-		score := float32(match.(float64))
-		idMap.Add(id, score)
-	}, query, hash, assetId, faceIdx, assetId, faceIdx)
+		"WHERE asset_b = ? AND face_b = ?" +
+		") GROUP BY id"
 
+	return newFinderQuery(query, assetId, assetId, faceIdx, assetId, faceIdx)
 }
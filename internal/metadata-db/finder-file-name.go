@@ -8,18 +8,25 @@ import (
 type FinderByFileName struct {
 }
 
-// Find searches all assets having the given name as origin.FileName
-func (f FinderByFileName) Find(name any) (ScoredIdMap, error) {
+// Find searches all assets having the given name as origin.FileName or as preferred name (asset.name)
+func (f FinderByFileName) Find(name any) (*FinderQuery, error) {
 
 	var sName = name.(string)
 	if len(sName) == 0 {
 		return nil, nil
 	}
 
-	var query = "SELECT a.id, f.name FROM origin o " +
+	//Score: length of search term relative to length of the name, added for each matching origin
+	//and for the preferred name (if not the name of an origin)
+	var query = "SELECT n.id AS id, SUM(n.score) AS score FROM (" +
+		"SELECT o.asset AS id, ? * 1.0 / length(f.name) AS score FROM origin o " +
 		"INNER JOIN fileName f ON f.id = o.name " +
-		"INNER JOIN asset a ON o.asset = a.id " +
-		"WHERE f.name like ?;"
+		"WHERE f.name like ? " +
+		"UNION ALL " +
+		"SELECT a.id AS id, ? * 1.0 / length(f.name) AS score FROM asset a " +
+		"INNER JOIN fileName f ON f.id = a.name " +
+		"WHERE f.name like ? AND a.name NOT IN (SELECT o.name FROM origin o WHERE o.asset = a.id)" +
+		") n GROUP BY n.id"
 
 	var findName = sName
 	if strings.Contains(findName, "*") {
@@ -30,10 +37,6 @@ func (f FinderByFileName) Find(name any) (ScoredIdMap, error) {
 	}
 	fmt.Printf("findAssetIdsByFileName: %s\n", findName)
 
-	return findAssetIds(func(id int64, match any, idMap *ScoredIdMap) {
-		score := float32(len(sName)) / float32(len(match.(string)))
-		//fmt.Printf("Match: %s, Score: %f\n", match, score)
-		idMap.Add(id, score)
-	}, query, findName)
+	return newFinderQuery(query, len(sName), findName, len(sName), findName), nil
 
 }
@@ -0,0 +1,83 @@
+package metadata_db
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DbTimeFormat can be used to compare with DATETIME columns (which are stored as time.Time.String() in UTC,
+	// see metadata_db_entity.AddMetaDataTx), format UTC times only
+	DbTimeFormat = "2006-01-02 15:04:05"
+)
+
+type FinderByFileTime struct {
+}
+
+// FinderByCaptureTime uses capture time (from EXIF, file-time if not available)
+type FinderByCaptureTime struct {
+}
+
+// FileTimeRange From is inclusive, To is exclusive, zero means open range
+type FileTimeRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// Find searches all assets having a file-time within the given FileTimeRange
+func (f FinderByFileTime) Find(timeRange any) (*FinderQuery, error) {
+	return findByTimeRange("fileTime", timeRange.(FileTimeRange))
+}
+
+// Find searches all assets having a capture-time within the given FileTimeRange
+func (f FinderByCaptureTime) Find(timeRange any) (*FinderQuery, error) {
+	return findByTimeRange("captureTime", timeRange.(FileTimeRange))
+}
+
+// findByTimeRange creates a query for a DATETIME column of asset
+func findByTimeRange(column string, r FileTimeRange) (*FinderQuery, error) {
+
+	if r.From.IsZero() && r.To.IsZero() {
+		return nil, nil
+	}
+
+	var query = "SELECT a.id AS id, 0.0 AS score FROM asset a WHERE 1=1"
+	var params = make([]any, 0)
+	if !r.From.IsZero() {
+		query += " AND a." + column + " >= ?"
+		params = append(params, r.From.UTC().Format(DbTimeFormat))
+	}
+	if !r.To.IsZero() {
+		query += " AND a." + column + " < ?"
+		params = append(params, r.To.UTC().Format(DbTimeFormat))
+	}
+
+	fmt.Printf("findAssetIdsBy %s: %s - %s\n", column, r.From, r.To)
+
+	return newFinderQuery(query, params...), nil
+
+}
+
+// ParseDatePeriod parses YYYY, YYYY-MM, YYYY-MM-DD or YYYY-MM-DDTHH:MM
+// and returns start (inclusive) and end (exclusive) of the period.
+func ParseDatePeriod(s string) (time.Time, time.Time, error) {
+
+	formats := []struct {
+		layout string
+		add    func(t time.Time) time.Time
+	}{
+		{"2006-01-02T15:04", func(t time.Time) time.Time { return t.Add(time.Minute) }},
+		{"2006-01-02 15:04", func(t time.Time) time.Time { return t.Add(time.Minute) }},
+		{"2006-01-02", func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }},
+		{"2006-01", func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }},
+		{"2006", func(t time.Time) time.Time { return t.AddDate(1, 0, 0) }},
+	}
+
+	for _, f := range formats {
+		if t, err := time.ParseInLocation(f.layout, s, time.Local); err == nil {
+			return t, f.add(t), nil
+		}
+	}
+
+	return time.Time{}, time.Time{}, fmt.Errorf("invalid date '%s', expected YYYY, YYYY-MM or YYYY-MM-DD", s)
+}
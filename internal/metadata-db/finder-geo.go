@@ -0,0 +1,158 @@
+package metadata_db
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/c8121/asset-storage/internal/geo"
+)
+
+type FinderByBoundingBox struct {
+}
+
+type FinderByRadius struct {
+}
+
+type FinderByPlace struct {
+}
+
+// BoundingBox in degrees. West > East crosses the antimeridian.
+type BoundingBox struct {
+	West  float64
+	South float64
+	East  float64
+	North float64
+}
+
+// GeoRadius is a circle around a location, Radius in km
+type GeoRadius struct {
+	Latitude  float64
+	Longitude float64
+	Radius    float64
+}
+
+// Find searches all assets located within the given *BoundingBox
+func (f FinderByBoundingBox) Find(bbox any) (*FinderQuery, error) {
+
+	var b = bbox.(*BoundingBox)
+	if b == nil {
+		return nil, nil
+	}
+
+	var query = "SELECT a.id AS id, 0.0 AS score FROM asset a " +
+		"WHERE a.latitude BETWEEN ? AND ? AND "
+	if b.West <= b.East {
+		query += "a.longitude BETWEEN ? AND ?"
+	} else {
+		query += "(a.longitude >= ? OR a.longitude <= ?)"
+	}
+
+	return newFinderQuery(query, b.South, b.North, b.West, b.East), nil
+}
+
+// Find searches all assets located within the given *GeoRadius, score is higher the nearer an asset is
+func (f FinderByRadius) Find(radius any) (*FinderQuery, error) {
+
+	var r = radius.(*GeoRadius)
+	if r == nil {
+		return nil, nil
+	}
+
+	//Bounding box first to use index, then exact distance
+	dLat := r.Radius / geo.EarthRadius * 180 / math.Pi
+	dLon := 180.0
+	if c := math.Cos(r.Latitude * math.Pi / 180); c > 0.01 {
+		dLon = math.Min(180, dLat/c)
+	}
+
+	var query = "SELECT a.id AS id, 1.0 - geo_distance(a.latitude, a.longitude, ?, ?) / ? AS score FROM asset a " +
+		"WHERE a.latitude BETWEEN ? AND ? "
+	var params = []any{r.Latitude, r.Longitude, r.Radius, r.Latitude - dLat, r.Latitude + dLat}
+	if dLon < 180 && r.Longitude-dLon >= -180 && r.Longitude+dLon <= 180 {
+		query += "AND a.longitude BETWEEN ? AND ? "
+		params = append(params, r.Longitude-dLon, r.Longitude+dLon)
+	}
+	query += "AND geo_distance(a.latitude, a.longitude, ?, ?) <= ?"
+	params = append(params, r.Latitude, r.Longitude, r.Radius)
+
+	return newFinderQuery(query, params...), nil
+}
+
+// Find searches all assets located in or near a city or in a country (see geo.FindPlace)
+func (f FinderByPlace) Find(place any) (*FinderQuery, error) {
+
+	var sPlace = place.(string)
+	if len(sPlace) == 0 {
+		return nil, nil
+	}
+
+	var query = "SELECT a.id AS id, 0.0 AS score FROM asset a " +
+		"WHERE a.city LIKE ? OR a.country LIKE ?"
+
+	sPlace = strings.ReplaceAll(sPlace, "*", "%")
+	return newFinderQuery(query, sPlace, sPlace), nil
+}
+
+// ParseBoundingBox parses "west,south,east,north" (degrees)
+func ParseBoundingBox(s string) (*BoundingBox, error) {
+
+	values, err := parseFloats(s, 4)
+	if err != nil {
+		return nil, err
+	}
+	b := &BoundingBox{West: values[0], South: values[1], East: values[2], North: values[3]}
+	if b.South > b.North || b.South < -90 || b.North > 90 ||
+		b.West < -180 || b.West > 180 || b.East < -180 || b.East > 180 {
+		return nil, fmt.Errorf("invalid bounding box '%s', expected west,south,east,north", s)
+	}
+	return b, nil
+}
+
+// ParseGeoRadius parses "latitude,longitude,radius", radius in km, or with unit "m" or "km"
+func ParseGeoRadius(s string) (*GeoRadius, error) {
+
+	parts := strings.Split(s, ",")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid radius '%s', expected latitude,longitude,radius", s)
+	}
+
+	factor := 1.0
+	radius := strings.ToLower(strings.TrimSpace(parts[2]))
+	if strings.HasSuffix(radius, "km") {
+		radius = strings.TrimSuffix(radius, "km")
+	} else if strings.HasSuffix(radius, "m") {
+		radius = strings.TrimSuffix(radius, "m")
+		factor = 0.001
+	}
+	parts[2] = radius
+
+	values, err := parseFloats(strings.Join(parts, ","), 3)
+	if err != nil {
+		return nil, err
+	}
+	r := &GeoRadius{Latitude: values[0], Longitude: values[1], Radius: values[2] * factor}
+	if r.Latitude < -90 || r.Latitude > 90 || r.Longitude < -180 || r.Longitude > 180 || r.Radius <= 0 {
+		return nil, fmt.Errorf("invalid radius '%s', expected latitude,longitude,radius", s)
+	}
+	return r, nil
+}
+
+// parseFloats parses count comma separated numbers
+func parseFloats(s string, count int) ([]float64, error) {
+
+	parts := strings.Split(s, ",")
+	if len(parts) != count {
+		return nil, fmt.Errorf("expected %d comma separated numbers, got '%s'", count, s)
+	}
+	values := make([]float64, count)
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s'", part)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
@@ -0,0 +1,24 @@
+package metadata_db
+
+import (
+	"strings"
+)
+
+type FinderByOwner struct {
+}
+
+// Find searches all assets having an origin with the given owner
+func (f FinderByOwner) Find(name any) (*FinderQuery, error) {
+
+	var sName = name.(string)
+	if len(sName) == 0 {
+		return nil, nil
+	}
+
+	var query = "SELECT DISTINCT o.asset AS id, 0.0 AS score FROM origin o " +
+		"INNER JOIN owner w ON w.id = o.owner " +
+		"WHERE w.name LIKE ?"
+
+	return newFinderQuery(query, strings.ReplaceAll(sName, "*", "%")), nil
+
+}
@@ -1,25 +1,18 @@
 package metadata_db
 
-import "time"
-
 type FinderByPathId struct {
 }
 
 // Find searches all assets assigned to given path id
-func (f FinderByPathId) Find(pathId any) (ScoredIdMap, error) {
+func (f FinderByPathId) Find(pathId any) (*FinderQuery, error) {
 
 	if pathId.(int64) == 0 {
 		return nil, nil
 	}
 
-	var query = "SELECT a.id, a.fileTime FROM origin o " +
-		"INNER JOIN asset a ON o.asset = a.id " +
-		"WHERE path = ?;"
+	var query = "SELECT DISTINCT o.asset AS id, 0.0 AS score FROM origin o " +
+		"WHERE o.path = ?"
 
-	return findAssetIds(func(id int64, match any, idMap *ScoredIdMap) {
-		dt := match.(time.Time)
-		score := float32(dt.Unix()) / float32(1000.0)
-		idMap.Set(id, score)
-	}, query, pathId)
+	return newFinderQuery(query, pathId), nil
 
 }
@@ -8,20 +8,20 @@ import (
 type FinderByPathName struct {
 }
 
-// Find searches all assets assigned to given path id
-func (f FinderByPathName) Find(name any) (ScoredIdMap, error) {
+// Find searches all assets having a path item (or one of its parents) matching the given name
+func (f FinderByPathName) Find(name any) (*FinderQuery, error) {
 
 	var sName = name.(string)
 	if len(sName) == 0 {
 		return nil, nil
 	}
 
-	//TODO search parents
-
-	var query = "SELECT a.id, p.name FROM origin o " +
-		"INNER JOIN pathItem p ON p.id = o.path " +
-		"INNER JOIN asset a ON o.asset = a.id " +
-		"WHERE p.name like ?;"
+	//Score: length of search term relative to length of the name, added for each matching origin and parent
+	var query = "SELECT o.asset AS id, SUM(? * 1.0 / length(p.name)) AS score FROM origin o " +
+		"INNER JOIN pathItemClosure c ON c.descendant = o.path " +
+		"INNER JOIN pathItem p ON p.id = c.ancestor " +
+		"WHERE p.name like ? " +
+		"GROUP BY o.asset"
 
 	var findName = sName
 	if strings.Contains(findName, "*") {
@@ -32,9 +32,6 @@ func (f FinderByPathName) Find(name any) (ScoredIdMap, error) {
 	}
 	fmt.Printf("findAssetIdsByPathName: %s\n", findName)
 
-	return findAssetIds(func(id int64, match any, idMap *ScoredIdMap) {
-		score := float32(len(sName)) / float32(len(match.(string)))
-		idMap.Add(id, score)
-	}, query, findName)
+	return newFinderQuery(query, len(sName), findName), nil
 
 }
@@ -0,0 +1,102 @@
+package metadata_db
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	metadata_db_entity "github.com/c8121/asset-storage/internal/metadata-db-entity"
+	"github.com/c8121/asset-storage/internal/util"
+)
+
+type FinderByPathTree struct {
+}
+
+type FinderByPathPrefix struct {
+}
+
+// Find searches all assets assigned to given path id or any path below
+func (f FinderByPathTree) Find(pathId any) (*FinderQuery, error) {
+
+	if pathId.(int64) == 0 {
+		return nil, nil
+	}
+
+	return pathTreeQuery([]int64{pathId.(int64)}), nil
+}
+
+// Find searches all assets below a full path (like /home/anna/Photos/2019).
+// If the last path segment does not exist, it is used as prefix (/home/anna/Photos/20 finds 2019, 2020, ...).
+func (f FinderByPathPrefix) Find(path any) (*FinderQuery, error) {
+
+	var sPath = path.(string)
+	if len(sPath) == 0 {
+		return nil, nil
+	}
+
+	fmt.Printf("findAssetIdsByPathPrefix: %s\n", sPath)
+
+	pathItem, err := metadata_db_entity.GetPathItem(sPath, false)
+	if err == nil {
+		return pathTreeQuery([]int64{pathItem.Id}), nil
+	} else if !errors.Is(err, metadata_db_entity.ErrNotFound) {
+		return nil, err
+	}
+
+	//Search siblings starting with the last segment
+	names := metadata_db_entity.SplitPath(sPath)
+	var parentId int64 = 0
+	if len(names) > 1 {
+		parent, err := metadata_db_entity.GetPathItem(strings.Join(names[:len(names)-1], "/"), false)
+		if errors.Is(err, metadata_db_entity.ErrNotFound) {
+			return pathTreeQuery(nil), nil
+		} else if err != nil {
+			return nil, err
+		}
+		parentId = parent.Id
+	}
+
+	children, err := findPathItemIds(parentId, names[len(names)-1])
+	if err != nil {
+		return nil, err
+	}
+	return pathTreeQuery(children), nil
+}
+
+// pathTreeQuery selects all assets assigned to one of the path items or its descendants
+func pathTreeQuery(pathIds []int64) *FinderQuery {
+
+	if len(pathIds) == 0 {
+		return newFinderQuery("SELECT a.id AS id, 0.0 AS score FROM asset a WHERE 0")
+	}
+
+	args := make([]any, len(pathIds))
+	for i, id := range pathIds {
+		args[i] = id
+	}
+
+	return newFinderQuery("SELECT DISTINCT o.asset AS id, 0.0 AS score FROM origin o "+
+		"INNER JOIN pathItemClosure c ON c.descendant = o.path "+
+		"WHERE c.ancestor IN ("+strings.Repeat("?,", len(pathIds)-1)+"?)", args...)
+}
+
+// findPathItemIds returns id's of all children of parent having a name starting with prefix
+func findPathItemIds(parentId int64, prefix string) ([]int64, error) {
+
+	rows, err := db.Query("SELECT id FROM pathItem WHERE parent = ? AND name LIKE ?;",
+		parentId, strings.ReplaceAll(prefix, "*", "%")+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer util.CloseOrLog(rows)
+
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
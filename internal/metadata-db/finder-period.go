@@ -0,0 +1,17 @@
+package metadata_db
+
+type FinderByPeriod struct {
+}
+
+// Find searches all assets stored in the given storage time-period (see storage.TimePeriodName)
+func (f FinderByPeriod) Find(period any) (*FinderQuery, error) {
+
+	if len(period.(string)) == 0 {
+		return nil, nil
+	}
+
+	var query = "SELECT a.id AS id, 0.0 AS score FROM asset a WHERE a.period = ?"
+
+	return newFinderQuery(query, period), nil
+
+}
@@ -0,0 +1,29 @@
+package metadata_db
+
+import (
+	"strconv"
+	"strings"
+)
+
+type FinderByPerson struct {
+}
+
+// Find searches all assets showing the given person (id or name, see faces.ClusterPersons).
+// Score is the number of faces of the person in the asset.
+func (f FinderByPerson) Find(person any) (*FinderQuery, error) {
+
+	var sPerson = strings.TrimSpace(person.(string))
+	if len(sPerson) == 0 {
+		return nil, nil
+	}
+
+	var query = "SELECT pf.asset AS id, COUNT(*) * 1.0 AS score FROM personFace pf "
+	if id, err := strconv.ParseInt(sPerson, 10, 64); err == nil {
+		query += "WHERE pf.person = ? GROUP BY pf.asset"
+		return newFinderQuery(query, id), nil
+	}
+
+	query += "INNER JOIN person p ON p.id = pf.person " +
+		"WHERE p.name LIKE ? GROUP BY pf.asset"
+	return newFinderQuery(query, strings.ReplaceAll(sPerson, "*", "%")), nil
+}
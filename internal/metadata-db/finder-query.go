@@ -0,0 +1,129 @@
+package metadata_db
+
+import (
+	"fmt"
+	"strings"
+
+	metadata_db_entity "github.com/c8121/asset-storage/internal/metadata-db-entity"
+)
+
+type FinderByQuery struct {
+}
+
+// QueryError describes a syntax error, Position is 1-based
+type QueryError struct {
+	Position int
+	Message  string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("invalid query at position %d: %s", e.Position, e.Message)
+}
+
+// Find searches the full-text index (file names, path names, description, tags, content).
+// Supports words, "quoted phrases", prefix* and OR/NOT, ranked by BM25.
+func (f FinderByQuery) Find(expression any) (*FinderQuery, error) {
+
+	var sQuery = expression.(string)
+	ftsQuery, err := ToFtsQuery(sQuery)
+	if err != nil {
+		return nil, err
+	}
+	if len(ftsQuery) == 0 {
+		return nil, nil
+	}
+
+	weights := make([]string, len(metadata_db_entity.SearchIndexRankWeights))
+	for i, w := range metadata_db_entity.SearchIndexRankWeights {
+		weights[i] = fmt.Sprintf("%f", w)
+	}
+
+	//bm25: lower (more negative) is better
+	var query = "SELECT rowid AS id, -bm25(assetSearch, " + strings.Join(weights, ", ") + ") AS score FROM assetSearch " +
+		"WHERE assetSearch MATCH ?"
+
+	fmt.Printf("findAssetIdsByQuery: %s\n", ftsQuery)
+
+	return newFinderQuery(query, ftsQuery), nil
+
+}
+
+// ToFtsQuery converts user input into a FTS5 query expression.
+// Each word is quoted to avoid syntax errors caused by special characters,
+// "phrases" are kept, a trailing asterisk is used as prefix query.
+// OR and NOT are kept as operators, all other terms are combined with AND.
+// NOT excludes the following term from the terms before (like "beach NOT sea"),
+// returns a QueryError if there is no term before (like "NOT sea" or "beach OR NOT sea"), which FTS5 cannot search.
+func ToFtsQuery(s string) (string, error) {
+
+	terms := make([]string, 0)
+	lastWasOperator := true
+	lastOperator := ""
+
+	addOperator := func(op string, pos int) error {
+		if lastOperator == "NOT" {
+			return &QueryError{pos + 1, "expected term after NOT"}
+		}
+		if op == "NOT" && lastWasOperator {
+			return &QueryError{pos + 1, "NOT requires a term before, like 'beach NOT sea'"}
+		}
+		if !lastWasOperator {
+			terms = append(terms, op)
+			lastWasOperator = true
+			lastOperator = op
+		}
+		return nil
+	}
+	addTerm := func(term string, prefix bool) {
+		term = strings.ReplaceAll(term, "\"", "")
+		if len(strings.TrimSpace(term)) == 0 {
+			return
+		}
+		quoted := "\"" + term + "\""
+		if prefix {
+			quoted += "*"
+		}
+		terms = append(terms, quoted)
+		lastWasOperator = false
+		lastOperator = ""
+	}
+
+	l := len(s)
+	for i := 0; i < l; {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"':
+			e := strings.Index(s[i+1:], "\"")
+			if e == -1 {
+				e = l - i - 1
+			}
+			addTerm(s[i+1:i+1+e], false)
+			i += e + 2
+		default:
+			e := strings.IndexAny(s[i:], " \t\n\r")
+			if e == -1 {
+				e = l - i
+			}
+			word := s[i : i+e]
+			switch word {
+			case "OR", "NOT":
+				if err := addOperator(word, i); err != nil {
+					return "", err
+				}
+			case "AND":
+			default:
+				prefix := strings.HasSuffix(word, "*")
+				addTerm(strings.TrimRight(word, "*"), prefix)
+			}
+			i += e
+		}
+	}
+
+	if lastWasOperator && len(terms) > 0 {
+		terms = terms[:len(terms)-1]
+	}
+
+	return strings.Join(terms, " "), nil
+}
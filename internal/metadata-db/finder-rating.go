@@ -0,0 +1,16 @@
+package metadata_db
+
+type FinderByRating struct {
+}
+
+// Find searches all assets rated with at least the given rating
+func (f FinderByRating) Find(minRating any) (*FinderQuery, error) {
+
+	if minRating.(int) <= 0 {
+		return nil, nil
+	}
+
+	var query = "SELECT a.id AS id, 0.0 AS score FROM asset a WHERE a.rating >= ?"
+
+	return newFinderQuery(query, minRating), nil
+}
@@ -0,0 +1,21 @@
+package metadata_db
+
+import (
+	"strings"
+)
+
+type FinderByRelated struct {
+}
+
+// Find searches all assets related to the asset with the given hash (attachments of a message, files of an archive, versions)
+func (f FinderByRelated) Find(hash any) (*FinderQuery, error) {
+
+	var sHash = strings.ToLower(strings.TrimSpace(hash.(string)))
+	if len(sHash) == 0 {
+		return nil, nil
+	}
+
+	var query = "SELECT DISTINCT r.asset AS id, 0.0 AS score FROM assetRelation r WHERE r.related = ?"
+
+	return newFinderQuery(query, sHash), nil
+}
@@ -0,0 +1,40 @@
+package metadata_db
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type FinderBySimilarImage struct {
+}
+
+// DefaultImageHashDistance is the max Hamming distance of image hashes (see filter.DHash) to consider images similar
+var DefaultImageHashDistance = 8
+
+// Find searches all images looking similar to the image with the given hash.
+// Value is "<hash>" or "<hash>/<max distance>". Score is higher the more similar an image is.
+func (f FinderBySimilarImage) Find(similar any) (*FinderQuery, error) {
+
+	var sSimilar = similar.(string)
+	if len(sSimilar) == 0 {
+		return nil, nil
+	}
+
+	hash := sSimilar
+	maxDistance := DefaultImageHashDistance
+	if p := strings.Index(sSimilar, "/"); p != -1 {
+		hash = sSimilar[:p]
+		var err error
+		maxDistance, err = strconv.Atoi(sSimilar[p+1:])
+		if err != nil || maxDistance < 0 || maxDistance > 64 {
+			return nil, &FilterError{"SimilarTo", fmt.Errorf("invalid distance in '%s'", sSimilar)}
+		}
+	}
+
+	var query = "SELECT a.id AS id, (64 - hamming_distance(a.imageHash, s.imageHash)) / 64.0 AS score " +
+		"FROM asset a INNER JOIN asset s ON s.hash = ? " +
+		"WHERE a.imageHash IS NOT NULL AND hamming_distance(a.imageHash, s.imageHash) <= ?"
+
+	return newFinderQuery(query, hash, maxDistance), nil
+}
@@ -0,0 +1,92 @@
+package metadata_db
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type FinderBySize struct {
+}
+
+// SizeRange Min and Max are inclusive, zero means open range
+type SizeRange struct {
+	Min int64
+	Max int64
+}
+
+// Find searches all assets having a size within the given SizeRange
+func (f FinderBySize) Find(sizeRange any) (*FinderQuery, error) {
+
+	var r = sizeRange.(SizeRange)
+	if r.Min <= 0 && r.Max <= 0 {
+		return nil, nil
+	}
+
+	var query = "SELECT a.id AS id, 0.0 AS score FROM asset a WHERE 1=1"
+	var params = make([]any, 0)
+	if r.Min > 0 {
+		query += " AND a.size >= ?"
+		params = append(params, r.Min)
+	}
+	if r.Max > 0 {
+		query += " AND a.size <= ?"
+		params = append(params, r.Max)
+	}
+
+	fmt.Printf("findAssetIdsBySize: %d - %d\n", r.Min, r.Max)
+
+	return newFinderQuery(query, params...), nil
+
+}
+
+// ParseSize parses sizes like 1024, 500K, 10MB, 1.5G (units are powers of 1024)
+func ParseSize(s string) (int64, error) {
+
+	units := []struct {
+		suffix string
+		factor float64
+	}{
+		{"KB", 1024}, {"MB", 1024 * 1024}, {"GB", 1024 * 1024 * 1024}, {"TB", 1024 * 1024 * 1024 * 1024},
+		{"K", 1024}, {"M", 1024 * 1024}, {"G", 1024 * 1024 * 1024}, {"T", 1024 * 1024 * 1024 * 1024},
+		{"B", 1},
+	}
+
+	v := strings.ToUpper(strings.TrimSpace(s))
+	factor := 1.0
+	for _, u := range units {
+		if strings.HasSuffix(v, u.suffix) {
+			v = strings.TrimSpace(v[:len(v)-len(u.suffix)])
+			factor = u.factor
+			break
+		}
+	}
+
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid size '%s'", s)
+	}
+	return int64(f * factor), nil
+}
+
+// ParseSizeRange parses >size, <size, min-max or an exact size
+func ParseSizeRange(s string) (SizeRange, error) {
+
+	var err error
+	r := SizeRange{}
+	switch {
+	case strings.HasPrefix(s, ">"):
+		r.Min, err = ParseSize(strings.TrimPrefix(s[1:], "="))
+	case strings.HasPrefix(s, "<"):
+		r.Max, err = ParseSize(strings.TrimPrefix(s[1:], "="))
+	case strings.Contains(s, "-"):
+		p := strings.Index(s, "-")
+		if r.Min, err = ParseSize(s[:p]); err == nil {
+			r.Max, err = ParseSize(s[p+1:])
+		}
+	default:
+		r.Min, err = ParseSize(s)
+		r.Max = r.Min
+	}
+	return r, err
+}
@@ -0,0 +1,26 @@
+package metadata_db
+
+import (
+	"strings"
+
+	metadata_db_entity "github.com/c8121/asset-storage/internal/metadata-db-entity"
+)
+
+type FinderByTag struct {
+}
+
+// Find searches all assets having the given tag
+func (f FinderByTag) Find(name any) (*FinderQuery, error) {
+
+	var sName = metadata_db_entity.NormalizeTagName(name.(string))
+	if len(sName) == 0 {
+		return nil, nil
+	}
+
+	var query = "SELECT DISTINCT x.asset AS id, 0.0 AS score FROM assetTag x " +
+		"INNER JOIN tag t ON t.id = x.tag " +
+		"WHERE t.name LIKE ?"
+
+	return newFinderQuery(query, strings.ReplaceAll(sName, "*", "%")), nil
+
+}
@@ -1,36 +1,105 @@
 package metadata_db
 
 import (
-	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/c8121/asset-storage/internal/util"
 )
 
-func findAssetIds(calcScore func(id int64, match any, idMap *ScoredIdMap), query string, args ...any) (ScoredIdMap, error) {
+// FinderQuery is a SQL query selecting 'id' and 'score' of matching assets.
+// It is used as sub-query, to combine multiple finders into one query.
+type FinderQuery struct {
+	Sql  string
+	Args []any
+}
 
-	stmt, err := db.Prepare(query)
-	if err != nil {
-		return nil, err
+func newFinderQuery(sql string, args ...any) *FinderQuery {
+	return &FinderQuery{Sql: sql, Args: args}
+}
+
+// allAssetsQuery selects all assets, used if nothing is filtered or to negate
+func allAssetsQuery() *FinderQuery {
+	return newFinderQuery("SELECT a.id AS id, 0.0 AS score FROM asset a")
+}
+
+// existingAssetsQuery selects all assets not moved to trash, scores are zero
+func existingAssetsQuery() *FinderQuery {
+	return newFinderQuery("SELECT a.id AS id, 0.0 AS score FROM asset a WHERE a.deleted = 0")
+}
+
+// deletedAssetsQuery selects all assets moved to trash, scores are zero
+func deletedAssetsQuery() *FinderQuery {
+	return newFinderQuery("SELECT a.id AS id, 0.0 AS score FROM asset a WHERE a.deleted = 1")
+}
+
+// intersectQueries selects assets matching all includes and none of the excludes. Scores are added.
+// If includes is empty, all assets except excludes are selected.
+func intersectQueries(includes []*FinderQuery, excludes []*FinderQuery) *FinderQuery {
+
+	if len(includes) == 0 {
+		includes = []*FinderQuery{allAssetsQuery()}
+	}
+	if len(includes) == 1 && len(excludes) == 0 {
+		return includes[0]
 	}
-	defer util.CloseOrLog(stmt)
 
-	if rows, err := stmt.Query(args...); err == nil {
-		defer util.CloseOrLog(rows)
+	var from strings.Builder
+	scores := make([]string, len(includes))
+	args := make([]any, 0)
 
-		ids := make(ScoredIdMap)
+	for i, q := range includes {
+		alias := "q" + strconv.Itoa(i)
+		scores[i] = alias + ".score"
+		if i == 0 {
+			from.WriteString(" FROM (" + q.Sql + ") " + alias)
+		} else {
+			from.WriteString(" INNER JOIN (" + q.Sql + ") " + alias + " ON " + alias + ".id = q0.id")
+		}
+		args = append(args, q.Args...)
+	}
 
-		for rows.Next() {
-			var id int64
-			var match any
-			if err := rows.Scan(&id, &match); err != nil {
-				return nil, err
-			}
-			calcScore(id, match, &ids)
+	for i, q := range excludes {
+		if i == 0 {
+			from.WriteString(" WHERE ")
+		} else {
+			from.WriteString(" AND ")
 		}
+		from.WriteString("q0.id NOT IN (SELECT id FROM (" + q.Sql + "))")
+		args = append(args, q.Args...)
+	}
+
+	return newFinderQuery("SELECT q0.id AS id, "+strings.Join(scores, " + ")+" AS score"+from.String(), args...)
+}
+
+// unionQueries selects assets matching at least one of the queries, keeps the highest score.
+func unionQueries(queries []*FinderQuery) *FinderQuery {
+
+	if len(queries) == 1 {
+		return queries[0]
+	}
+
+	parts := make([]string, len(queries))
+	args := make([]any, 0)
+	for i, q := range queries {
+		parts[i] = "SELECT id, score FROM (" + q.Sql + ")"
+		args = append(args, q.Args...)
+	}
+
+	return newFinderQuery("SELECT id, MAX(score) AS score FROM ("+
+		strings.Join(parts, " UNION ALL ")+") GROUP BY id", args...)
+}
+
+// Count returns the number of assets selected by the query
+func (q *FinderQuery) Count() (int, error) {
 
-		fmt.Printf("Found %d items\n", len(ids))
-		return ids, nil
-	} else {
-		return nil, err
+	stmt, err := db.Prepare("SELECT COUNT(*) FROM (" + q.Sql + ");")
+	if err != nil {
+		return 0, err
 	}
+	defer util.CloseOrLog(stmt)
+
+	var count int
+	err = stmt.QueryRow(q.Args...).Scan(&count)
+	return count, err
 }
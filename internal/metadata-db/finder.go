@@ -1,6 +1,6 @@
 package metadata_db
 
 type Finder interface {
-	//Find searches in the metadata database
-	Find(query any) (ScoredIdMap, error)
+	//Find creates a sub-query selecting matching assets, returns nil if nothing is to be filtered
+	Find(query any) (*FinderQuery, error)
 }
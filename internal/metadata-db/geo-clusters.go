@@ -0,0 +1,95 @@
+package metadata_db
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/c8121/asset-storage/internal/util"
+)
+
+// GeoJsonFeatureCollection of points, see https://geojson.org
+type GeoJsonFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []GeoJsonFeature `json:"features"`
+}
+
+type GeoJsonFeature struct {
+	Type       string               `json:"type"`
+	Geometry   GeoJsonPoint         `json:"geometry"`
+	Properties GeoClusterProperties `json:"properties"`
+}
+
+type GeoJsonPoint struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"` //Longitude, latitude
+}
+
+// GeoClusterProperties describes all assets of a cluster, Hash is the latest captured asset
+type GeoClusterProperties struct {
+	Count int
+	Hash  string
+}
+
+const (
+	MaxZoom = 22
+
+	// GeoClustersPerTile is the number of clusters per map tile (256 pixels) in each direction
+	GeoClustersPerTile = 4
+)
+
+var (
+	// MaxGeoClusters limits the number of clusters returned, largest first
+	MaxGeoClusters = 10000
+)
+
+// ListGeoClusters returns located assets matching the filter, grouped by a grid depending on the zoom level of a map (0-22).
+// The position of a cluster is the average position of its assets. Offset, Count and Sort of the filter are ignored.
+func ListGeoClusters(filter *AssetListFilter, zoom int) (*GeoJsonFeatureCollection, error) {
+
+	if zoom < 0 || zoom > MaxZoom {
+		return nil, &FilterError{"zoom", fmt.Errorf("zoom %d out of range 0-%d", zoom, MaxZoom)}
+	}
+
+	matches, err := FindAssets(filter)
+	if err != nil {
+		return nil, err
+	}
+	if matches == nil {
+		matches = existingAssetsQuery()
+	}
+
+	//Grid size in degrees
+	cellSize := 360.0 / math.Pow(2, float64(zoom)) / GeoClustersPerTile
+
+	//SQLite returns the other columns from the row having MAX(captureTime)
+	var query = "WITH matches(id, score) AS (" + matches.Sql + ") " +
+		"SELECT COUNT(*) AS cnt, AVG(a.latitude), AVG(a.longitude), a.hash, MAX(a.captureTime) FROM matches x " +
+		"INNER JOIN asset a ON a.id = x.id " +
+		"WHERE a.latitude IS NOT NULL AND a.longitude IS NOT NULL " +
+		"GROUP BY CAST((a.latitude + 90) / ? AS INTEGER), CAST((a.longitude + 180) / ? AS INTEGER) " +
+		"ORDER BY cnt DESC LIMIT ?;"
+
+	params := make([]any, 0, len(matches.Args)+3)
+	params = append(params, matches.Args...)
+	params = append(params, cellSize, cellSize, MaxGeoClusters)
+
+	rows, err := db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer util.CloseOrLog(rows)
+
+	result := &GeoJsonFeatureCollection{Type: "FeatureCollection", Features: make([]GeoJsonFeature, 0)}
+	for rows.Next() {
+		var latitude, longitude float64
+		var captureTime any
+		feature := GeoJsonFeature{Type: "Feature", Geometry: GeoJsonPoint{Type: "Point"}}
+		if err := rows.Scan(&feature.Properties.Count, &latitude, &longitude, &feature.Properties.Hash, &captureTime); err != nil {
+			return nil, err
+		}
+		feature.Geometry.Coordinates = [2]float64{longitude, latitude}
+		result.Features = append(result.Features, feature)
+	}
+
+	return result, rows.Err()
+}
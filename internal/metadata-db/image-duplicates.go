@@ -0,0 +1,131 @@
+package metadata_db
+
+import (
+	"math/bits"
+	"sort"
+
+	"github.com/c8121/asset-storage/internal/util"
+)
+
+// SimilarImageGroup is a cluster of images having similar image hashes (see filter.DHash)
+type SimilarImageGroup struct {
+	Items []AssetListItem
+}
+
+// bkNode is a node of a BK-tree, used to find hashes within a Hamming distance without comparing all pairs
+type bkNode struct {
+	hash     uint64
+	index    int
+	children map[int]*bkNode
+}
+
+func (n *bkNode) add(hash uint64, index int) {
+	for {
+		d := bits.OnesCount64(n.hash ^ hash)
+		child, ok := n.children[d]
+		if !ok {
+			n.children[d] = &bkNode{hash: hash, index: index, children: make(map[int]*bkNode)}
+			return
+		}
+		n = child
+	}
+}
+
+func (n *bkNode) find(hash uint64, maxDistance int, found func(index int)) {
+	d := bits.OnesCount64(n.hash ^ hash)
+	if d <= maxDistance {
+		found(n.index)
+	}
+	for childDistance, child := range n.children {
+		if childDistance >= d-maxDistance && childDistance <= d+maxDistance {
+			child.find(hash, maxDistance, found)
+		}
+	}
+}
+
+// FindSimilarImageGroups returns all groups of at least two images with image hashes within maxDistance.
+// Groups are transitive: if A is similar to B and B to C, all three are in one group.
+// Deleted assets are ignored.
+// Largest groups first, items of a group sorted by file-time (oldest first).
+func FindSimilarImageGroups(maxDistance int) ([]SimilarImageGroup, error) {
+
+	var query = "SELECT a.id, a.hash, m.name as mimeType, a.fileTime, f.name, a.size, a.captureTime, a.imageHash " +
+		" FROM asset a " +
+		" INNER JOIN mimeType m ON a.mimeType = m.id " +
+		" INNER JOIN fileName f ON a.name = f.id " +
+		" WHERE a.imageHash IS NOT NULL AND a.deleted = 0 ORDER BY a.fileTime ASC, a.hash ASC;"
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer util.CloseOrLog(rows)
+
+	items := make([]AssetListItem, 0)
+	hashes := make([]uint64, 0)
+	for rows.Next() {
+		var item AssetListItem
+		var hash int64
+		if err := rows.Scan(&item.Id, &item.Hash, &item.MimeType, &item.FileTime, &item.Name, &item.Size, &item.CaptureTime, &hash); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		hashes = append(hashes, uint64(hash))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	//Union-find over all pairs found in the BK-tree
+	parent := make([]int, len(items))
+	var root func(i int) int
+	root = func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+
+	var tree *bkNode
+	for i, hash := range hashes {
+		parent[i] = i
+		if tree == nil {
+			tree = &bkNode{hash: hash, index: i, children: make(map[int]*bkNode)}
+			continue
+		}
+		tree.find(hash, maxDistance, func(j int) {
+			ri, rj := root(i), root(j)
+			if ri != rj {
+				//Keep the lower index as root, it is the oldest item
+				parent[max(ri, rj)] = min(ri, rj)
+			}
+		})
+		tree.add(hash, i)
+	}
+
+	groupByRoot := make(map[int]*SimilarImageGroup)
+	roots := make([]int, 0)
+	for i, item := range items {
+		r := root(i)
+		group, ok := groupByRoot[r]
+		if !ok {
+			group = &SimilarImageGroup{}
+			groupByRoot[r] = group
+			roots = append(roots, r)
+		}
+		group.Items = append(group.Items, item)
+	}
+
+	groups := make([]SimilarImageGroup, 0)
+	for _, r := range roots {
+		if len(groupByRoot[r].Items) > 1 {
+			groups = append(groups, *groupByRoot[r])
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return len(groups[i].Items) > len(groups[j].Items)
+	})
+
+	return groups, nil
+}
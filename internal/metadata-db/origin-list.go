@@ -0,0 +1,46 @@
+package metadata_db
+
+import (
+	"strings"
+
+	"github.com/c8121/asset-storage/internal/metadata"
+	metadata_db_entity "github.com/c8121/asset-storage/internal/metadata-db-entity"
+)
+
+// OriginListItem is an origin of an asset with its full path
+type OriginListItem struct {
+	Index int //Index in JsonAssetMetaData.Origins, used to hide or remove the origin
+	metadata.JsonAssetOrigin
+	PathId   int64  //PathItem of Path, 0 if not in database (hidden origins for example)
+	FullPath string //Path including name
+	Latest   bool   //Origin used as name, see metadata.GetLatestOrigin
+}
+
+// ListOrigins returns all origins of an asset, including hidden origins
+func ListOrigins(meta *metadata.JsonAssetMetaData) []OriginListItem {
+
+	latest := metadata.GetLatestOrigin(meta)
+
+	items := make([]OriginListItem, 0, len(meta.Origins))
+	for i, origin := range meta.Origins {
+
+		item := OriginListItem{
+			Index:           i,
+			JsonAssetOrigin: origin,
+			Latest:          latest != nil && *latest == origin,
+		}
+
+		if pathItem, err := metadata_db_entity.GetPathItem(origin.Path, false); err == nil {
+			item.PathId = pathItem.Id
+		}
+		//Like PathItemPath.FullPath
+		names := append(metadata_db_entity.SplitPath(origin.Path), origin.Name)
+		if names[0] == "" {
+			names = names[1:]
+		}
+		item.FullPath = "/" + strings.Join(names, "/")
+
+		items = append(items, item)
+	}
+	return items
+}
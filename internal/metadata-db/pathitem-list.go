@@ -1,14 +1,43 @@
 package metadata_db
 
 import (
+	"strings"
+
 	metadata_db_entity "github.com/c8121/asset-storage/internal/metadata-db-entity"
 	"github.com/c8121/asset-storage/internal/util"
 )
 
-func ListPathItems(parentId int) ([]metadata_db_entity.PathItem, error) {
+// PathItemListItem is a PathItem with number of children, number and total size of all assets below (recursive, without deleted assets)
+type PathItemListItem struct {
+	metadata_db_entity.PathItem
+	Children   int
+	AssetCount int
+	TotalSize  int64
+}
+
+// PathItemPath is the full path of a PathItem, Items are ordered from root to the item itself
+type PathItemPath struct {
+	Id       int64
+	FullPath string
+	Items    []metadata_db_entity.PathItem
+}
+
+// ListPathItems returns one page of children of a PathItem (0 = root items)
+func ListPathItems(parentId int64, offset int, count int) ([]PathItemListItem, error) {
 
-	var query = "SELECT id, parent, name " +
-		" FROM pathItem WHERE parent = ? ORDER BY name, id asc LIMIT 9999;"
+	//Counts are grouped per item of the page, assets are counted once per item even if they have several origins below
+	var query = "WITH page AS (SELECT id, parent, name FROM pathItem WHERE parent = ? ORDER BY name, id LIMIT ? OFFSET ?), " +
+		" children AS (SELECT c.parent AS id, COUNT(*) AS n FROM page p " +
+		"   INNER JOIN pathItem c ON c.parent = p.id GROUP BY c.parent), " +
+		" assets AS (SELECT x.id AS id, COUNT(*) AS n, COALESCE(SUM(a.size), 0) AS size " +
+		"   FROM (SELECT DISTINCT p.id AS id, o.asset AS asset FROM page p " +
+		"     INNER JOIN pathItemClosure c ON c.ancestor = p.id " +
+		"     INNER JOIN origin o ON o.path = c.descendant) x " +
+		"   INNER JOIN asset a ON a.id = x.asset WHERE a.deleted = 0 GROUP BY x.id) " +
+		"SELECT p.id, p.parent, p.name, COALESCE(ch.n, 0), COALESCE(s.n, 0), COALESCE(s.size, 0) FROM page p " +
+		" LEFT JOIN children ch ON ch.id = p.id " +
+		" LEFT JOIN assets s ON s.id = p.id " +
+		" ORDER BY p.name, p.id ASC;"
 
 	stmt, err := db.Prepare(query)
 	if err != nil {
@@ -16,13 +45,14 @@ func ListPathItems(parentId int) ([]metadata_db_entity.PathItem, error) {
 	}
 	defer util.CloseOrLog(stmt)
 
-	var items []metadata_db_entity.PathItem
+	var items []PathItemListItem
 
-	if rows, err := stmt.Query(parentId); err == nil {
+	if rows, err := stmt.Query(parentId, count, offset); err == nil {
 		defer util.CloseOrLog(rows)
 		for rows.Next() {
-			var item metadata_db_entity.PathItem
-			if err := rows.Scan(&item.Id, &item.Parent, &item.Name); err != nil {
+			var item PathItemListItem
+			if err := rows.Scan(&item.Id, &item.Parent, &item.Name,
+				&item.Children, &item.AssetCount, &item.TotalSize); err != nil {
 				return items, err
 			}
 			items = append(items, item)
@@ -34,3 +64,79 @@ func ListPathItems(parentId int) ([]metadata_db_entity.PathItem, error) {
 
 	return items, nil
 }
+
+// GetPathItemPath returns the PathItem and all its parents (breadcrumbs)
+func GetPathItemPath(pathItemId int64) (*PathItemPath, error) {
+
+	var query = "SELECT p.id, p.parent, p.name FROM pathItemClosure c " +
+		" INNER JOIN pathItem p ON p.id = c.ancestor " +
+		" WHERE c.descendant = ? ORDER BY c.depth DESC;"
+
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer util.CloseOrLog(stmt)
+
+	rows, err := stmt.Query(pathItemId)
+	if err != nil {
+		return nil, err
+	}
+	defer util.CloseOrLog(rows)
+
+	path := &PathItemPath{Id: pathItemId, Items: make([]metadata_db_entity.PathItem, 0)}
+	names := make([]string, 0)
+	for rows.Next() {
+		var item metadata_db_entity.PathItem
+		if err := rows.Scan(&item.Id, &item.Parent, &item.Name); err != nil {
+			return nil, err
+		}
+		path.Items = append(path.Items, item)
+		names = append(names, item.Name)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(path.Items) == 0 {
+		return nil, metadata_db_entity.ErrNotFound
+	}
+
+	path.FullPath = "/" + strings.Join(names, "/")
+	return path, nil
+}
+
+// ListAssetPaths returns the full paths of all origins of an asset
+func ListAssetPaths(assetId int64) ([]PathItemPath, error) {
+
+	stmt, err := db.Prepare("SELECT DISTINCT path FROM origin WHERE asset = ? ORDER BY path;")
+	if err != nil {
+		return nil, err
+	}
+	defer util.CloseOrLog(stmt)
+
+	rows, err := stmt.Query(assetId)
+	if err != nil {
+		return nil, err
+	}
+	pathIds := make([]int64, 0)
+	for rows.Next() {
+		var pathId int64
+		if err := rows.Scan(&pathId); err != nil {
+			util.CloseOrLog(rows)
+			return nil, err
+		}
+		pathIds = append(pathIds, pathId)
+	}
+	util.CloseOrLog(rows)
+
+	paths := make([]PathItemPath, 0, len(pathIds))
+	for _, pathId := range pathIds {
+		path, err := GetPathItemPath(pathId)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, *path)
+	}
+
+	return paths, nil
+}
@@ -0,0 +1,288 @@
+package metadata_db
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+	Search query language:
+
+		expression := or
+		or         := and ("OR" and)*
+		and        := unary (["AND"] unary)*
+		unary      := ("-" | "NOT") unary | primary
+		primary    := "(" expression ")" | term
+		term       := [field ":"] (word | "quoted phrase")
+
+	Example: mime:image/* name:holiday -path:tmp after:2022-01-01 owner:anna (tag:beach OR tag:sea)
+
+	Terms without field are searched in full-text index, see QueryFields for available fields.
+	Words with a colon which is not preceded by a field name (like http://example.com) are searched as text.
+*/
+
+type queryTokenType int
+
+const (
+	tokenWord queryTokenType = iota
+	tokenPhrase
+	tokenField
+	tokenLParen
+	tokenRParen
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenEnd
+)
+
+type queryToken struct {
+	Type  queryTokenType
+	Value string
+	Pos   int //0-based byte offset
+}
+
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+// ParseQuery parses a search query expression into a tree of QueryNode's
+func ParseQuery(s string) (QueryNode, error) {
+
+	tokens, err := tokenizeQuery(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &queryParser{tokens: tokens}
+	if p.peek().Type == tokenEnd {
+		return nil, &QueryError{1, "empty query"}
+	}
+
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.Type != tokenEnd {
+		if t.Type == tokenRParen {
+			return nil, &QueryError{t.Pos + 1, "unexpected ')'"}
+		}
+		return nil, &QueryError{t.Pos + 1, fmt.Sprintf("unexpected '%s'", t.Value)}
+	}
+
+	return node, nil
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() queryToken {
+	t := p.tokens[p.pos]
+	if t.Type != tokenEnd {
+		p.pos++
+	}
+	return t
+}
+
+func (p *queryParser) parseOr() (QueryNode, error) {
+
+	node, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []QueryNode{node}
+	for p.peek().Type == tokenOr {
+		op := p.next()
+		if !p.startsTerm() {
+			return nil, &QueryError{op.Pos + 1, "expected term after OR"}
+		}
+		node, err = p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return &QueryOr{Nodes: nodes}, nil
+}
+
+func (p *queryParser) parseAnd() (QueryNode, error) {
+
+	node, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []QueryNode{node}
+	for {
+		if p.peek().Type == tokenAnd {
+			op := p.next()
+			if !p.startsTerm() {
+				return nil, &QueryError{op.Pos + 1, "expected term after AND"}
+			}
+		} else if !p.startsTerm() {
+			break
+		}
+		node, err = p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return &QueryAnd{Nodes: nodes}, nil
+}
+
+func (p *queryParser) parseUnary() (QueryNode, error) {
+
+	if p.peek().Type == tokenNot {
+		op := p.next()
+		if !p.startsTerm() {
+			return nil, &QueryError{op.Pos + 1, "expected term after '" + op.Value + "'"}
+		}
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &QueryNot{Node: node}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() (QueryNode, error) {
+
+	t := p.next()
+	switch t.Type {
+	case tokenLParen:
+		if p.peek().Type == tokenRParen {
+			return nil, &QueryError{t.Pos + 1, "empty parentheses"}
+		}
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().Type != tokenRParen {
+			return nil, &QueryError{t.Pos + 1, "missing ')'"}
+		}
+		p.next()
+		return node, nil
+
+	case tokenWord:
+		return &QueryTerm{Value: t.Value, Position: t.Pos + 1}, nil
+
+	case tokenPhrase:
+		return &QueryTerm{Value: t.Value, Phrase: true, Position: t.Pos + 1}, nil
+
+	case tokenField:
+		v := p.next()
+		if v.Type != tokenWord && v.Type != tokenPhrase {
+			return nil, &QueryError{t.Pos + 1, "missing value for '" + t.Value + ":'"}
+		}
+		term := &QueryTerm{Field: t.Value, Value: v.Value, Phrase: v.Type == tokenPhrase, Position: t.Pos + 1}
+		if err := term.prepare(); err != nil {
+			return nil, err
+		}
+		return term, nil
+
+	case tokenRParen:
+		return nil, &QueryError{t.Pos + 1, "unexpected ')'"}
+
+	case tokenEnd:
+		return nil, &QueryError{t.Pos + 1, "unexpected end of query"}
+	}
+
+	return nil, &QueryError{t.Pos + 1, fmt.Sprintf("unexpected '%s'", t.Value)}
+}
+
+// startsTerm checks if next token can start a term
+func (p *queryParser) startsTerm() bool {
+	switch p.peek().Type {
+	case tokenWord, tokenPhrase, tokenField, tokenLParen, tokenNot:
+		return true
+	}
+	return false
+}
+
+// tokenizeQuery splits query into tokens
+func tokenizeQuery(s string) ([]queryToken, error) {
+
+	tokens := make([]queryToken, 0)
+	l := len(s)
+
+	for i := 0; i < l; {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '(':
+			tokens = append(tokens, queryToken{tokenLParen, "(", i})
+			i++
+
+		case c == ')':
+			tokens = append(tokens, queryToken{tokenRParen, ")", i})
+			i++
+
+		case c == '-':
+			tokens = append(tokens, queryToken{tokenNot, "-", i})
+			i++
+
+		case c == '"':
+			e := strings.IndexByte(s[i+1:], '"')
+			if e == -1 {
+				return nil, &QueryError{i + 1, "missing closing quote"}
+			}
+			tokens = append(tokens, queryToken{tokenPhrase, s[i+1 : i+1+e], i})
+			i += e + 2
+
+		default:
+			e := i
+			for e < l && !strings.ContainsRune(" \t\n\r()\"", rune(s[e])) {
+				e++
+			}
+			word := s[i:e]
+
+			//field:value, searched as text if not a field (like "http://example.com" or "note:")
+			if p := strings.IndexByte(word, ':'); p > 0 && isField(word[:p]) {
+				field := strings.ToLower(word[:p])
+				tokens = append(tokens, queryToken{tokenField, field, i})
+				if p+1 < len(word) {
+					tokens = append(tokens, queryToken{tokenWord, word[p+1:], i + p + 1})
+				}
+				i = e
+				continue
+			}
+
+			switch word {
+			case "OR":
+				tokens = append(tokens, queryToken{tokenOr, word, i})
+			case "AND":
+				tokens = append(tokens, queryToken{tokenAnd, word, i})
+			case "NOT":
+				tokens = append(tokens, queryToken{tokenNot, word, i})
+			default:
+				tokens = append(tokens, queryToken{tokenWord, word, i})
+			}
+			i = e
+		}
+	}
+
+	tokens = append(tokens, queryToken{tokenEnd, "", l})
+	return tokens, nil
+}
+
+// isField checks if s is the name of one of QueryFields (case-insensitive)
+func isField(s string) bool {
+	_, ok := QueryFields[strings.ToLower(s)]
+	return ok
+}
@@ -0,0 +1,244 @@
+package metadata_db
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// QueryNode is an element of a parsed search query, see ParseQuery
+type QueryNode interface {
+	//Find creates a sub-query selecting all assets matching this node
+	Find() (*FinderQuery, error)
+	String() string
+}
+
+type QueryAnd struct {
+	Nodes []QueryNode
+}
+
+type QueryOr struct {
+	Nodes []QueryNode
+}
+
+type QueryNot struct {
+	Node QueryNode
+}
+
+type QueryTerm struct {
+	Field    string //Empty for full-text search
+	Value    string
+	Phrase   bool
+	Position int
+
+	finderValue any
+}
+
+// QueryField maps a field prefix of the search query language to a Finder
+type QueryField struct {
+	Finder Finder
+	Value  func(term *QueryTerm) (any, error) //Converts term value into value for Finder
+}
+
+var (
+	QueryFields = map[string]QueryField{
+		"text":    {FinderByQuery{}, fullTextValue},
+		"name":    {FinderByFileName{}, stringValue},
+		"path":    {FinderByPathName{}, stringValue},
+		"pathid":  {FinderByPathId{}, int64Value},
+		"under":   {FinderByPathPrefix{}, stringValue},
+		"treeid":  {FinderByPathTree{}, int64Value},
+		"mime":    {FinderByMimeType{}, stringValue},
+		"type":    {FinderByMimeType{}, stringValue},
+		"owner":   {FinderByOwner{}, stringValue},
+		"tag":     {FinderByTag{}, stringValue},
+		"face":    {FinderByFace{}, stringValue},
+		"person":  {FinderByPerson{}, stringValue},
+		"after":   {FinderByFileTime{}, afterValue},
+		"before":  {FinderByFileTime{}, beforeValue},
+		"date":    {FinderByFileTime{}, dateValue},
+		"taken":   {FinderByCaptureTime{}, dateValue},
+		"period":  {FinderByPeriod{}, stringValue},
+		"size":    {FinderBySize{}, sizeValue},
+		"similar": {FinderBySimilarImage{}, stringValue},
+		"bbox":    {FinderByBoundingBox{}, boundingBoxValue},
+		"near":    {FinderByRadius{}, radiusValue},
+		"place":   {FinderByPlace{}, stringValue},
+		"rating":  {FinderByRating{}, intValue},
+		"related": {FinderByRelated{}, stringValue},
+	}
+
+	DefaultQueryField = "text"
+)
+
+// Find returns all assets matching all nodes. Scores are added.
+func (q *QueryAnd) Find() (*FinderQuery, error) {
+
+	includes := make([]*FinderQuery, 0)
+	excludes := make([]*FinderQuery, 0)
+
+	for _, node := range q.Nodes {
+		if not, ok := node.(*QueryNot); ok {
+			exclude, err := not.Node.Find()
+			if err != nil {
+				return nil, err
+			}
+			excludes = append(excludes, exclude)
+			continue
+		}
+
+		include, err := node.Find()
+		if err != nil {
+			return nil, err
+		}
+		includes = append(includes, include)
+	}
+
+	return intersectQueries(includes, excludes), nil
+}
+
+// Find returns all assets matching at least one node.
+func (q *QueryOr) Find() (*FinderQuery, error) {
+
+	queries := make([]*FinderQuery, 0, len(q.Nodes))
+	for _, node := range q.Nodes {
+		query, err := node.Find()
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, query)
+	}
+	return unionQueries(queries), nil
+}
+
+// Find returns all assets not matching the node.
+func (q *QueryNot) Find() (*FinderQuery, error) {
+
+	exclude, err := q.Node.Find()
+	if err != nil {
+		return nil, err
+	}
+	return intersectQueries(nil, []*FinderQuery{exclude}), nil
+}
+
+// Find uses the Finder of the field to search assets
+func (q *QueryTerm) Find() (*FinderQuery, error) {
+
+	if q.finderValue == nil {
+		if err := q.prepare(); err != nil {
+			return nil, err
+		}
+	}
+
+	field := QueryFields[q.getField()]
+	query, err := field.Finder.Find(q.finderValue)
+	if err != nil {
+		return nil, err
+	}
+	if query == nil {
+		//Finder ignored the value
+		return allAssetsQuery(), nil
+	}
+	return query, nil
+}
+
+// prepare validates the term value and converts it into the value used by the Finder
+func (q *QueryTerm) prepare() error {
+
+	field, ok := QueryFields[q.getField()]
+	if !ok {
+		return &QueryError{q.Position, "unknown field '" + q.Field + "'"}
+	}
+
+	value, err := field.Value(q)
+	if err != nil {
+		return &QueryError{q.Position, err.Error()}
+	}
+	q.finderValue = value
+	return nil
+}
+
+func (q *QueryTerm) getField() string {
+	if q.Field == "" {
+		return DefaultQueryField
+	}
+	return q.Field
+}
+
+func (q *QueryAnd) String() string {
+	return "AND(" + joinNodes(q.Nodes) + ")"
+}
+
+func (q *QueryOr) String() string {
+	return "OR(" + joinNodes(q.Nodes) + ")"
+}
+
+func (q *QueryNot) String() string {
+	return "NOT(" + q.Node.String() + ")"
+}
+
+func (q *QueryTerm) String() string {
+	return q.getField() + ":" + strconv.Quote(q.Value)
+}
+
+func joinNodes(nodes []QueryNode) string {
+	s := make([]string, len(nodes))
+	for i, node := range nodes {
+		s[i] = node.String()
+	}
+	return strings.Join(s, ", ")
+}
+
+func stringValue(term *QueryTerm) (any, error) {
+	return term.Value, nil
+}
+
+func fullTextValue(term *QueryTerm) (any, error) {
+	if term.Phrase {
+		return "\"" + term.Value + "\"", nil
+	}
+	return term.Value, nil
+}
+
+func int64Value(term *QueryTerm) (any, error) {
+	i, err := strconv.ParseInt(term.Value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number '%s'", term.Value)
+	}
+	return i, nil
+}
+
+func intValue(term *QueryTerm) (any, error) {
+	i, err := strconv.Atoi(term.Value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number '%s'", term.Value)
+	}
+	return i, nil
+}
+
+func afterValue(term *QueryTerm) (any, error) {
+	from, _, err := ParseDatePeriod(term.Value)
+	return FileTimeRange{From: from}, err
+}
+
+func beforeValue(term *QueryTerm) (any, error) {
+	from, _, err := ParseDatePeriod(term.Value)
+	return FileTimeRange{To: from}, err
+}
+
+func dateValue(term *QueryTerm) (any, error) {
+	from, to, err := ParseDatePeriod(term.Value)
+	return FileTimeRange{From: from, To: to}, err
+}
+
+func sizeValue(term *QueryTerm) (any, error) {
+	return ParseSizeRange(term.Value)
+}
+
+func boundingBoxValue(term *QueryTerm) (any, error) {
+	return ParseBoundingBox(term.Value)
+}
+
+func radiusValue(term *QueryTerm) (any, error) {
+	return ParseGeoRadius(term.Value)
+}
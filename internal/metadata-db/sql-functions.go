@@ -0,0 +1,49 @@
+package metadata_db
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math/bits"
+
+	"github.com/c8121/asset-storage/internal/geo"
+	"modernc.org/sqlite"
+)
+
+func init() {
+	//Registered functions are available in all connections opened afterwards
+	sqlite.MustRegisterDeterministicScalarFunction("hamming_distance", 2, hammingDistance)
+	sqlite.MustRegisterDeterministicScalarFunction("geo_distance", 4, geoDistance)
+}
+
+// hammingDistance returns the number of different bits of two integers, NULL if one of them is NULL
+func hammingDistance(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+
+	if args[0] == nil || args[1] == nil {
+		return nil, nil
+	}
+	a, ok1 := args[0].(int64)
+	b, ok2 := args[1].(int64)
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("hamming_distance: integer arguments expected")
+	}
+	return int64(bits.OnesCount64(uint64(a ^ b))), nil
+}
+
+// geoDistance returns the distance in km of two locations (lat1, lon1, lat2, lon2), NULL if one of them is NULL
+func geoDistance(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+
+	values := make([]float64, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case nil:
+			return nil, nil
+		case float64:
+			values[i] = v
+		case int64:
+			values[i] = float64(v)
+		default:
+			return nil, fmt.Errorf("geo_distance: numeric arguments expected")
+		}
+	}
+	return geo.Distance(values[0], values[1], values[2], values[3]), nil
+}
@@ -0,0 +1,110 @@
+package metadata_db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/c8121/asset-storage/internal/util"
+)
+
+// TimelineBucket is the number of assets captured within a year, month or day (Period is YYYY, YYYY-MM or YYYY-MM-DD)
+type TimelineBucket struct {
+	Period string
+	Count  int
+}
+
+const (
+	GranularityYear  = "year"
+	GranularityMonth = "month"
+	GranularityDay   = "day"
+)
+
+var (
+	//Granularity -> length of date prefix of captureTime (stored as time.Time.String() in UTC, see DbTimeFormat)
+	granularityLength = map[string]int{
+		GranularityYear:  4,
+		GranularityMonth: 7,
+		GranularityDay:   10,
+	}
+)
+
+// ListTimeline counts assets matching the filter per year, month or day of capture time, newest first.
+// Offset, Count and Sort of the filter are ignored.
+func ListTimeline(filter *AssetListFilter, granularity string) ([]TimelineBucket, error) {
+
+	if granularity == "" {
+		granularity = GranularityMonth
+	}
+	length, ok := granularityLength[granularity]
+	if !ok {
+		return nil, &FilterError{"Granularity", fmt.Errorf("unknown granularity '%s', expected year, month or day", granularity)}
+	}
+
+	matches, err := FindAssets(filter)
+	if err != nil {
+		return nil, err
+	}
+	if matches == nil {
+		matches = existingAssetsQuery()
+	}
+
+	var query = "WITH matches(id, score) AS (" + matches.Sql + ") " +
+		"SELECT substr(a.captureTime, 1, ?) AS bucket, COUNT(*) FROM matches x " +
+		"INNER JOIN asset a ON a.id = x.id " +
+		"GROUP BY bucket ORDER BY bucket DESC;"
+
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer util.CloseOrLog(stmt)
+
+	params := make([]any, 0, len(matches.Args)+1)
+	params = append(params, matches.Args...)
+	params = append(params, length)
+
+	rows, err := stmt.Query(params...)
+	if err != nil {
+		return nil, err
+	}
+	defer util.CloseOrLog(rows)
+
+	buckets := make([]TimelineBucket, 0)
+	for rows.Next() {
+		var bucket TimelineBucket
+		if err := rows.Scan(&bucket.Period, &bucket.Count); err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, bucket)
+	}
+
+	return buckets, rows.Err()
+}
+
+// ListOnThisDay returns assets matching the filter, captured on the same day and month as day in previous years.
+// Capture times are compared in UTC.
+// Default sort order is SortCaptureTimeDesc.
+func ListOnThisDay(filter *AssetListFilter, day time.Time) (*AssetList, error) {
+
+	sort := filter.Sort
+	if sort == "" {
+		sort = SortCaptureTimeDesc
+	} else if _, ok := sortOrders[sort]; !ok {
+		return nil, &FilterError{"Sort", fmt.Errorf("unknown sort order '%s'", sort)}
+	}
+
+	onThisDay := newFinderQuery("SELECT a.id AS id, 0.0 AS score FROM asset a "+
+		"WHERE substr(a.captureTime, 6, 5) = ? AND substr(a.captureTime, 1, 4) < ?",
+		day.UTC().Format("01-02"), day.UTC().Format("2006"))
+
+	matches, err := FindAssets(filter)
+	if err != nil {
+		return nil, err
+	}
+	if matches == nil {
+		matches = existingAssetsQuery()
+	}
+	matches = intersectQueries([]*FinderQuery{onThisDay, matches}, nil)
+
+	return listAssets(matches, sort, filter.Offset, filter.Count)
+}
@@ -0,0 +1,114 @@
+package metadata_db
+
+import (
+	"strings"
+
+	"github.com/c8121/asset-storage/internal/metadata"
+	"github.com/c8121/asset-storage/internal/util"
+)
+
+// VersionListItem is one asset of a lineage, see ListVersions
+type VersionListItem struct {
+	AssetListItem
+	DerivedFrom string //Hash of the asset this one is a version of, empty for the original
+	Depth       int    //Number of versions between the original and this one
+	Deleted     bool   //Moved to trash, listed to keep the lineage complete
+	Latest      bool   //No other (not deleted) version is derived from this one
+}
+
+// supersededVersionsQuery selects all assets having a derived version which is not deleted.
+// Deleted versions are followed, a version derived from a deleted version supersedes the original as well.
+func supersededVersionsQuery() *FinderQuery {
+	return newFinderQuery("WITH RECURSIVE down(original, id, depth) AS ("+
+		"SELECT o.id, r.asset, 1 FROM assetRelation r "+
+		"INNER JOIN asset o ON o.hash = r.related WHERE r.type = ? "+
+		"UNION SELECT down.original, r.asset, down.depth + 1 FROM down "+
+		"INNER JOIN asset d ON d.id = down.id AND d.deleted = 1 "+
+		"INNER JOIN assetRelation r ON r.related = d.hash AND r.type = ? "+
+		"WHERE down.depth < ?) "+
+		"SELECT DISTINCT down.original AS id, 0.0 AS score FROM down "+
+		"INNER JOIN asset d ON d.id = down.id AND d.deleted = 0",
+		metadata.RelationDerivedFrom, metadata.RelationDerivedFrom, metadata.MaxVersionDepth)
+}
+
+// ListVersions returns the lineage of an asset: the original (following DerivedFrom up)
+// and all versions derived from it, including deleted versions.
+// Sorted by depth, then file-time (oldest first). Empty if the asset is not in the database.
+func ListVersions(hash string) ([]VersionListItem, error) {
+
+	hash = strings.ToLower(hash)
+
+	//Ancestors of the asset, the last one is the original
+	var original string
+	err := db.QueryRow("WITH RECURSIVE up(hash, depth) AS ("+
+		"SELECT ?, 0 "+
+		"UNION SELECT r.related, up.depth + 1 FROM up "+
+		"INNER JOIN asset a ON a.hash = up.hash "+
+		"INNER JOIN assetRelation r ON r.asset = a.id AND r.type = ? "+
+		"WHERE up.depth < ?) "+
+		"SELECT hash FROM up ORDER BY depth DESC LIMIT 1;",
+		hash, metadata.RelationDerivedFrom, metadata.MaxVersionDepth).Scan(&original)
+	if err != nil {
+		return nil, err
+	}
+
+	//All versions derived from the original
+	var query = "WITH RECURSIVE down(id, derivedFrom, depth) AS (" +
+		"SELECT a.id, '', 0 FROM asset a WHERE a.hash = ? " +
+		"UNION SELECT r.asset, d.hash, down.depth + 1 FROM down " +
+		"INNER JOIN asset d ON d.id = down.id " +
+		"INNER JOIN assetRelation r ON r.related = d.hash AND r.type = ? " +
+		"WHERE down.depth < ?) " +
+		"SELECT a.id, a.hash, m.name as mimeType, a.fileTime, f.name, a.size, a.captureTime, a.rating, " +
+		"down.derivedFrom, down.depth, a.deleted FROM down " +
+		"INNER JOIN asset a ON a.id = down.id " +
+		"INNER JOIN mimeType m ON a.mimeType = m.id " +
+		"INNER JOIN fileName f ON a.name = f.id " +
+		"ORDER BY down.depth ASC, a.fileTime ASC, a.hash ASC;"
+
+	rows, err := db.Query(query, original, metadata.RelationDerivedFrom, metadata.MaxVersionDepth)
+	if err != nil {
+		return nil, err
+	}
+	defer util.CloseOrLog(rows)
+
+	items := make([]VersionListItem, 0)
+	for rows.Next() {
+		var item VersionListItem
+		if err := rows.Scan(&item.Id, &item.Hash, &item.MimeType, &item.FileTime, &item.Name, &item.Size, &item.CaptureTime, &item.Rating,
+			&item.DerivedFrom, &item.Depth, &item.Deleted); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	//Latest: not superseded by a version which is not deleted, following deleted versions
+	derived := make(map[string][]*VersionListItem)
+	for i := range items {
+		if items[i].DerivedFrom != "" {
+			derived[items[i].DerivedFrom] = append(derived[items[i].DerivedFrom], &items[i])
+		}
+	}
+	for i := range items {
+		items[i].Latest = !items[i].Deleted && !hasVersion(derived, items[i].Hash, 0)
+	}
+
+	return items, nil
+}
+
+// hasVersion returns true if a version which is not deleted is derived from hash,
+// directly or from deleted versions of it
+func hasVersion(derived map[string][]*VersionListItem, hash string, depth int) bool {
+	if depth >= metadata.MaxVersionDepth {
+		return false
+	}
+	for _, version := range derived[hash] {
+		if !version.Deleted || hasVersion(derived, version.Hash, depth+1) {
+			return true
+		}
+	}
+	return false
+}
@@ -30,7 +30,11 @@ func Open() {
 	util.PanicOnError(err, "Failed to open sqlite database: "+config.AssetMetaDataDb)
 
 	metadata_db.SetDatabase(db)
-	metadata_db_entity.AutoCreateEntities()
+	err = metadata_db_entity.Migrate()
+	util.PanicOnError(err, "Failed to create or migrate database "+config.AssetMetaDataDb)
+
+	err = metadata_db_entity.RebuildPathItemClosureIfEmpty()
+	util.PanicOnError(err, "Failed to create path-item closure")
 }
 
 // Close Disconnect from Database
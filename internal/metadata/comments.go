@@ -0,0 +1,179 @@
+package metadata
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// JsonComment is a comment on an asset, replies refer to the comment they answer (Parent)
+type JsonComment struct {
+	Id      int
+	Parent  int `json:",omitempty"` //Id of the comment this one replies to, 0 if not a reply
+	Author  string
+	Time    time.Time
+	Edited  time.Time `json:",omitzero"`
+	Text    string
+	Deleted bool `json:",omitempty"` //Deleted comment with replies, text is removed
+}
+
+const (
+	MaxCommentLength = 10000
+)
+
+var (
+	ErrCommentNotFound = errors.New("comment not found")
+	ErrNotAuthor       = errors.New("comment can only be changed by its author")
+)
+
+// AddComment adds a comment (or a reply if parent is not 0)
+func AddComment(hash string, parent int, text string, user string) (*JsonAssetMetaData, *JsonComment, error) {
+
+	text, err := validateComment(text)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var comment *JsonComment
+	meta, err := changeComments(hash, func(meta *JsonAssetMetaData) (*JsonHistoryEntry, error) {
+		if parent != 0 && meta.findComment(parent) < 0 {
+			return nil, fmt.Errorf("%w: %d", ErrCommentNotFound, parent)
+		}
+
+		//Next id after the highest id ever used, ids of deleted comments are not reused
+		id := max(meta.NextCommentId, 1)
+		for _, c := range meta.Comments {
+			id = max(id, c.Id+1)
+		}
+		meta.NextCommentId = id + 1
+		meta.Comments = append(meta.Comments, JsonComment{
+			Id:     id,
+			Parent: parent,
+			Author: user,
+			Time:   time.Now(),
+			Text:   text,
+		})
+		comment = &meta.Comments[len(meta.Comments)-1]
+		return commentHistoryEntry(HistoryCommentAdded, user, nil, comment), nil
+	})
+	return meta, comment, err
+}
+
+// UpdateComment changes the text of a comment, only allowed for its author
+func UpdateComment(hash string, id int, text string, user string) (*JsonAssetMetaData, *JsonComment, error) {
+
+	text, err := validateComment(text)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var comment *JsonComment
+	meta, err := changeComments(hash, func(meta *JsonAssetMetaData) (*JsonHistoryEntry, error) {
+		index, err := meta.findOwnComment(id, user)
+		if err != nil {
+			return nil, err
+		}
+		comment = &meta.Comments[index]
+		if comment.Text == text {
+			return nil, nil
+		}
+
+		old := *comment
+		comment.Text = text
+		comment.Edited = time.Now()
+		return commentHistoryEntry(HistoryCommentChanged, user, &old, comment), nil
+	})
+	return meta, comment, err
+}
+
+// DeleteComment removes a comment, only allowed for its author.
+// Comments with replies are kept as deleted (without text) to keep the thread.
+func DeleteComment(hash string, id int, user string) (*JsonAssetMetaData, error) {
+	return changeComments(hash, func(meta *JsonAssetMetaData) (*JsonHistoryEntry, error) {
+		index, err := meta.findOwnComment(id, user)
+		if err != nil {
+			return nil, err
+		}
+
+		old := meta.Comments[index]
+		for _, c := range meta.Comments {
+			if c.Parent == id {
+				meta.Comments[index].Text = ""
+				meta.Comments[index].Deleted = true
+				return commentHistoryEntry(HistoryCommentDeleted, user, &old, nil), nil
+			}
+		}
+		meta.Comments = append(meta.Comments[:index], meta.Comments[index+1:]...)
+		return commentHistoryEntry(HistoryCommentDeleted, user, &old, nil), nil
+	})
+}
+
+// changeComments loads meta-data, calls change and saves with the history entry returned by change
+// (nil if nothing changed)
+func changeComments(hash string, change func(meta *JsonAssetMetaData) (*JsonHistoryEntry, error)) (*JsonAssetMetaData, error) {
+
+	updateLock.Lock()
+	defer updateLock.Unlock()
+
+	meta, err := LoadByHash(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := change(meta)
+	if err != nil || entry == nil {
+		return meta, err
+	}
+	return meta, meta.saveWithHistory(*entry)
+}
+
+// findComment returns the index of a comment in Comments, -1 if not found
+func (assetMetaData *JsonAssetMetaData) findComment(id int) int {
+	for i, c := range assetMetaData.Comments {
+		if c.Id == id && !c.Deleted {
+			return i
+		}
+	}
+	return -1
+}
+
+// findOwnComment returns the index of a comment in Comments, error if not found or user is not the author
+func (assetMetaData *JsonAssetMetaData) findOwnComment(id int, user string) (int, error) {
+	index := assetMetaData.findComment(id)
+	if index < 0 {
+		return 0, fmt.Errorf("%w: %d", ErrCommentNotFound, id)
+	}
+	if assetMetaData.Comments[index].Author != user {
+		return 0, ErrNotAuthor
+	}
+	return index, nil
+}
+
+func validateComment(text string) (string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", fmt.Errorf("comment is empty")
+	}
+	if len(text) > MaxCommentLength {
+		return "", fmt.Errorf("comment is too long (max. %d bytes)", MaxCommentLength)
+	}
+	return text, nil
+}
+
+// commentHistoryEntry creates an entry with comment before and after a change (nil if added/deleted)
+func commentHistoryEntry(action string, user string, oldComment *JsonComment, newComment *JsonComment) *JsonHistoryEntry {
+	entry := &JsonHistoryEntry{
+		Time:   time.Now(),
+		User:   user,
+		Action: action,
+	}
+	if oldComment != nil {
+		entry.OldValue, _ = json.Marshal(oldComment)
+	}
+	if newComment != nil {
+		entry.NewValue, _ = json.Marshal(newComment)
+	}
+	return entry
+}
@@ -0,0 +1,398 @@
+package metadata
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/c8121/asset-storage/internal/config"
+	"github.com/c8121/asset-storage/internal/util"
+)
+
+// JsonHistoryEntry is one change of meta-data, stored append-only next to the meta-data file
+type JsonHistoryEntry struct {
+	Time     time.Time
+	User     string
+	Action   string          //One of the History* constants
+	Field    string          `json:",omitempty"` //Changed field, see EditableFields
+	OldValue json.RawMessage `json:",omitempty"`
+	NewValue json.RawMessage `json:",omitempty"` //For origins and comments: the added or changed JsonAssetOrigin/JsonComment
+}
+
+const (
+	HistoryFileExtension = ".history.jsonl"
+
+	HistoryCreated        = "created"
+	HistoryOriginAdded    = "origin-added"
+	HistoryOriginHidden   = "origin-hidden"
+	HistoryOriginShown    = "origin-shown"
+	HistoryOriginRemoved  = "origin-removed"
+	HistoryCommentAdded   = "comment-added"
+	HistoryCommentChanged = "comment-changed"
+	HistoryCommentDeleted = "comment-deleted"
+	HistoryChanged        = "changed"
+	HistoryDeleted        = "deleted"
+	HistoryRestored       = "restored"
+	HistoryRolledBack     = "rolled-back"
+
+	MaxRating = 5
+
+	// SystemUser is the user of history entries not changed by a user (data read from EXIF for example)
+	SystemUser = "system"
+)
+
+// editableField reads and writes one field of JsonAssetMetaData as JSON
+type editableField struct {
+	get func(m *JsonAssetMetaData) any
+	set func(m *JsonAssetMetaData, value json.RawMessage) error
+}
+
+var (
+	// EditableFields can be changed by UpdateMetaData and rolled back by RollbackField
+	EditableFields = map[string]editableField{
+		"Tags": {
+			func(m *JsonAssetMetaData) any { return m.Tags },
+			func(m *JsonAssetMetaData, value json.RawMessage) error {
+				var tags []string
+				err := util.UnmarshalOrZero(value, &tags)
+				if len(tags) == 0 {
+					tags = nil
+				}
+				m.Tags = tags
+				return err
+			}},
+		"Rating": {
+			func(m *JsonAssetMetaData) any { return m.Rating },
+			func(m *JsonAssetMetaData, value json.RawMessage) error {
+				var rating int
+				if err := util.UnmarshalOrZero(value, &rating); err != nil {
+					return err
+				}
+				if rating < 0 || rating > MaxRating {
+					return fmt.Errorf("rating %d out of range 0-%d", rating, MaxRating)
+				}
+				m.Rating = rating
+				return nil
+			}},
+		"Name": {
+			func(m *JsonAssetMetaData) any { return m.Name },
+			func(m *JsonAssetMetaData, value json.RawMessage) error {
+				var name string
+				if err := util.UnmarshalOrZero(value, &name); err != nil {
+					return err
+				}
+				name = strings.TrimSpace(name)
+				if strings.ContainsAny(name, "/\\") {
+					return fmt.Errorf("name must not contain a path")
+				}
+				m.Name = name
+				return nil
+			}},
+		"Title": {
+			func(m *JsonAssetMetaData) any { return m.Title },
+			func(m *JsonAssetMetaData, value json.RawMessage) error {
+				var title string
+				err := util.UnmarshalOrZero(value, &title)
+				m.Title = strings.TrimSpace(title)
+				return err
+			}},
+		"Description": {
+			func(m *JsonAssetMetaData) any { return m.Description },
+			func(m *JsonAssetMetaData, value json.RawMessage) error {
+				var description string
+				err := util.UnmarshalOrZero(value, &description)
+				m.Description = description
+				return err
+			}},
+		"CaptureTime": {
+			func(m *JsonAssetMetaData) any { return m.CaptureTime },
+			func(m *JsonAssetMetaData, value json.RawMessage) error {
+				var captureTime time.Time
+				err := util.UnmarshalOrZero(value, &captureTime)
+				m.CaptureTime = captureTime
+				return err
+			}},
+		"Location": {
+			func(m *JsonAssetMetaData) any { return m.Location },
+			func(m *JsonAssetMetaData, value json.RawMessage) error {
+				var location *JsonLocation
+				if err := util.UnmarshalOrZero(value, &location); err != nil {
+					return err
+				}
+				if location != nil && (math.Abs(location.Latitude) > 90 || math.Abs(location.Longitude) > 180) {
+					return fmt.Errorf("location %f,%f out of range", location.Latitude, location.Longitude)
+				}
+				m.Location = location
+				return nil
+			}},
+		"DerivedFrom": {
+			func(m *JsonAssetMetaData) any { return GetDerivedFrom(m) },
+			func(m *JsonAssetMetaData, value json.RawMessage) error {
+				var hash string
+				if err := util.UnmarshalOrZero(value, &hash); err != nil {
+					return err
+				}
+				return m.setDerivedFrom(hash)
+			}},
+		"Deleted": {
+			func(m *JsonAssetMetaData) any { return m.Deleted },
+			func(m *JsonAssetMetaData, value json.RawMessage) error {
+				var deleted bool
+				err := util.UnmarshalOrZero(value, &deleted)
+				m.Deleted = deleted
+				return err
+			}},
+	}
+
+	// systemFields are calculated from the content and can be changed by SystemUser only
+	systemFields = map[string]editableField{
+		"MimeType": {
+			func(m *JsonAssetMetaData) any { return m.MimeType },
+			func(m *JsonAssetMetaData, value json.RawMessage) error {
+				var mimeType string
+				if err := util.UnmarshalOrZero(value, &mimeType); err != nil {
+					return err
+				}
+				if mimeType == "" {
+					return fmt.Errorf("mime-type must not be empty")
+				}
+				m.MimeType = mimeType
+				return nil
+			}},
+		"Digests": {
+			func(m *JsonAssetMetaData) any { return m.Digests },
+			func(m *JsonAssetMetaData, value json.RawMessage) error {
+				var digests map[string]string
+				err := util.UnmarshalOrZero(value, &digests)
+				m.Digests = digests
+				return err
+			}},
+		"ImageHash": {
+			func(m *JsonAssetMetaData) any { return m.ImageHash },
+			func(m *JsonAssetMetaData, value json.RawMessage) error {
+				var imageHash string
+				err := util.UnmarshalOrZero(value, &imageHash)
+				m.ImageHash = imageHash
+				return err
+			}},
+	}
+
+	ErrUnknownField = errors.New("unknown or read-only field")
+
+	historyLock sync.Mutex
+
+	// updateLock serializes load-change-save of meta-data files.
+	// Works within one process only: apps running at the same time (cmd/add and rest-server for example)
+	// can still overwrite changes of each other.
+	updateLock sync.Mutex
+)
+
+// UpdateMetaData changes editable fields (field name -> new value as JSON), saves meta-data
+// and appends one history entry per changed field.
+func UpdateMetaData(hash string, changes map[string]json.RawMessage, user string) (*JsonAssetMetaData, error) {
+	return UpdateMetaDataWith(hash, func(*JsonAssetMetaData) map[string]json.RawMessage { return changes }, user)
+}
+
+// UpdateMetaDataWith is UpdateMetaData, changes are created from the current meta-data
+// (while no other change is possible, to set fields only if empty for example)
+func UpdateMetaDataWith(hash string, getChanges func(meta *JsonAssetMetaData) map[string]json.RawMessage, user string) (*JsonAssetMetaData, error) {
+
+	updateLock.Lock()
+	defer updateLock.Unlock()
+
+	meta, err := LoadByHash(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := getChanges(meta)
+	entries := make([]JsonHistoryEntry, 0, len(changes))
+	for field, value := range changes {
+		entry, err := meta.setField(field, value, user)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", field, err)
+		}
+		if entry != nil {
+			entries = append(entries, *entry)
+		}
+	}
+
+	return meta, meta.saveWithHistory(entries...)
+}
+
+// RollbackField sets the field changed by a history entry (index as returned by LoadHistory)
+// back to its value before this change.
+func RollbackField(hash string, entryIndex int, user string) (*JsonAssetMetaData, error) {
+
+	//Lock before loading the history, entries might be appended by concurrent updates
+	updateLock.Lock()
+	defer updateLock.Unlock()
+
+	history, err := LoadHistory(hash)
+	if err != nil {
+		return nil, err
+	}
+	if entryIndex < 0 || entryIndex >= len(history) {
+		return nil, fmt.Errorf("history entry %d not found", entryIndex)
+	}
+	rollbackTo := history[entryIndex]
+	if rollbackTo.Field == "" {
+		return nil, fmt.Errorf("history entry %d (%s) cannot be rolled back", entryIndex, rollbackTo.Action)
+	}
+
+	meta, err := LoadByHash(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := meta.setField(rollbackTo.Field, rollbackTo.OldValue, user)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return meta, nil
+	}
+	entry.Action = HistoryRolledBack
+	return meta, meta.saveWithHistory(*entry)
+}
+
+// setField changes a field, returns the history entry or nil if the value did not change
+func (assetMetaData *JsonAssetMetaData) setField(field string, value json.RawMessage, user string) (*JsonHistoryEntry, error) {
+
+	editable, ok := EditableFields[field]
+	if !ok && user == SystemUser {
+		editable, ok = systemFields[field]
+	}
+	if !ok {
+		return nil, ErrUnknownField
+	}
+
+	oldValue, err := json.Marshal(editable.get(assetMetaData))
+	if err != nil {
+		return nil, err
+	}
+	if err = editable.set(assetMetaData, value); err != nil {
+		return nil, err
+	}
+	newValue, err := json.Marshal(editable.get(assetMetaData))
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(oldValue, newValue) {
+		return nil, nil
+	}
+
+	entry := &JsonHistoryEntry{
+		Time:     time.Now(),
+		User:     user,
+		Action:   HistoryChanged,
+		Field:    field,
+		OldValue: oldValue,
+		NewValue: newValue,
+	}
+	if field == "Deleted" {
+		if assetMetaData.Deleted {
+			entry.Action = HistoryDeleted
+		} else {
+			entry.Action = HistoryRestored
+		}
+	}
+	return entry, nil
+}
+
+// saveWithHistory saves meta-data, then appends entries to history
+func (assetMetaData *JsonAssetMetaData) saveWithHistory(entries ...JsonHistoryEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	if err := assetMetaData.Save(GetMetaDataFilePath(assetMetaData.Hash)); err != nil {
+		return err
+	}
+	return AppendHistory(assetMetaData.Hash, entries...)
+}
+
+// AppendHistory adds entries to the history file of an asset
+func AppendHistory(assetHash string, entries ...JsonHistoryEntry) error {
+
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	historyLock.Lock()
+	defer historyLock.Unlock()
+
+	path := GetHistoryFilePath(assetHash)
+	util.PanicOnError(os.MkdirAll(filepath.Dir(path), FilePermissions), "Failed to create destination directory")
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, FilePermissions)
+	if err != nil {
+		return err
+	}
+	defer util.CloseOrLog(file)
+
+	_, err = file.Write(buf.Bytes())
+	return err
+}
+
+// LoadHistory returns all history entries of an asset, oldest first. Empty if there is no history.
+func LoadHistory(assetHash string) ([]JsonHistoryEntry, error) {
+
+	history := make([]JsonHistoryEntry, 0)
+
+	file, err := os.Open(GetHistoryFilePath(assetHash))
+	if errors.Is(err, os.ErrNotExist) {
+		return history, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer util.CloseOrLog(file)
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var entry JsonHistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("invalid history entry %d: %w", len(history), err)
+		}
+		history = append(history, entry)
+	}
+	return history, scanner.Err()
+}
+
+// GetHistoryFilePath returns the path and filename of a history file.
+func GetHistoryFilePath(assetHash string) string {
+	name := fmt.Sprintf("%s%s", assetHash[2:], HistoryFileExtension)
+	path := filepath.Join(
+		config.AssetMetaDataBaseDir,
+		assetHash[:2],
+		name)
+	return path
+}
+
+// originHistoryEntry creates an entry for a new origin
+func originHistoryEntry(action string, origin JsonAssetOrigin) JsonHistoryEntry {
+	value, _ := json.Marshal(origin)
+	return JsonHistoryEntry{
+		Time:     time.Now(),
+		User:     origin.Owner,
+		Action:   action,
+		NewValue: value,
+	}
+}
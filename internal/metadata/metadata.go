@@ -15,9 +15,29 @@ import (
 
 type (
 	JsonAssetMetaData struct {
-		Hash     string
-		MimeType string
-		Origins  []JsonAssetOrigin
+		SchemaVersion int //See CurrentSchemaVersion, older files are upgraded when loaded
+		Hash          string
+		MimeType      string
+		Size          int64
+		Digests       map[string]string `json:",omitempty"` //Algorithm -> hex digest (md5, sha1, blake3), see storage.DigestAlgorithms
+		CaptureTime   time.Time         `json:",omitzero"`  //From EXIF, zero if not available
+		ImageHash     string            `json:",omitempty"` //Perceptual hash of images (hex), see filter.DHash
+		Location      *JsonLocation     `json:",omitempty"` //GPS coordinates from EXIF, nil if not available
+		Origins       []JsonAssetOrigin
+		Name          string `json:",omitempty"` //Preferred name, see GetDisplayName
+		Title         string `json:",omitempty"`
+		Description   string
+		Tags          []string
+		Rating        int            `json:",omitempty"` //0-MaxRating
+		Deleted       bool           `json:",omitempty"` //Moved to trash, hidden in lists
+		Relations     []JsonRelation `json:",omitempty"` //Other assets this one was unpacked or derived from
+		Comments      []JsonComment  `json:",omitempty"` //See AddComment
+		NextCommentId int            `json:",omitempty"` //Ids of deleted comments are not reused
+	}
+
+	JsonLocation struct {
+		Latitude  float64 //Degrees, negative is south
+		Longitude float64 //Degrees, negative is west
 	}
 
 	JsonAssetOrigin struct {
@@ -25,11 +45,26 @@ type (
 		Path     string
 		Owner    string
 		FileTime time.Time
+		From     string `json:",omitempty"` //Sender of messages
+		Subject  string `json:",omitempty"` //Subject of messages
+		Hidden   bool   `json:",omitempty"` //Not used as name, not listed in paths, see SetOriginHidden
+	}
+
+	JsonRelation struct {
+		Type string //See Relation* constants
+		Hash string //Related asset
 	}
 )
 
 const (
-	FilePermissions = 0744
+	RelationPartOf       = storage.RelationPartOf       //File of an archive, message of a mailbox
+	RelationAttachmentOf = storage.RelationAttachmentOf //Attachment of a message
+)
+
+const (
+	FilePermissions       = 0744
+	MetaDataFileExtension = ".json"
+	TextFileExtension     = ".txt"
 )
 
 // Init creates required directories
@@ -38,39 +73,85 @@ func Init() {
 }
 
 // AddMetaData creates or updates meta-data JSON file
-func AddMetaData(hash string, mimeType string, name string, path string, owner string, fileTime time.Time) (*JsonAssetMetaData, error) {
+func AddMetaData(hash string, mimeType string, size int64, name string, path string, owner string, fileTime time.Time) (*JsonAssetMetaData, error) {
+	return addMetaData(hash, mimeType, size, nil, JsonAssetOrigin{Name: name, Path: path, Owner: owner, FileTime: fileTime}, nil)
+}
+
+// AddFileMetaData creates or updates meta-data JSON file of a file added by storage.AddFile.
+// Digests, date, sender and subject of messages and the relation of unpacked files are taken from info.
+func AddFileMetaData(info *storage.AddedFileInfo, name string, path string, owner string, fileTime time.Time) (*JsonAssetMetaData, error) {
+
+	origin := JsonAssetOrigin{Name: name, Path: path, Owner: owner, FileTime: fileTime, From: info.From, Subject: info.Subject}
+	if !info.FileTime.IsZero() {
+		origin.FileTime = info.FileTime
+	}
+
+	var relation *JsonRelation
+	if info.Parent != "" {
+		relation = &JsonRelation{Type: info.Relation, Hash: info.Parent}
+	}
+
+	return addMetaData(info.Hash, info.MimeType, info.Size, info.Digests, origin, relation)
+}
+
+// addMetaData creates or updates meta-data JSON file, adds origin and relation (if not nil) if not exists.
+// Digests are set if not available yet (meta-data created before digests were calculated).
+func addMetaData(hash string, mimeType string, size int64, digests map[string]string, origin JsonAssetOrigin, relation *JsonRelation) (*JsonAssetMetaData, error) {
 
 	metaDataFile := GetMetaDataFilePath(hash)
 
+	updateLock.Lock()
+	defer updateLock.Unlock()
+
+	var history []JsonHistoryEntry
+
 	metaData, err := LoadIfExists(metaDataFile)
 	if errors.Is(err, os.ErrNotExist) {
 		metaData = CreateNew(
 			hash,
 			mimeType,
-			name,
-			path,
-			owner,
-			fileTime)
+			size,
+			origin.Name,
+			origin.Path,
+			origin.Owner,
+			origin.FileTime)
+		metaData.Origins[0] = origin
+		history = append(history, originHistoryEntry(HistoryCreated, metaData.Origins[0]))
 	} else if err != nil {
 		return nil, err
 	} else {
-		metaData.AddOrigin(
-			name,
-			path,
-			owner,
-			fileTime)
+		if metaData.Size == 0 {
+			metaData.Size = size
+		}
+		if metaData.addOrigin(origin) {
+			history = append(history, originHistoryEntry(HistoryOriginAdded, metaData.Origins[len(metaData.Origins)-1]))
+		}
+	}
+	if relation != nil {
+		metaData.AddRelation(relation.Type, relation.Hash)
+	}
+	if len(metaData.Digests) == 0 && len(digests) > 0 {
+		metaData.Digests = digests
 	}
 
 	//fmt.Printf("MetaData: %s\n", metaData)
-	return metaData, metaData.Save(metaDataFile)
+	if err = metaData.Save(metaDataFile); err != nil {
+		return metaData, err
+	}
+	if len(history) > 0 {
+		err = AppendHistory(hash, history...)
+	}
+	return metaData, err
 
 }
 
 // CreateNew Create new JsonAssetMetaData struct, filled with given data
-func CreateNew(hash string, mimeType string, name string, path string, owner string, fileTime time.Time) *JsonAssetMetaData {
+func CreateNew(hash string, mimeType string, size int64, name string, path string, owner string, fileTime time.Time) *JsonAssetMetaData {
 	assetMetadata := &JsonAssetMetaData{
-		Hash:     hash,
-		MimeType: mimeType,
+		SchemaVersion: CurrentSchemaVersion,
+		Hash:          hash,
+		MimeType:      mimeType,
+		Size:          size,
 		Origins: []JsonAssetOrigin{
 			{
 				Name:     name,
@@ -84,19 +165,9 @@ func CreateNew(hash string, mimeType string, name string, path string, owner str
 	return assetMetadata
 }
 
-// AddOrigin Add origin data if not exists
-func (assetMetaData *JsonAssetMetaData) AddOrigin(name string, path string, owner string, time time.Time) {
-
-	for _, origin := range assetMetaData.Origins {
-		if origin.Name == name &&
-			origin.Path == path &&
-			origin.Owner == owner &&
-			origin.FileTime == time {
-			return
-		}
-	}
-
-	assetMetaData.Origins = append(assetMetaData.Origins, JsonAssetOrigin{
+// AddOrigin Add origin data if not exists, returns true if added
+func (assetMetaData *JsonAssetMetaData) AddOrigin(name string, path string, owner string, time time.Time) bool {
+	return assetMetaData.addOrigin(JsonAssetOrigin{
 		Name:     name,
 		Path:     path,
 		Owner:    owner,
@@ -104,10 +175,41 @@ func (assetMetaData *JsonAssetMetaData) AddOrigin(name string, path string, owne
 	})
 }
 
-// GetLatestOrigin finds the newest origin within given meta-data
+func (assetMetaData *JsonAssetMetaData) addOrigin(newOrigin JsonAssetOrigin) bool {
+
+	for _, origin := range assetMetaData.Origins {
+		if origin.Name == newOrigin.Name &&
+			origin.Path == newOrigin.Path &&
+			origin.Owner == newOrigin.Owner &&
+			origin.FileTime.Equal(newOrigin.FileTime) {
+			return false
+		}
+	}
+
+	assetMetaData.Origins = append(assetMetaData.Origins, newOrigin)
+	return true
+}
+
+// AddRelation adds a relation to another asset if not exists, returns true if added
+func (assetMetaData *JsonAssetMetaData) AddRelation(relationType string, hash string) bool {
+
+	for _, relation := range assetMetaData.Relations {
+		if relation.Type == relationType && relation.Hash == hash {
+			return false
+		}
+	}
+
+	assetMetaData.Relations = append(assetMetaData.Relations, JsonRelation{Type: relationType, Hash: hash})
+	return true
+}
+
+// GetLatestOrigin finds the newest origin within given meta-data, hidden origins are ignored
 func GetLatestOrigin(assetMetaData *JsonAssetMetaData) *JsonAssetOrigin {
 	var latest *JsonAssetOrigin = nil
 	for _, origin := range assetMetaData.Origins {
+		if origin.Hidden {
+			continue
+		}
 		if latest == nil || latest.FileTime.Before(origin.FileTime) {
 			latest = &origin
 		}
@@ -115,11 +217,23 @@ func GetLatestOrigin(assetMetaData *JsonAssetMetaData) *JsonAssetOrigin {
 	return latest
 }
 
+// GetDisplayName returns the preferred name, name of the latest origin if not set
+func GetDisplayName(assetMetaData *JsonAssetMetaData) string {
+	if assetMetaData.Name != "" {
+		return assetMetaData.Name
+	}
+	if latest := GetLatestOrigin(assetMetaData); latest != nil {
+		return latest.Name
+	}
+	return ""
+}
+
 // Save Create dir if not exists and save JSON
 func (assetMetaData *JsonAssetMetaData) Save(path string) error {
 
 	util.PanicOnError(os.MkdirAll(filepath.Dir(path), FilePermissions), "Failed to create destination directory")
 
+	assetMetaData.SchemaVersion = CurrentSchemaVersion
 	jsonBytes, err := json.Marshal(assetMetaData)
 	if err != nil {
 		return err
@@ -128,7 +242,7 @@ func (assetMetaData *JsonAssetMetaData) Save(path string) error {
 	return os.WriteFile(path, jsonBytes, FilePermissions)
 }
 
-// LoadIfExists Load JSON-file, if exists.
+// LoadIfExists Load JSON-file, if exists. Older versions are upgraded (not saved).
 func LoadIfExists(path string) (*JsonAssetMetaData, error) {
 
 	buf, err := os.ReadFile(path)
@@ -136,14 +250,15 @@ func LoadIfExists(path string) (*JsonAssetMetaData, error) {
 		return nil, err
 	}
 
-	var assetMetadata = &JsonAssetMetaData{}
-	err = json.Unmarshal(buf, assetMetadata)
+	buf, _, err = util.UpgradeJson(buf, path, upgrades)
 	if err != nil {
 		return nil, err
 	}
 
-	if assetMetadata.Hash == "" {
-		assetMetadata.Hash = storage.HashFromStoragePath(path)
+	var assetMetadata = &JsonAssetMetaData{}
+	err = json.Unmarshal(buf, assetMetadata)
+	if err != nil {
+		return nil, err
 	}
 	return assetMetadata, err
 }
@@ -155,9 +270,41 @@ func LoadByHash(assetHash string) (*JsonAssetMetaData, error) {
 	return meta, err
 }
 
+// SaveText writes extracted text content of an asset next to its meta-data file.
+func SaveText(assetHash string, text string) error {
+	path := GetTextFilePath(assetHash)
+	util.PanicOnError(os.MkdirAll(filepath.Dir(path), FilePermissions), "Failed to create destination directory")
+	return os.WriteFile(path, []byte(text), FilePermissions)
+}
+
+// LoadText returns previously extracted text content of an asset.
+func LoadText(assetHash string) (string, error) {
+	buf, err := os.ReadFile(GetTextFilePath(assetHash))
+	if err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// TextExists checks if text content was extracted before.
+func TextExists(assetHash string) bool {
+	_, err := os.Stat(GetTextFilePath(assetHash))
+	return err == nil
+}
+
+// GetTextFilePath returns the path and filename of a extracted text file.
+func GetTextFilePath(assetHash string) string {
+	name := fmt.Sprintf("%s%s", assetHash[2:], TextFileExtension)
+	path := filepath.Join(
+		config.AssetMetaDataBaseDir,
+		assetHash[:2],
+		name)
+	return path
+}
+
 // GetMetaDataFilePath returns the path and filename of a meta-data file.
 func GetMetaDataFilePath(assetHash string) string {
-	name := fmt.Sprintf("%s%s", assetHash[2:], ".json")
+	name := fmt.Sprintf("%s%s", assetHash[2:], MetaDataFileExtension)
 	path := filepath.Join(
 		config.AssetMetaDataBaseDir,
 		assetHash[:2],
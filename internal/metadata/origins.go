@@ -0,0 +1,91 @@
+package metadata
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrOriginNotFound = errors.New("origin not found")
+	ErrLastOrigin     = errors.New("the last visible origin cannot be hidden or removed")
+)
+
+// SetOriginHidden hides an origin (index in Origins) or shows it again. Hidden origins are kept,
+// but not used as name (see GetLatestOrigin) and not listed in paths.
+func SetOriginHidden(hash string, index int, hidden bool, user string) (*JsonAssetMetaData, error) {
+	return changeOrigin(hash, index, user, func(meta *JsonAssetMetaData) (string, error) {
+		if meta.Origins[index].Hidden == hidden {
+			return "", nil
+		}
+		if hidden && !meta.hasOtherVisibleOrigin(index) {
+			return "", ErrLastOrigin
+		}
+		meta.Origins[index].Hidden = hidden
+		if hidden {
+			return HistoryOriginHidden, nil
+		}
+		return HistoryOriginShown, nil
+	})
+}
+
+// RemoveOrigin removes an origin (index in Origins)
+func RemoveOrigin(hash string, index int, user string) (*JsonAssetMetaData, error) {
+	return changeOrigin(hash, index, user, func(meta *JsonAssetMetaData) (string, error) {
+		if !meta.Origins[index].Hidden && !meta.hasOtherVisibleOrigin(index) {
+			return "", ErrLastOrigin
+		}
+		meta.Origins = append(meta.Origins[:index], meta.Origins[index+1:]...)
+		return HistoryOriginRemoved, nil
+	})
+}
+
+// changeOrigin loads meta-data, calls change and saves with a history entry containing the origin
+// before and after the change. change returns the history action, empty if nothing changed.
+func changeOrigin(hash string, index int, user string, change func(meta *JsonAssetMetaData) (string, error)) (*JsonAssetMetaData, error) {
+
+	updateLock.Lock()
+	defer updateLock.Unlock()
+
+	meta, err := LoadByHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 || index >= len(meta.Origins) {
+		return nil, fmt.Errorf("%w: %d", ErrOriginNotFound, index)
+	}
+
+	oldValue, err := json.Marshal(meta.Origins[index])
+	if err != nil {
+		return nil, err
+	}
+
+	action, err := change(meta)
+	if err != nil || action == "" {
+		return meta, err
+	}
+
+	entry := JsonHistoryEntry{
+		Time:     time.Now(),
+		User:     user,
+		Action:   action,
+		OldValue: oldValue,
+	}
+	if action != HistoryOriginRemoved {
+		if entry.NewValue, err = json.Marshal(meta.Origins[index]); err != nil {
+			return nil, err
+		}
+	}
+	return meta, meta.saveWithHistory(entry)
+}
+
+// hasOtherVisibleOrigin checks if there is a visible origin except the one at index
+func (assetMetaData *JsonAssetMetaData) hasOtherVisibleOrigin(index int) bool {
+	for i, origin := range assetMetaData.Origins {
+		if i != index && !origin.Hidden {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package metadata
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/c8121/asset-storage/internal/storage"
+	"github.com/c8121/asset-storage/internal/util"
+)
+
+// CurrentSchemaVersion is written to all meta-data files, must be len(upgrades)
+const CurrentSchemaVersion = 1
+
+// upgrades[i] converts meta-data JSON from version i to i+1, see util.UpgradeJson.
+// Never change existing upgrades, append a new one when changing JsonAssetMetaData incompatibly.
+var upgrades = []util.JsonUpgrade{
+	//1: Hash is required (was taken from file path if missing)
+	storage.UpgradeHashFromStoragePath,
+}
+
+// Upgrade reads a meta-data file and converts it to CurrentSchemaVersion.
+// Returns the JSON to save, nil if the file is up to date.
+func Upgrade(path string) ([]byte, error) {
+
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	buf, upgraded, err := util.UpgradeJson(buf, path, upgrades)
+	if err != nil || !upgraded {
+		return nil, err
+	}
+
+	//Make sure the result is valid, write it the way Save does
+	var assetMetadata = &JsonAssetMetaData{}
+	if err = json.Unmarshal(buf, assetMetadata); err != nil {
+		return nil, err
+	}
+	return json.Marshal(assetMetadata)
+}
@@ -0,0 +1,74 @@
+package metadata
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/c8121/asset-storage/internal/storage"
+)
+
+const (
+	RelationDerivedFrom = "derived-from" //Edited version of another asset, see SetDerivedFrom
+
+	MaxVersionDepth = 100 //Limit when following DerivedFrom
+)
+
+// GetDerivedFrom returns the hash of the asset this one is a version of, empty if none
+func GetDerivedFrom(assetMetaData *JsonAssetMetaData) string {
+	for _, relation := range assetMetaData.Relations {
+		if relation.Type == RelationDerivedFrom {
+			return relation.Hash
+		}
+	}
+	return ""
+}
+
+// setDerivedFrom replaces the derived-from relation (removes it if hash is empty).
+// The original must exist and must not be a version of this asset.
+func (assetMetaData *JsonAssetMetaData) setDerivedFrom(hash string) error {
+
+	hash = strings.ToLower(strings.TrimSpace(hash))
+	if hash != "" {
+		if !storage.IsValidHash(hash) {
+			return fmt.Errorf("invalid hash '%s'", hash)
+		}
+		if err := checkDerivedFrom(assetMetaData.Hash, hash); err != nil {
+			return err
+		}
+	}
+
+	relations := make([]JsonRelation, 0, len(assetMetaData.Relations))
+	for _, relation := range assetMetaData.Relations {
+		if relation.Type != RelationDerivedFrom {
+			relations = append(relations, relation)
+		}
+	}
+	if hash != "" {
+		relations = append(relations, JsonRelation{Type: RelationDerivedFrom, Hash: hash})
+	}
+	if len(relations) == 0 {
+		relations = nil
+	}
+	assetMetaData.Relations = relations
+	return nil
+}
+
+// checkDerivedFrom follows the lineage of original to make sure hash is not one of its ancestors
+func checkDerivedFrom(hash string, original string) error {
+
+	for depth := 0; original != ""; depth++ {
+		if original == hash {
+			return fmt.Errorf("asset cannot be a version of itself or of its own versions")
+		}
+		if depth >= MaxVersionDepth {
+			return fmt.Errorf("too many versions (max. %d)", MaxVersionDepth)
+		}
+		meta, err := LoadByHash(original)
+		if err != nil {
+			//Not wrapped: a missing original is an invalid value, not a missing asset
+			return fmt.Errorf("original asset %s: %s", original, err)
+		}
+		original = GetDerivedFrom(meta)
+	}
+	return nil
+}
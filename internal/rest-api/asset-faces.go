@@ -2,6 +2,7 @@ package restapi
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -11,11 +12,12 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+const MaxSimilarFacesCount = 1000
+
 func GetFaceImage(c *gin.Context) {
 
-	hash := c.Param("hash")
-	if len(hash) < 32 {
-		util.LogError(c.AbortWithError(http.StatusNotFound, fmt.Errorf("invalid hash")))
+	hash, ok := hashParam(c)
+	if !ok {
 		return
 	}
 
@@ -36,9 +38,8 @@ func GetFaceImage(c *gin.Context) {
 
 func GetFaces(c *gin.Context) {
 
-	hash := c.Param("hash")
-	if len(hash) < 32 {
-		util.LogError(c.AbortWithError(http.StatusNotFound, fmt.Errorf("invalid hash")))
+	hash, ok := hashParam(c)
+	if !ok {
 		return
 	}
 
@@ -56,3 +57,36 @@ func GetFaces(c *gin.Context) {
 
 	c.Data(http.StatusOK, "application/json", json)
 }
+
+// GetSimilarFaces is a rest-api handler to send faces similar to a face (see faces.FindSimilarFaces), most similar first.
+// Optional query parameter count (default faces.SimilarFacesCount).
+func GetSimilarFaces(c *gin.Context) {
+
+	hash, ok := hashParam(c)
+	if !ok {
+		return
+	}
+
+	idx, err := strconv.Atoi(c.Param("idx"))
+	if err != nil {
+		util.LogError(c.AbortWithError(http.StatusNotFound, fmt.Errorf("invalid index")))
+		return
+	}
+
+	count, err := strconv.Atoi(c.DefaultQuery("count", strconv.Itoa(faces.SimilarFacesCount)))
+	if err != nil || count < 1 || count > MaxSimilarFacesCount {
+		util.LogError(c.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid count (1-%d)", MaxSimilarFacesCount)))
+		return
+	}
+
+	matches, err := faces.FindSimilarFaces(hash+"/"+strconv.Itoa(idx), count, faces.SimilarFacesThreshold)
+	if errors.Is(err, faces.ErrNoFaceIndex) || errors.Is(err, faces.ErrFaceNotFound) {
+		util.LogError(c.AbortWithError(http.StatusNotFound, err))
+		return
+	} else if err != nil {
+		util.LogError(c.AbortWithError(http.StatusInternalServerError, err))
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, matches)
+}
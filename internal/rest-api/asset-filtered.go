@@ -16,9 +16,8 @@ import (
 // GetFiltered is a rest-api handler to filter/convert an asset
 func GetFiltered(c *gin.Context) {
 
-	hash := c.Param("hash")
-	if len(hash) < 32 {
-		util.LogError(c.AbortWithError(http.StatusNotFound, fmt.Errorf("invalid hash")))
+	hash, ok := hashParam(c)
+	if !ok {
 		return
 	}
 
@@ -18,9 +18,8 @@ var (
 // GetPreview is a rest-api handler to generate a preview image
 func GetPreview(c *gin.Context) {
 
-	hash := c.Param("hash")
-	if len(hash) < 32 {
-		util.LogError(c.AbortWithError(http.StatusNotFound, fmt.Errorf("invalid hash")))
+	hash, ok := hashParam(c)
+	if !ok {
 		return
 	}
 
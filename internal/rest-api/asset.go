@@ -1,6 +1,7 @@
 package restapi
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -16,12 +17,21 @@ var (
 	DefaultListItemCount = 30
 )
 
+// hashParam returns the :hash parameter, aborts with 404 if it is not a valid hash (see storage.IsValidHash)
+func hashParam(c *gin.Context) (string, bool) {
+	hash := c.Param("hash")
+	if !storage.IsValidHash(hash) {
+		util.LogError(c.AbortWithError(http.StatusNotFound, fmt.Errorf("invalid hash")))
+		return "", false
+	}
+	return hash, true
+}
+
 // GetAsset is a rest-api handler to send the asset content
 func GetAsset(c *gin.Context) {
 
-	hash := c.Param("hash")
-	if len(hash) < 32 {
-		util.LogError(c.AbortWithError(http.StatusNotFound, fmt.Errorf("invalid hash")))
+	hash, ok := hashParam(c)
+	if !ok {
 		return
 	}
 
@@ -77,16 +87,46 @@ func ListAssets(c *gin.Context) {
 	}
 	//fmt.Printf("Filter: %v\n", listFilter)
 
-	items, err := metadata_db.ListAssets(listFilter)
-	if err != nil {
-		util.LogError(c.AbortWithError(http.StatusInternalServerError, err))
+	list, err := metadata_db.ListAssets(listFilter)
+	if abortOnListError(c, err) {
 		return
 	}
 
-	if len(items) > 0 {
-		c.IndentedJSON(http.StatusOK, items)
-	} else {
-		//https://github.com/gin-gonic/gin/issues/125 ?
-		c.Data(http.StatusOK, "application/json", []byte("[]"))
+	c.IndentedJSON(http.StatusOK, list)
+}
+
+// ListFacets is a rest-api handler to send counts of assets matching a filter, grouped by mime-type, owner, etc.
+func ListFacets(c *gin.Context) {
+
+	var listFilter *metadata_db.AssetListFilter = nil
+	err := c.ShouldBind(&listFilter)
+	if err != nil || listFilter == nil {
+		util.LogError(fmt.Errorf("failed to parse request: %w", err))
+		listFilter = &metadata_db.AssetListFilter{}
+	}
+
+	facets, err := metadata_db.ListFacets(listFilter)
+	if abortOnListError(c, err) {
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, facets)
+}
+
+// abortOnListError sends 400 for invalid queries or filters, 500 for other errors. Returns true if err is not nil.
+func abortOnListError(c *gin.Context, err error) bool {
+
+	var queryErr *metadata_db.QueryError
+	var filterErr *metadata_db.FilterError
+	if errors.As(err, &queryErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": queryErr.Error(), "position": queryErr.Position})
+		return true
+	} else if errors.As(err, &filterErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": filterErr.Error(), "field": filterErr.Field})
+		return true
+	} else if err != nil {
+		util.LogError(c.AbortWithError(http.StatusInternalServerError, err))
+		return true
 	}
+	return false
 }
@@ -22,9 +22,8 @@ type (
 // GetCollection is a rest-api handler to send the collection content
 func GetCollection(c *gin.Context) {
 
-	hash := c.Param("hash")
-	if len(hash) < 32 {
-		util.LogError(c.AbortWithError(http.StatusNotFound, fmt.Errorf("invalid hash")))
+	hash, ok := hashParam(c)
+	if !ok {
 		return
 	}
 
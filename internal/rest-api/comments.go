@@ -0,0 +1,114 @@
+package restapi
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/c8121/asset-storage/internal/metadata"
+	metadata_db_entity "github.com/c8121/asset-storage/internal/metadata-db-entity"
+	"github.com/c8121/asset-storage/internal/users"
+	"github.com/c8121/asset-storage/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+type CommentRequest struct {
+	Parent int //Id of the comment to reply to, 0 for a new thread (ignored when changing a comment)
+	Text   string
+}
+
+// ListComments is a rest-api handler to send all comments of an asset, oldest first.
+// Replies refer to their parent comment (Parent).
+func ListComments(c *gin.Context) {
+
+	hash, ok := hashParam(c)
+	if !ok {
+		return
+	}
+
+	meta, err := metadata.LoadByHash(hash)
+	if abortOnUpdateError(c, err) {
+		return
+	}
+
+	comments := meta.Comments
+	if comments == nil {
+		comments = make([]metadata.JsonComment, 0)
+	}
+	c.IndentedJSON(http.StatusOK, comments)
+}
+
+// AddComment is a rest-api handler to add a comment or reply. Expects CommentRequest as JSON.
+func AddComment(c *gin.Context) {
+
+	hash, ok := hashParam(c)
+	if !ok {
+		return
+	}
+
+	var req CommentRequest
+	if err := c.BindJSON(&req); err != nil {
+		return
+	}
+
+	meta, comment, err := metadata.AddComment(hash, req.Parent, req.Text, users.GetUsername(c))
+	if abortOnUpdateError(c, err) || abortOnDatabaseError(c, metadata_db_entity.AddMetaData(meta)) {
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, comment)
+}
+
+// UpdateComment is a rest-api handler to change the text of an own comment. Expects CommentRequest as JSON.
+func UpdateComment(c *gin.Context) {
+
+	hash, id, ok := commentParams(c)
+	if !ok {
+		return
+	}
+
+	var req CommentRequest
+	if err := c.BindJSON(&req); err != nil {
+		return
+	}
+
+	meta, comment, err := metadata.UpdateComment(hash, id, req.Text, users.GetUsername(c))
+	if abortOnUpdateError(c, err) || abortOnDatabaseError(c, metadata_db_entity.AddMetaData(meta)) {
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, comment)
+}
+
+// DeleteComment is a rest-api handler to delete an own comment
+func DeleteComment(c *gin.Context) {
+
+	hash, id, ok := commentParams(c)
+	if !ok {
+		return
+	}
+
+	meta, err := metadata.DeleteComment(hash, id, users.GetUsername(c))
+	if abortOnUpdateError(c, err) || abortOnDatabaseError(c, metadata_db_entity.AddMetaData(meta)) {
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// commentParams returns hash and comment id from url, sends 404 if invalid
+func commentParams(c *gin.Context) (string, int, bool) {
+
+	hash, ok := hashParam(c)
+	if !ok {
+		return "", 0, false
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.LogError(c.AbortWithError(http.StatusNotFound, fmt.Errorf("invalid comment id")))
+		return "", 0, false
+	}
+
+	return hash, id, true
+}
@@ -0,0 +1,26 @@
+package restapi
+
+import (
+	"fmt"
+	"net/http"
+
+	metadata_db "github.com/c8121/asset-storage/internal/metadata-db"
+	"github.com/c8121/asset-storage/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+// FindByDigest is a rest-api handler to send all assets having a content digest
+// (algorithm md5, sha1, sha256 or blake3, see storage.DigestAlgorithms). Sends 404 if none was found.
+func FindByDigest(c *gin.Context) {
+
+	list, err := metadata_db.FindByDigest(c.Param("algorithm"), c.Param("digest"))
+	if abortOnListError(c, err) {
+		return
+	}
+	if len(list) == 0 {
+		util.LogError(c.AbortWithError(http.StatusNotFound, fmt.Errorf("digest not found")))
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, list)
+}
@@ -0,0 +1,35 @@
+package restapi
+
+import (
+	"net/http"
+	"strconv"
+
+	metadata_db "github.com/c8121/asset-storage/internal/metadata-db"
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultZoom is used if ?zoom is not given
+const DefaultZoom = 2
+
+// GetGeoClusters is a rest-api handler to send located assets as GeoJSON points, clustered for a map at ?zoom=0-22.
+// Accepts AssetListFilter fields as query parameters (GET) or JSON (POST), use BoundingBox to limit to the visible area.
+func GetGeoClusters(c *gin.Context) {
+
+	listFilter := bindListFilter(c)
+
+	zoom := DefaultZoom
+	if s := c.Query("zoom"); s != "" {
+		var err error
+		if zoom, err = strconv.Atoi(s); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid zoom, expected 0-22", "field": "zoom"})
+			return
+		}
+	}
+
+	clusters, err := metadata_db.ListGeoClusters(listFilter, zoom)
+	if abortOnListError(c, err) {
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, clusters)
+}
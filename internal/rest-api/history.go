@@ -0,0 +1,113 @@
+package restapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/c8121/asset-storage/internal/faces"
+	"github.com/c8121/asset-storage/internal/metadata"
+	metadata_db_entity "github.com/c8121/asset-storage/internal/metadata-db-entity"
+	"github.com/c8121/asset-storage/internal/users"
+	"github.com/c8121/asset-storage/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+type RollbackRequest struct {
+	Entry int //Index of the history entry, see GetHistory
+}
+
+// GetHistory is a rest-api handler to send all changes of the asset meta-data, oldest first
+func GetHistory(c *gin.Context) {
+
+	hash, ok := hashParam(c)
+	if !ok {
+		return
+	}
+
+	history, err := metadata.LoadHistory(hash)
+	if err != nil {
+		util.LogError(c.AbortWithError(http.StatusInternalServerError, err))
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, history)
+}
+
+// UpdateMetaData is a rest-api handler to change editable fields of the asset meta-data.
+// Expects a JSON object of field name -> new value, see metadata.EditableFields.
+func UpdateMetaData(c *gin.Context) {
+
+	hash, ok := hashParam(c)
+	if !ok {
+		return
+	}
+
+	var changes map[string]json.RawMessage
+	if err := c.BindJSON(&changes); err != nil {
+		return
+	}
+
+	meta, err := metadata.UpdateMetaData(hash, changes, users.GetUsername(c))
+	if abortOnUpdateError(c, err) || abortOnDatabaseError(c, metadata_db_entity.AddMetaData(meta)) {
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, meta)
+}
+
+// RollbackMetaData is a rest-api handler to set a field back to the value it had before a change.
+// Expects RollbackRequest as JSON.
+func RollbackMetaData(c *gin.Context) {
+
+	hash, ok := hashParam(c)
+	if !ok {
+		return
+	}
+
+	var req RollbackRequest
+	if err := c.BindJSON(&req); err != nil {
+		return
+	}
+
+	meta, err := metadata.RollbackField(hash, req.Entry, users.GetUsername(c))
+	if abortOnUpdateError(c, err) || abortOnDatabaseError(c, metadata_db_entity.AddMetaData(meta)) {
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, meta)
+}
+
+// abortOnUpdateError sends 404 if the asset, origin, comment, person or face does not exist, 403 if the user is not allowed
+// to change it, 400 if the change was rejected. Returns true if aborted.
+func abortOnUpdateError(c *gin.Context, err error) bool {
+
+	if errors.Is(err, os.ErrNotExist) {
+		util.LogError(c.AbortWithError(http.StatusNotFound, fmt.Errorf("invalid hash (not found)")))
+		return true
+	} else if errors.Is(err, metadata.ErrOriginNotFound) || errors.Is(err, metadata.ErrCommentNotFound) ||
+		errors.Is(err, faces.ErrPersonNotFound) || errors.Is(err, faces.ErrFaceNotFound) {
+		util.LogError(c.AbortWithError(http.StatusNotFound, err))
+		return true
+	} else if errors.Is(err, metadata.ErrNotAuthor) {
+		util.LogError(c.AbortWithError(http.StatusForbidden, err))
+		return true
+	} else if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Abort()
+		return true
+	}
+	return false
+}
+
+// abortOnDatabaseError sends 500 if the changed meta-data could not be written to database.
+// Returns true if aborted.
+func abortOnDatabaseError(c *gin.Context, err error) bool {
+	if err != nil {
+		util.LogError(c.AbortWithError(http.StatusInternalServerError, err))
+		return true
+	}
+	return false
+}
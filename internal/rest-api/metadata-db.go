@@ -1,6 +1,8 @@
 package restapi
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 
 	metadata_db "github.com/c8121/asset-storage/internal/metadata-db"
@@ -9,6 +11,11 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+var (
+	DefaultPathItemCount = 1000
+	MaxPathItemCount     = 10000
+)
+
 func ListMimeTypes(c *gin.Context) {
 
 	items, err := metadata_db_entity.ListMimeTypes()
@@ -25,10 +32,19 @@ func ListMimeTypes(c *gin.Context) {
 	}
 }
 
-// ListPathItems is a rest-api handler
+// ListPathItems is a rest-api handler to send children of a path item,
+// including number and total size of assets below. Supports ?offset=...&count=...
 func ListPathItems(c *gin.Context) {
 
-	items, err := metadata_db.ListPathItems(util.Atoi(c.Param("parent"), 0))
+	parentId := int64(util.Atoi(c.Param("parent"), 0))
+	offset := util.Atoi(c.Query("offset"), 0)
+	count := util.Atoi(c.Query("count"), DefaultPathItemCount)
+	if count < 1 || count > MaxPathItemCount {
+		util.LogError(c.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid count (1-%d)", MaxPathItemCount)))
+		return
+	}
+
+	items, err := metadata_db.ListPathItems(parentId, offset, count)
 	if err != nil {
 		util.LogError(c.AbortWithError(http.StatusInternalServerError, err))
 		return
@@ -41,3 +57,41 @@ func ListPathItems(c *gin.Context) {
 		c.Data(http.StatusOK, "application/json", []byte("[]"))
 	}
 }
+
+// GetPathItemPath is a rest-api handler to send the full path (breadcrumbs) of a path item
+func GetPathItemPath(c *gin.Context) {
+
+	path, err := metadata_db.GetPathItemPath(int64(util.Atoi(c.Param("id"), 0)))
+	if errors.Is(err, metadata_db_entity.ErrNotFound) {
+		util.LogError(c.AbortWithError(http.StatusNotFound, fmt.Errorf("path item not found")))
+		return
+	} else if err != nil {
+		util.LogError(c.AbortWithError(http.StatusInternalServerError, err))
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, path)
+}
+
+// ListAssetPaths is a rest-api handler to send full paths of all origins of an asset
+func ListAssetPaths(c *gin.Context) {
+
+	hash, ok := hashParam(c)
+	if !ok {
+		return
+	}
+
+	assetId := metadata_db_entity.GetAssetId(hash)
+	if assetId == 0 {
+		util.LogError(c.AbortWithError(http.StatusNotFound, fmt.Errorf("invalid hash (not found)")))
+		return
+	}
+
+	paths, err := metadata_db.ListAssetPaths(assetId)
+	if err != nil {
+		util.LogError(c.AbortWithError(http.StatusInternalServerError, err))
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, paths)
+}
@@ -12,9 +12,8 @@ import (
 // GetMetaData is a rest-api handler to send the asset meta-data
 func GetMetaData(c *gin.Context) {
 
-	hash := c.Param("hash")
-	if len(hash) < 32 {
-		util.LogError(c.AbortWithError(http.StatusNotFound, fmt.Errorf("invalid hash")))
+	hash, ok := hashParam(c)
+	if !ok {
 		return
 	}
 
@@ -0,0 +1,88 @@
+package restapi
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/c8121/asset-storage/internal/metadata"
+	metadata_db "github.com/c8121/asset-storage/internal/metadata-db"
+	metadata_db_entity "github.com/c8121/asset-storage/internal/metadata-db-entity"
+	"github.com/c8121/asset-storage/internal/users"
+	"github.com/c8121/asset-storage/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+type OriginRequest struct {
+	Hidden bool
+}
+
+// ListOrigins is a rest-api handler to send all origins of an asset with full paths, including hidden origins
+func ListOrigins(c *gin.Context) {
+
+	hash, ok := hashParam(c)
+	if !ok {
+		return
+	}
+
+	meta, err := metadata.LoadByHash(hash)
+	if abortOnUpdateError(c, err) {
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, metadata_db.ListOrigins(meta))
+}
+
+// UpdateOrigin is a rest-api handler to hide an origin or show it again. Expects OriginRequest as JSON.
+func UpdateOrigin(c *gin.Context) {
+
+	hash, index, ok := originParams(c)
+	if !ok {
+		return
+	}
+
+	var req OriginRequest
+	if err := c.BindJSON(&req); err != nil {
+		return
+	}
+
+	meta, err := metadata.SetOriginHidden(hash, index, req.Hidden, users.GetUsername(c))
+	if abortOnUpdateError(c, err) || abortOnDatabaseError(c, metadata_db_entity.AddMetaData(meta)) {
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, meta)
+}
+
+// RemoveOrigin is a rest-api handler to remove an origin
+func RemoveOrigin(c *gin.Context) {
+
+	hash, index, ok := originParams(c)
+	if !ok {
+		return
+	}
+
+	meta, err := metadata.RemoveOrigin(hash, index, users.GetUsername(c))
+	if abortOnUpdateError(c, err) || abortOnDatabaseError(c, metadata_db_entity.AddMetaData(meta)) {
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, meta)
+}
+
+// originParams returns hash and origin index from url, sends 404 if invalid
+func originParams(c *gin.Context) (string, int, bool) {
+
+	hash, ok := hashParam(c)
+	if !ok {
+		return "", 0, false
+	}
+
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil {
+		util.LogError(c.AbortWithError(http.StatusNotFound, fmt.Errorf("invalid origin index")))
+		return "", 0, false
+	}
+
+	return hash, index, true
+}
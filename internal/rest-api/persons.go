@@ -0,0 +1,144 @@
+package restapi
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/c8121/asset-storage/internal/faces"
+	"github.com/c8121/asset-storage/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	// PersonListItem is a person without its faces
+	PersonListItem struct {
+		Id        int64
+		Name      string
+		FaceCount int
+		Face      string //First face ("<hash>/<index>"), image available at /faces/<hash>/<index>
+	}
+
+	PersonRequest struct {
+		Name   string
+		Person int64    //Person to merge
+		Faces  []string //Faces to split ("<hash>/<index>")
+	}
+)
+
+// ListPersons is a rest-api handler to send all persons, most faces first
+func ListPersons(c *gin.Context) {
+
+	persons, err := faces.LoadPersons()
+	if err != nil {
+		util.LogError(c.AbortWithError(http.StatusInternalServerError, err))
+		return
+	}
+
+	list := make([]PersonListItem, 0, len(persons.Persons))
+	for _, person := range persons.Persons {
+		item := PersonListItem{Id: person.Id, Name: person.Name, FaceCount: len(person.Faces)}
+		if len(person.Faces) > 0 {
+			item.Face = person.Faces[0].Face
+		}
+		list = append(list, item)
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].FaceCount > list[j].FaceCount })
+
+	c.IndentedJSON(http.StatusOK, list)
+}
+
+// GetPerson is a rest-api handler to send a person with all faces
+func GetPerson(c *gin.Context) {
+
+	id, ok := personParam(c)
+	if !ok {
+		return
+	}
+
+	persons, err := faces.LoadPersons()
+	if err != nil {
+		util.LogError(c.AbortWithError(http.StatusInternalServerError, err))
+		return
+	}
+	person, err := persons.GetPerson(id)
+	if abortOnUpdateError(c, err) {
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, person)
+}
+
+// UpdatePerson is a rest-api handler to name a person. Expects PersonRequest (Name) as JSON.
+func UpdatePerson(c *gin.Context) {
+
+	id, ok := personParam(c)
+	if !ok {
+		return
+	}
+
+	var req PersonRequest
+	if err := c.BindJSON(&req); err != nil {
+		return
+	}
+
+	person, err := faces.RenamePerson(id, req.Name)
+	if abortOnUpdateError(c, err) {
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, person)
+}
+
+// MergePerson is a rest-api handler to move all faces of another person (PersonRequest.Person) to this person
+func MergePerson(c *gin.Context) {
+
+	id, ok := personParam(c)
+	if !ok {
+		return
+	}
+
+	var req PersonRequest
+	if err := c.BindJSON(&req); err != nil {
+		return
+	}
+
+	person, err := faces.MergePersons(id, req.Person)
+	if abortOnUpdateError(c, err) {
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, person)
+}
+
+// SplitPerson is a rest-api handler to move faces (PersonRequest.Faces) to a new person (named PersonRequest.Name)
+func SplitPerson(c *gin.Context) {
+
+	id, ok := personParam(c)
+	if !ok {
+		return
+	}
+
+	var req PersonRequest
+	if err := c.BindJSON(&req); err != nil {
+		return
+	}
+
+	person, err := faces.SplitPerson(id, req.Faces, req.Name)
+	if abortOnUpdateError(c, err) {
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, person)
+}
+
+// personParam returns the person id from url, sends 404 if invalid
+func personParam(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		util.LogError(c.AbortWithError(http.StatusNotFound, fmt.Errorf("invalid person id")))
+		return 0, false
+	}
+	return id, true
+}
@@ -9,9 +9,29 @@ func CreateRoutes(router *gin.Engine) {
 	router.GET("/assets/:hash", users.AuthRequiredHandler(GetAsset))
 
 	router.POST("/assets/list", users.AuthRequiredHandler(ListAssets))
+	router.POST("/assets/facets", users.AuthRequiredHandler(ListFacets))
+	router.GET("/assets/by-digest/:algorithm/:digest", users.AuthRequiredHandler(FindByDigest))
+	router.GET("/assets/geo", users.AuthRequiredHandler(GetGeoClusters))
+	router.POST("/assets/geo", users.AuthRequiredHandler(GetGeoClusters))
+
+	router.GET("/timeline", users.AuthRequiredHandler(GetTimeline))
+	router.POST("/timeline", users.AuthRequiredHandler(GetTimeline))
+	router.GET("/timeline/onthisday", users.AuthRequiredHandler(ListOnThisDay))
+	router.POST("/timeline/onthisday", users.AuthRequiredHandler(ListOnThisDay))
 
 	router.GET("/assets/thumbnail/:hash", users.AuthRequiredHandler(GetPreview))
 	router.GET("/assets/metadata/:hash", users.AuthRequiredHandler(GetMetaData))
+	router.PATCH("/assets/metadata/:hash", users.AuthRequiredHandler(UpdateMetaData))
+	router.GET("/assets/:hash/history", users.AuthRequiredHandler(GetHistory))
+	router.POST("/assets/:hash/history/rollback", users.AuthRequiredHandler(RollbackMetaData))
+	router.GET("/assets/:hash/origins", users.AuthRequiredHandler(ListOrigins))
+	router.PATCH("/assets/:hash/origins/:index", users.AuthRequiredHandler(UpdateOrigin))
+	router.DELETE("/assets/:hash/origins/:index", users.AuthRequiredHandler(RemoveOrigin))
+	router.GET("/assets/:hash/versions", users.AuthRequiredHandler(ListVersions))
+	router.GET("/assets/:hash/comments", users.AuthRequiredHandler(ListComments))
+	router.POST("/assets/:hash/comments", users.AuthRequiredHandler(AddComment))
+	router.PATCH("/assets/:hash/comments/:id", users.AuthRequiredHandler(UpdateComment))
+	router.DELETE("/assets/:hash/comments/:id", users.AuthRequiredHandler(DeleteComment))
 
 	router.GET("/assets/filter/:filter/:hash", users.AuthRequiredHandler(GetFiltered))
 	router.POST("/assets/filter/:filter/:hash", users.AuthRequiredHandler(GetFiltered))
@@ -27,8 +47,17 @@ func CreateRoutes(router *gin.Engine) {
 
 	router.GET("/faces/:hash", users.AuthRequiredHandler(GetFaces))
 	router.GET("/faces/:hash/:idx", users.AuthRequiredHandler(GetFaceImage))
+	router.GET("/faces/:hash/:idx/similar", users.AuthRequiredHandler(GetSimilarFaces))
+
+	router.GET("/persons", users.AuthRequiredHandler(ListPersons))
+	router.GET("/persons/:id", users.AuthRequiredHandler(GetPerson))
+	router.PATCH("/persons/:id", users.AuthRequiredHandler(UpdatePerson))
+	router.POST("/persons/:id/merge", users.AuthRequiredHandler(MergePerson))
+	router.POST("/persons/:id/split", users.AuthRequiredHandler(SplitPerson))
 
 	router.GET("/mimetypes/list", users.AuthRequiredHandler(ListMimeTypes))
 	router.GET("/pathitems/list", users.AuthRequiredHandler(ListPathItems))
 	router.GET("/pathitems/list/:parent", users.AuthRequiredHandler(ListPathItems))
+	router.GET("/pathitems/path/:id", users.AuthRequiredHandler(GetPathItemPath))
+	router.GET("/pathitems/asset/:hash", users.AuthRequiredHandler(ListAssetPaths))
 }
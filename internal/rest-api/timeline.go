@@ -0,0 +1,63 @@
+package restapi
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	metadata_db "github.com/c8121/asset-storage/internal/metadata-db"
+	"github.com/c8121/asset-storage/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+// GetTimeline is a rest-api handler to send asset counts per year, month or day (?granularity=year|month|day).
+// Accepts AssetListFilter fields as query parameters (GET) or JSON (POST).
+func GetTimeline(c *gin.Context) {
+
+	listFilter := bindListFilter(c)
+
+	buckets, err := metadata_db.ListTimeline(listFilter, c.Query("granularity"))
+	if abortOnListError(c, err) {
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, buckets)
+}
+
+// ListOnThisDay is a rest-api handler to send assets captured on this day (or ?date=YYYY-MM-DD) in previous years.
+// Accepts AssetListFilter fields as query parameters (GET) or JSON (POST).
+func ListOnThisDay(c *gin.Context) {
+
+	listFilter := bindListFilter(c)
+
+	day := time.Now()
+	if date := c.Query("date"); date != "" {
+		var err error
+		if day, err = time.ParseInLocation("2006-01-02", date, time.UTC); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date, expected YYYY-MM-DD", "field": "date"})
+			return
+		}
+	}
+
+	list, err := metadata_db.ListOnThisDay(listFilter, day)
+	if abortOnListError(c, err) {
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, list)
+}
+
+// bindListFilter reads AssetListFilter from request, uses defaults if not available
+func bindListFilter(c *gin.Context) *metadata_db.AssetListFilter {
+
+	var listFilter *metadata_db.AssetListFilter = nil
+	err := c.ShouldBind(&listFilter)
+	if err != nil || listFilter == nil {
+		util.LogError(fmt.Errorf("failed to parse request: %w", err))
+		listFilter = &metadata_db.AssetListFilter{}
+	}
+	if listFilter.Count == 0 {
+		listFilter.Count = DefaultListItemCount
+	}
+	return listFilter
+}
@@ -1,6 +1,7 @@
 package restapi
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -9,19 +10,21 @@ import (
 	"time"
 
 	"github.com/c8121/asset-storage/internal/config"
+	"github.com/c8121/asset-storage/internal/ingest"
 	"github.com/c8121/asset-storage/internal/metadata"
-	metadata_db_entity "github.com/c8121/asset-storage/internal/metadata-db-entity"
 	"github.com/c8121/asset-storage/internal/storage"
+	"github.com/c8121/asset-storage/internal/users"
 	"github.com/c8121/asset-storage/internal/util"
 	"github.com/gin-gonic/gin"
 )
 
 type (
 	AddUploadedFileRequest struct {
-		TempName string
-		Name     string
-		Owner    string
-		FileTime time.Time
+		TempName    string
+		Name        string
+		Owner       string
+		FileTime    time.Time
+		DerivedFrom string //Optional hash of the asset the uploaded file is a version of
 	}
 )
 
@@ -67,8 +70,22 @@ func AddUploadedFile(c *gin.Context) {
 
 	path := filepath.Join(config.AssetStorageTempDir, req.TempName)
 
+	var derivedFrom json.RawMessage
+	if req.DerivedFrom != "" {
+		//Check before adding the file, the relation itself is validated by UpdateMetaData
+		if !storage.IsValidHash(req.DerivedFrom) {
+			c.JSON(http.StatusBadRequest, "DerivedFrom: invalid hash")
+			return
+		}
+		if _, err := metadata.LoadByHash(req.DerivedFrom); err != nil {
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("DerivedFrom: %s", err))
+			return
+		}
+		derivedFrom, _ = json.Marshal(req.DerivedFrom)
+	}
+
 	//Add file to storage
-	infos, err := storage.AddFile(path)
+	infos, err := storage.AddFileAs(path, req.Name)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, err.Error())
 		return
@@ -79,11 +96,16 @@ func AddUploadedFile(c *gin.Context) {
 	for _, info := range infos {
 		if info.IsNewFile || !config.SkipMetaDataIfExists {
 
+			//Unpacked files are named like within the archive or message
+			name := req.Name
+			if info.Parent != "" {
+				name = filepath.Base(info.SourcePath)
+			}
+
 			//Create/Update meta-data
-			meta, err := metadata.AddMetaData(
-				info.Hash,
-				info.MimeType,
-				req.Name,
+			meta, err := metadata.AddFileMetaData(
+				&info,
+				name,
 				"",
 				req.Owner,
 				req.FileTime)
@@ -92,13 +114,18 @@ func AddUploadedFile(c *gin.Context) {
 				return
 			}
 
+			//Mark the uploaded file (not unpacked items) as version of another asset
+			if derivedFrom != nil && info.Parent == "" {
+				meta, err = metadata.UpdateMetaData(info.Hash, map[string]json.RawMessage{"DerivedFrom": derivedFrom}, users.GetUsername(c))
+				if err != nil {
+					c.JSON(http.StatusBadRequest, err.Error())
+					return
+				}
+			}
+
 			list = append(list, *meta)
 
-			//Create/Update meta-data-database
-			err = metadata_db_entity.AddMetaData(meta)
-			if err != nil {
-				fmt.Printf("Error adding meta-data to database '%s': %s\n", path, err)
-			}
+			ingest.Process(meta, path, nil)
 		}
 	}
 
@@ -0,0 +1,31 @@
+package restapi
+
+import (
+	"net/http"
+
+	"github.com/c8121/asset-storage/internal/metadata"
+	metadata_db "github.com/c8121/asset-storage/internal/metadata-db"
+	"github.com/gin-gonic/gin"
+)
+
+// ListVersions is a rest-api handler to send the lineage of an asset:
+// the original and all versions derived from it (see metadata_db.ListVersions).
+// Set DerivedFrom via PATCH /assets/metadata/:hash to mark an asset as version of another.
+func ListVersions(c *gin.Context) {
+
+	hash, ok := hashParam(c)
+	if !ok {
+		return
+	}
+
+	if _, err := metadata.LoadByHash(hash); abortOnUpdateError(c, err) {
+		return
+	}
+
+	versions, err := metadata_db.ListVersions(hash)
+	if abortOnDatabaseError(c, err) {
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, versions)
+}
@@ -6,8 +6,8 @@ import (
 	"path/filepath"
 
 	"github.com/c8121/asset-storage/internal/config"
+	"github.com/c8121/asset-storage/internal/ingest"
 	"github.com/c8121/asset-storage/internal/metadata"
-	metadata_db_entity "github.com/c8121/asset-storage/internal/metadata-db-entity"
 	"github.com/c8121/asset-storage/internal/storage"
 )
 
@@ -47,9 +47,8 @@ func AddFilesToArchive(h SshHandler) {
 		for _, info := range infos {
 			if info.IsNewFile || !config.SkipMetaDataIfExists {
 				//Create/Update meta-data
-				meta, err := metadata.AddMetaData(
-					info.Hash,
-					info.MimeType,
+				meta, err := metadata.AddFileMetaData(
+					&info,
 					filepath.Base(info.SourcePath),
 					filepath.Dir(file.UserPath),
 					h.GetUsername(),
@@ -59,11 +58,7 @@ func AddFilesToArchive(h SshHandler) {
 					continue
 				}
 
-				//Create/Update meta-data-database
-				err = metadata_db_entity.AddMetaData(meta)
-				if err != nil {
-					fmt.Printf("Error adding meta-data to database '%s': %s\n", file, err)
-				}
+				ingest.Process(meta, file.LocalPath, nil)
 			}
 		}
 	}
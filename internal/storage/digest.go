@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+
+	"lukechampine.com/blake3"
+)
+
+// Digest algorithms. The content hash (AddedFileInfo.Hash) is SHA-256,
+// additional digests (see DigestAlgorithms) are calculated in the same pass.
+const (
+	DigestMD5    = "md5"
+	DigestSHA1   = "sha1"
+	DigestSHA256 = "sha256"
+	DigestBLAKE3 = "blake3"
+)
+
+var (
+	// DigestAlgorithms are calculated additionally to the content hash
+	DigestAlgorithms = []string{DigestMD5, DigestSHA1, DigestBLAKE3}
+
+	//Algorithm -> length of hex digest
+	digestLengths = map[string]int{
+		DigestMD5:    32,
+		DigestSHA1:   40,
+		DigestSHA256: 64,
+		DigestBLAKE3: 64,
+	}
+)
+
+// digestWriter calculates all DigestAlgorithms
+type digestWriter map[string]hash.Hash
+
+func newDigestWriter() digestWriter {
+	return digestWriter{
+		DigestMD5:    md5.New(),
+		DigestSHA1:   sha1.New(),
+		DigestBLAKE3: blake3.New(32, nil),
+	}
+}
+
+func (w digestWriter) Write(p []byte) (int, error) {
+	for _, h := range w {
+		h.Write(p)
+	}
+	return len(p), nil
+}
+
+// Sum returns algorithm -> hex digest
+func (w digestWriter) Sum() map[string]string {
+	digests := make(map[string]string, len(w))
+	for algorithm, h := range w {
+		digests[algorithm] = fmt.Sprintf("%x", h.Sum(nil))
+	}
+	return digests
+}
+
+// ComputeDigests reads all content and returns algorithm -> hex digest of
+// DigestAlgorithms and DigestSHA256 (the content hash)
+func ComputeDigests(reader io.Reader) (map[string]string, error) {
+
+	digests := newDigestWriter()
+	digests[DigestSHA256] = sha256.New()
+
+	buf := make([]byte, IoBufferSize)
+	if _, err := io.CopyBuffer(digests, reader, buf); err != nil {
+		return nil, err
+	}
+	return digests.Sum(), nil
+}
+
+// ParseDigest checks algorithm and hex digest, returns the digest in lower case
+func ParseDigest(algorithm string, digest string) (string, error) {
+
+	length, ok := digestLengths[algorithm]
+	if !ok {
+		return "", fmt.Errorf("unknown digest algorithm '%s'", algorithm)
+	}
+
+	digest = strings.ToLower(strings.TrimSpace(digest))
+	if len(digest) != length || strings.Trim(digest, "0123456789abcdef") != "" {
+		return "", fmt.Errorf("invalid %s digest '%s' (expected %d hex digits)", algorithm, digest, length)
+	}
+	return digest, nil
+}
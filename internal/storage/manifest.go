@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// ManifestEntry is one line of a checksum manifest, see ParseManifest
+type ManifestEntry struct {
+	Line       int
+	Algorithms []string //Possible algorithms (a 64 digit digest is SHA-256 or BLAKE3 if not given)
+	Digest     string   //Hex, lower case
+	Name       string
+}
+
+var (
+	//md5sum, sha1sum, sha256sum, b3sum: "<digest>  <name>" (text mode) or "<digest> *<name>" (binary mode)
+	manifestLinePattern = regexp.MustCompile(`^([0-9a-fA-F]+) [ *](.+)$`)
+
+	//BSD style (md5 -r, shasum --tag, b3sum --tag): "MD5 (<name>) = <digest>"
+	manifestTagPattern = regexp.MustCompile(`^(MD5|SHA1|SHA256|BLAKE3) \((.+)\) = ([0-9a-fA-F]+)$`)
+)
+
+// ParseManifest reads a checksum manifest as written by md5sum, sha1sum, sha256sum or b3sum (also with --tag).
+// algorithm is used if not tagged, otherwise the algorithm is derived from the length of the digest.
+// Empty lines and lines starting with '#' are skipped.
+func ParseManifest(reader io.Reader, algorithm string) ([]ManifestEntry, error) {
+
+	entries := make([]ManifestEntry, 0)
+
+	scanner := bufio.NewScanner(reader)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		//Names containing newline or backslash are escaped, the line starts with a backslash then
+		escaped := strings.HasPrefix(line, "\\")
+		if escaped {
+			line = line[1:]
+		}
+
+		entry := ManifestEntry{Line: lineNumber}
+		if m := manifestTagPattern.FindStringSubmatch(line); m != nil {
+			entry.Algorithms = []string{strings.ToLower(m[1])}
+			entry.Name, entry.Digest = m[2], m[3]
+		} else if m := manifestLinePattern.FindStringSubmatch(line); m != nil {
+			entry.Digest, entry.Name = m[1], m[2]
+			if algorithm != "" {
+				entry.Algorithms = []string{algorithm}
+			} else {
+				entry.Algorithms = algorithmsByDigestLength(len(entry.Digest))
+			}
+		} else {
+			return nil, fmt.Errorf("line %d: invalid manifest line", lineNumber)
+		}
+		if escaped {
+			entry.Name = strings.NewReplacer("\\\\", "\\", "\\n", "\n").Replace(entry.Name)
+		}
+
+		if len(entry.Algorithms) == 0 {
+			return nil, fmt.Errorf("line %d: unknown digest length %d", lineNumber, len(entry.Digest))
+		}
+		for _, a := range entry.Algorithms {
+			digest, err := ParseDigest(a, entry.Digest)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			entry.Digest = digest
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
+
+// algorithmsByDigestLength returns all algorithms having hex digests of the given length
+func algorithmsByDigestLength(length int) []string {
+	algorithms := make([]string, 0)
+	for _, algorithm := range []string{DigestMD5, DigestSHA1, DigestSHA256, DigestBLAKE3} {
+		if digestLengths[algorithm] == length {
+			algorithms = append(algorithms, algorithm)
+		}
+	}
+	return algorithms
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"bytes"
+	"mime"
+	"path/filepath"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+/*
+	Mime-types are detected from content. Containers (zip, ole, ...) are only recognized as a specific
+	type (docx, xlsx, odt...) if the relevant entries are within the first bytes, so detection
+	reads MimeTypePeekSize bytes and the file extension is used as tie-breaker.
+*/
+
+const (
+	// MimeTypePeekSize is the number of bytes read from the beginning of a file to detect its mime-type
+	MimeTypePeekSize = 256 * 1024
+
+	rootMimeType = "application/octet-stream"
+)
+
+var (
+	// mimeTypesByExtension contains types not known by mime.TypeByExtension on all systems
+	mimeTypesByExtension = map[string]string{
+		".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+		".odt":  "application/vnd.oasis.opendocument.text",
+		".ods":  "application/vnd.oasis.opendocument.spreadsheet",
+		".odp":  "application/vnd.oasis.opendocument.presentation",
+		".odg":  "application/vnd.oasis.opendocument.graphics",
+		".epub": "application/epub+zip",
+		".jar":  "application/java-archive",
+		".apk":  "application/vnd.android.package-archive",
+		".doc":  "application/msword",
+		".xls":  "application/vnd.ms-excel",
+		".ppt":  "application/vnd.ms-powerpoint",
+		".mp4":  "video/mp4",
+		".m4v":  "video/x-m4v",
+		".m4a":  "audio/x-m4a",
+		".mov":  "video/quicktime",
+		".3gp":  "video/3gpp",
+		".mkv":  "video/x-matroska",
+		".webm": "video/webm",
+		".avi":  "video/x-msvideo",
+		".heic": "image/heic",
+	}
+)
+
+func init() {
+	mimetype.SetLimit(MimeTypePeekSize)
+}
+
+// DetectMimeType detects the mime-type from the first bytes of a file (see MimeTypePeekSize).
+// The extension of name is used if it denotes a more specific type than detected from content
+// (docx instead of zip for example). Messages and mailboxes are detected as well, see MailMimeType.
+func DetectMimeType(head []byte, name string) string {
+
+	detected := mimetype.Detect(head)
+	ext := strings.ToLower(filepath.Ext(name))
+
+	if mailMimeType := MailMimeType(name, ""); mailMimeType != "" {
+		return mailMimeType
+	}
+	if ext == "" && detected.Is("text/plain") && bytes.HasPrefix(head, mboxSeparator) {
+		//Mailboxes of some mail clients have no extension
+		return MboxMimeType
+	}
+
+	if byExtension := mimeTypeByExtension(ext); byExtension != "" && isMoreSpecific(byExtension, detected) {
+		return byExtension
+	}
+	return detected.String()
+}
+
+// mimeTypeByExtension returns the mime-type (without parameters) of a file extension, empty if unknown
+func mimeTypeByExtension(ext string) string {
+	if ext == "" {
+		return ""
+	}
+	if mimeType, ok := mimeTypesByExtension[ext]; ok {
+		return mimeType
+	}
+	mediaType, _, err := mime.ParseMediaType(mime.TypeByExtension(ext))
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
+
+// isMoreSpecific checks if mimeType is a sub-type of detected (docx is a zip, csv is text).
+// Types unknown to content detection are only used if nothing was detected.
+func isMoreSpecific(mimeType string, detected *mimetype.MIME) bool {
+
+	candidate := mimetype.Lookup(mimeType)
+	if candidate == nil {
+		return detected.Is(rootMimeType)
+	}
+	for parent := candidate.Parent(); parent != nil; parent = parent.Parent() {
+		if detected.Is(parent.String()) {
+			return true
+		}
+	}
+	return false
+}
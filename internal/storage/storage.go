@@ -2,6 +2,7 @@ package storage
 
 import (
 	"crypto/sha256"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -12,7 +13,6 @@ import (
 
 	"github.com/c8121/asset-storage/internal/config"
 	"github.com/c8121/asset-storage/internal/util"
-	"github.com/gabriel-vasile/mimetype"
 )
 
 const (
@@ -28,9 +28,25 @@ type (
 		MimeType    string
 		IsNewFile   bool
 		Size        int64
+		Digests     map[string]string //Algorithm -> hex digest, see DigestAlgorithms
+
+		//Set for messages (unpacked or added), see unpackMail
+		From     string
+		Subject  string
+		FileTime time.Time //Date of message, zero if not available
+
+		//Set for unpacked files
+		Parent   string //Hash of the archive, mailbox or message the file was unpacked from
+		Relation string //Relation to Parent, see Relation* constants
 	}
 )
 
+// Relations of unpacked files to the file they were unpacked from
+const (
+	RelationPartOf       = "part-of"       //File of an archive, message of a mailbox
+	RelationAttachmentOf = "attachment-of" //Attachment of a message
+)
+
 // CreateDirectories creates required directories
 func CreateDirectories() {
 	util.CreateDirIfNotExists(config.AssetStorageBaseDir, FilePermissions)
@@ -40,6 +56,12 @@ func CreateDirectories() {
 // AddFile adds one file to asset-storage.
 // Returns content-hash, file-path, mime-type, error as AddedFileInfo (might be more than one if an archive was added)
 func AddFile(path string) ([]AddedFileInfo, error) {
+	return AddFileAs(path, path)
+}
+
+// AddFileAs adds one file like AddFile, name is used to detect types not detectable from content
+// (temp-files of uploads for example).
+func AddFileAs(path string, name string) ([]AddedFileInfo, error) {
 
 	fmt.Println("Add file:", path)
 
@@ -58,12 +80,17 @@ func AddFile(path string) ([]AddedFileInfo, error) {
 
 	infos := make([]AddedFileInfo, 0)
 
-	info, err := copyToStorage(reader, stat.Size())
+	info, err := copyToStorage(reader, stat.Size(), name)
 	if err != nil {
 		return nil, err
 	}
 
 	info.SourcePath = path
+	if info.MimeType == MessageMimeType {
+		if err := readMessageInfo(path, info); err != nil {
+			fmt.Printf("Cannot read message '%s': %s\n", path, err)
+		}
+	}
 	infos = append(infos, *info)
 
 	if !info.IsNewFile {
@@ -75,6 +102,12 @@ func AddFile(path string) ([]AddedFileInfo, error) {
 		if err == nil {
 			for _, item := range unpacked {
 				item.SourcePath = path + "/" + item.SourcePath
+				if item.Parent == "" {
+					item.Parent = info.Hash
+				}
+				if item.Relation == "" {
+					item.Relation = RelationPartOf
+				}
 				infos = append(infos, item)
 				fmt.Printf(" '--> %s\n", item.SourcePath)
 			}
@@ -86,7 +119,8 @@ func AddFile(path string) ([]AddedFileInfo, error) {
 	return infos, nil
 }
 
-func copyToStorage(reader io.Reader, size int64) (*AddedFileInfo, error) {
+// copyToStorage stores content, name is used to detect the mime-type (see DetectMimeType)
+func copyToStorage(reader io.Reader, size int64, name string) (*AddedFileInfo, error) {
 
 	var info = &AddedFileInfo{IsNewFile: false}
 
@@ -105,17 +139,19 @@ func copyToStorage(reader io.Reader, size int64) (*AddedFileInfo, error) {
 	defer util.CloseOrLog(outWriter)
 
 	buf := make([]byte, IoBufferSize)
+	head := make([]byte, 0, min(max(size, IoBufferSize), MimeTypePeekSize))
 	hash := sha256.New()
+	digests := newDigestWriter()
 
 	for {
 		n, err := reader.Read(buf)
 		if n > 0 {
-			if len(info.MimeType) == 0 { //must be before outWriter.Write, because buf might get xor'ed
-				mime := mimetype.Detect(buf[:n])
-				info.MimeType = mime.String()
+			if len(head) < MimeTypePeekSize { //must be before outWriter.Write, because buf might get xor'ed
+				head = append(head, buf[:min(n, MimeTypePeekSize-len(head))]...)
 			}
 
 			hash.Write(buf[:n]) //must be before outWriter.Write
+			digests.Write(buf[:n])
 
 			n, err = outWriter.Write(buf[:n])
 			if err != nil {
@@ -134,7 +170,9 @@ func copyToStorage(reader io.Reader, size int64) (*AddedFileInfo, error) {
 
 	util.CloseOrLog(outWriter)
 
+	info.MimeType = DetectMimeType(head, name)
 	info.Hash = fmt.Sprintf("%x", hash.Sum(nil))
+	info.Digests = digests.Sum()
 	if len(info.Hash) < 2 {
 		return info, fmt.Errorf("invalid hash length: %d", len(info.Hash))
 	}
@@ -261,6 +299,42 @@ func HashFromStoragePath(path string) string {
 	return hash
 }
 
+// IsValidHash checks if hash is a content hash as created by this package (SHA-256 as 64 lower-case hex digits).
+// Hashes are part of file paths, so hashes from requests must be checked before use.
+func IsValidHash(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	for _, c := range hash {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// UpgradeHashFromStoragePath is a util.JsonUpgrade for files stored by hash (meta-data, collections):
+// Sets Hash from the file path (see HashFromStoragePath) if missing or empty
+func UpgradeHashFromStoragePath(doc map[string]json.RawMessage, path string) error {
+	var hash string
+	if err := util.UnmarshalOrZero(doc["Hash"], &hash); err != nil {
+		return err
+	}
+	if hash == "" {
+		value, err := json.Marshal(HashFromStoragePath(path))
+		if err != nil {
+			return err
+		}
+		doc["Hash"] = value
+	}
+	return nil
+}
+
+// TimePeriodFromStoragePath Extract time-period name from path (.../time-period/hash[:2]/hash[2:])
+func TimePeriodFromStoragePath(path string) string {
+	return filepath.Base(filepath.Dir(filepath.Dir(path)))
+}
+
 // HashFromContent calculates the content hash
 func HashFromContent(path string) (string, error) {
 
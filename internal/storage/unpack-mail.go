@@ -0,0 +1,312 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/c8121/asset-storage/internal/util"
+)
+
+/*
+	Messages (.eml) and mailboxes (.mbox) are unpacked: each message of a mailbox and each attachment
+	of a message is stored as asset. Attached messages (forwarded as attachment) are unpacked as well.
+*/
+
+const (
+	MessageMimeType = "message/rfc822"
+	MboxMimeType    = "application/mbox"
+
+	// MaxAttachmentDepth limits unpacking of messages attached to messages
+	MaxAttachmentDepth = 5
+
+	// MaxFileNameLength limits names of messages (taken from subject) and attachments
+	MaxFileNameLength = 200
+)
+
+var (
+	mboxSeparator = []byte("From ")
+
+	headerDecoder = &mime.WordDecoder{}
+)
+
+// MailMimeType returns the mime-type of messages and mailboxes by extension, which are not detected from content
+// (usually detected as text/plain). Returns detected for other files.
+func MailMimeType(name string, detected string) string {
+
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".eml":
+		return MessageMimeType
+	case ".mbox":
+		return MboxMimeType
+	}
+	return detected
+}
+
+// IsMail checks if file can be unpacked by unpackMail
+func IsMail(mimeType string) bool {
+	return mimeType == MessageMimeType || mimeType == MboxMimeType
+}
+
+// unpackMail stores all messages of a mailbox or all attachments of a message
+func unpackMail(path string, mimeType string) ([]AddedFileInfo, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer util.CloseOrLog(file)
+
+	if mimeType == MessageMimeType {
+		msg, err := mail.ReadMessage(file)
+		if err != nil {
+			return nil, err
+		}
+		//Attachments of the message itself relate to the file being added (Parent is set by AddFile)
+		date, _ := msg.Header.Date()
+		return unpackAttachments(msg.Header, msg.Body, "", "", date, 0), nil
+	}
+
+	unpacked := make([]AddedFileInfo, 0)
+	count := 0
+	err = splitMbox(file, func(raw []byte) {
+		count++
+		unpacked = append(unpacked, storeMessage(raw, fmt.Sprintf("message-%d", count), "", 0)...)
+	})
+	return unpacked, err
+}
+
+// storeMessage stores a message and its attachments. Returns the message first, then attachments.
+func storeMessage(raw []byte, defaultName string, parentPath string, depth int) []AddedFileInfo {
+
+	info, err := copyToStorage(bytes.NewReader(raw), int64(len(raw)), "")
+	if err != nil {
+		fmt.Printf("Error copying message %s: %s\n", defaultName, err)
+		return nil
+	}
+	info.MimeType = MessageMimeType
+	info.Size = int64(len(raw))
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		//Keep the message, even if it cannot be parsed
+		fmt.Printf("Error reading message %s: %s\n", defaultName, err)
+		info.SourcePath = joinSourcePath(parentPath, defaultName+".eml")
+		return []AddedFileInfo{*info}
+	}
+
+	setMessageInfo(info, msg.Header)
+	name := defaultName
+	if info.Subject != "" {
+		name = info.Subject
+	}
+	info.SourcePath = joinSourcePath(parentPath, sanitizeFileName(name)+".eml")
+
+	unpacked := []AddedFileInfo{*info}
+	return append(unpacked, unpackAttachments(msg.Header, msg.Body, info.Hash, info.SourcePath, info.FileTime, depth)...)
+}
+
+// unpackAttachments stores all attachments of a message.
+// parent is the hash of the message (empty if the message is the file being added), parentPath its SourcePath,
+// date of the message is used as file-time of attachments.
+func unpackAttachments(header mail.Header, body io.Reader, parent string, parentPath string, date time.Time, depth int) []AddedFileInfo {
+
+	unpacked := make([]AddedFileInfo, 0)
+	count := 0
+
+	var walk func(contentType string, disposition string, encoding string, body io.Reader, top bool)
+	walk = func(contentType string, disposition string, encoding string, body io.Reader, top bool) {
+
+		mediaType, params, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			mediaType = "text/plain"
+		}
+
+		if strings.HasPrefix(mediaType, "multipart/") {
+			reader := multipart.NewReader(body, params["boundary"])
+			for {
+				part, err := reader.NextRawPart()
+				if errors.Is(err, io.EOF) {
+					return
+				} else if err != nil {
+					fmt.Printf("Error reading message part: %s\n", err)
+					return
+				}
+				walk(part.Header.Get("Content-Type"), part.Header.Get("Content-Disposition"),
+					part.Header.Get("Content-Transfer-Encoding"), part, false)
+			}
+		}
+
+		dispositionType, dispositionParams, _ := mime.ParseMediaType(disposition)
+		fileName := decodeHeader(dispositionParams["filename"])
+		if fileName == "" {
+			fileName = decodeHeader(params["name"])
+		}
+		isAttachment := dispositionType == "attachment" || fileName != "" || (mediaType == MessageMimeType && !top)
+		if top || !isAttachment {
+			//Message text
+			return
+		}
+
+		content, err := io.ReadAll(decodeBody(body, encoding))
+		if err != nil {
+			fmt.Printf("Error reading attachment %s: %s\n", fileName, err)
+			return
+		}
+		count++
+		if fileName == "" {
+			fileName = fmt.Sprintf("attachment-%d", count)
+		}
+
+		if mediaType == MessageMimeType && depth < MaxAttachmentDepth {
+			for i, item := range storeMessage(content, strings.TrimSuffix(fileName, ".eml"), parentPath, depth+1) {
+				if i == 0 {
+					//Attached message, its attachments relate to it
+					item.Parent = parent
+					item.Relation = RelationAttachmentOf
+				}
+				unpacked = append(unpacked, item)
+			}
+			return
+		}
+
+		info, err := copyToStorage(bytes.NewReader(content), int64(len(content)), fileName)
+		if err != nil {
+			fmt.Printf("Error copying attachment %s: %s\n", fileName, err)
+			return
+		}
+		info.Size = int64(len(content))
+		info.SourcePath = joinSourcePath(parentPath, sanitizeFileName(fileName))
+		info.FileTime = date
+		info.Parent = parent
+		info.Relation = RelationAttachmentOf
+		unpacked = append(unpacked, *info)
+	}
+
+	walk(header.Get("Content-Type"), header.Get("Content-Disposition"), header.Get("Content-Transfer-Encoding"), body, true)
+	return unpacked
+}
+
+// setMessageInfo sets sender, subject and date of a message
+func setMessageInfo(info *AddedFileInfo, header mail.Header) {
+	info.From = decodeHeader(header.Get("From"))
+	info.Subject = decodeHeader(header.Get("Subject"))
+	if date, err := header.Date(); err == nil {
+		info.FileTime = date
+	}
+}
+
+// readMessageInfo sets sender, subject and date of a message file
+func readMessageInfo(path string, info *AddedFileInfo) error {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer util.CloseOrLog(file)
+
+	msg, err := mail.ReadMessage(file)
+	if err != nil {
+		return err
+	}
+	setMessageInfo(info, msg.Header)
+	return nil
+}
+
+// splitMbox calls handler with each message of a mailbox (mboxrd format: ">From " is unescaped)
+func splitMbox(reader io.Reader, handler func(raw []byte)) error {
+
+	var msg bytes.Buffer
+	started := false
+	previousEmpty := true
+
+	flush := func() {
+		if started {
+			//Line separating messages is not part of the message
+			raw := bytes.TrimSuffix(msg.Bytes(), []byte("\n"))
+			raw = bytes.TrimSuffix(raw, []byte("\r"))
+			handler(bytes.Clone(raw))
+		}
+		msg.Reset()
+	}
+
+	buffered := bufio.NewReader(reader)
+	for {
+		line, err := buffered.ReadBytes('\n')
+		if len(line) > 0 {
+			if previousEmpty && bytes.HasPrefix(line, mboxSeparator) {
+				flush()
+				started = true
+			} else if started {
+				if unescaped := bytes.TrimLeft(line, ">"); len(unescaped) < len(line) && bytes.HasPrefix(unescaped, mboxSeparator) {
+					line = line[1:]
+				}
+				msg.Write(line)
+			}
+			previousEmpty = len(bytes.TrimSpace(line)) == 0
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+	flush()
+
+	if !started {
+		return fmt.Errorf("not a mailbox (no 'From ' line found)")
+	}
+	return nil
+}
+
+// decodeBody decodes base64 or quoted-printable content
+func decodeBody(body io.Reader, encoding string) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	}
+	return body
+}
+
+// decodeHeader decodes RFC 2047 encoded words, returns the value unchanged if it cannot be decoded
+func decodeHeader(value string) string {
+	decoded, err := headerDecoder.DecodeHeader(value)
+	if err != nil {
+		return strings.TrimSpace(value)
+	}
+	return strings.TrimSpace(decoded)
+}
+
+// sanitizeFileName removes path separators and control characters from names taken from messages
+func sanitizeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r < 0x20 {
+			return '_'
+		}
+		return r
+	}, name)
+	if runes := []rune(name); len(runes) > MaxFileNameLength {
+		name = string(runes[:MaxFileNameLength])
+	}
+	return strings.TrimSpace(name)
+}
+
+func joinSourcePath(parentPath string, name string) string {
+	if parentPath == "" {
+		return name
+	}
+	return parentPath + "/" + name
+}
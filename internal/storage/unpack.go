@@ -10,15 +10,20 @@ import (
 
 // IsUnpackable checks if file can be unpacked.
 func IsUnpackable(path string, mimeType string) bool {
-	if strings.HasSuffix(strings.ToLower(mimeType), "zip") {
+	if strings.HasSuffix(strings.ToLower(mimeType), "zip") || IsMail(mimeType) {
 		return true
 	}
 	return false
 }
 
 // Unpack deflates files directly to storage.
+// Messages and mailboxes are unpacked as well, see unpackMail.
 func Unpack(path string, mimeType string) ([]AddedFileInfo, error) {
 
+	if IsMail(mimeType) {
+		return unpackMail(path, mimeType)
+	}
+
 	if !strings.HasSuffix(strings.ToLower(mimeType), "zip") {
 		fmt.Printf("Not an archive: %s, %s", path, mimeType)
 		return nil, nil
@@ -44,7 +49,7 @@ func Unpack(path string, mimeType string) ([]AddedFileInfo, error) {
 			continue
 		}
 
-		info, err := copyToStorage(reader, file.FileInfo().Size())
+		info, err := copyToStorage(reader, file.FileInfo().Size(), file.Name)
 		if err != nil {
 			fmt.Printf("Error copying file %s: %s\n", file.Name, err)
 		} else {
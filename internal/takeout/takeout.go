@@ -0,0 +1,208 @@
+package takeout
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/c8121/asset-storage/internal/metadata"
+)
+
+/*
+	Google Takeout (Google Photos export) writes a sidecar JSON next to each photo or video:
+
+	- photo.jpg -> photo.jpg.json (newer exports: photo.jpg.supplemental-metadata.json)
+	- photo(1).jpg -> photo.jpg(1).json (duplicate names)
+	- photo-edited.jpg -> photo.jpg.json (edited copy, shares the sidecar of the original)
+	- Names of sidecars are truncated to MaxSidecarNameLength (without .json)
+
+	Album folders contain an AlbumFileName with title and description of the album.
+*/
+
+type (
+	// Sidecar is the meta-data of one photo or video
+	Sidecar struct {
+		Title          string //Original file name
+		Description    string
+		PhotoTakenTime Timestamp
+		CreationTime   Timestamp //Upload time
+		GeoData        GeoData
+		GeoDataExif    GeoData
+	}
+
+	Timestamp struct {
+		Timestamp string //Unix time in seconds
+	}
+
+	GeoData struct {
+		Latitude  float64
+		Longitude float64
+	}
+
+	// Album is the meta-data of an album folder
+	Album struct {
+		Title       string
+		Description string
+	}
+)
+
+const (
+	SidecarExtension     = ".json"
+	SupplementalSuffix   = ".supplemental-metadata"
+	EditedSuffix         = "-edited"
+	AlbumFileName        = "metadata.json"
+	MaxSidecarNameLength = 46
+)
+
+// duplicateName matches names like "photo(1)" of duplicate file names
+var duplicateName = regexp.MustCompile(`^(.*)(\(\d+\))$`)
+
+// IsSidecar checks if the file is a sidecar or album meta-data, which should not be added as asset
+func IsSidecar(path string) bool {
+
+	name := filepath.Base(path)
+	if !strings.HasSuffix(strings.ToLower(name), SidecarExtension) {
+		return false
+	}
+	if name == AlbumFileName {
+		album, err := LoadAlbum(filepath.Dir(path))
+		return err == nil && album != nil
+	}
+
+	sidecar, err := load(path)
+	return err == nil && sidecar.Title != "" &&
+		(sidecar.PhotoTakenTime.Timestamp != "" || sidecar.CreationTime.Timestamp != "")
+}
+
+// FindSidecar loads the sidecar of a photo or video, nil if not found
+func FindSidecar(mediaPath string) (*Sidecar, error) {
+
+	dir, name := filepath.Split(mediaPath)
+	for _, candidate := range sidecarNames(name) {
+		sidecar, err := load(filepath.Join(dir, candidate))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		return sidecar, err
+	}
+	return nil, nil
+}
+
+// sidecarNames returns possible names of the sidecar of a file, most specific first
+func sidecarNames(name string) []string {
+
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+
+	//Without .json, counter of duplicates is appended after the suffix
+	type stem struct {
+		name    string
+		counter string
+	}
+	stems := []stem{{name, ""}}
+	if m := duplicateName.FindStringSubmatch(base); m != nil {
+		stems = append(stems, stem{m[1] + ext, m[2]})
+	}
+	if strings.HasSuffix(base, EditedSuffix) {
+		stems = append(stems, stem{strings.TrimSuffix(base, EditedSuffix) + ext, ""})
+	}
+
+	names := make([]string, 0, len(stems)*4)
+	for _, s := range stems {
+		for _, suffix := range []string{"", SupplementalSuffix} {
+			full := s.name + suffix
+			names = append(names, full+s.counter+SidecarExtension)
+			if len(full) > MaxSidecarNameLength {
+				names = append(names, full[:MaxSidecarNameLength]+s.counter+SidecarExtension)
+			}
+		}
+	}
+	return names
+}
+
+// LoadAlbum loads album meta-data of a folder, nil if it is not an album
+func LoadAlbum(dir string) (*Album, error) {
+
+	buf, err := os.ReadFile(filepath.Join(dir, AlbumFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	album := &Album{}
+	if err = json.Unmarshal(buf, album); err != nil {
+		return nil, err
+	}
+	if album.Title == "" {
+		return nil, nil
+	}
+	return album, nil
+}
+
+// TakenTime returns the capture time, upload time if not available, zero if none
+func (sidecar *Sidecar) TakenTime() time.Time {
+	if t := sidecar.PhotoTakenTime.Time(); !t.IsZero() {
+		return t
+	}
+	return sidecar.CreationTime.Time()
+}
+
+// Location returns the GPS coordinates, nil if not available
+func (sidecar *Sidecar) Location() *metadata.JsonLocation {
+	for _, geo := range []GeoData{sidecar.GeoData, sidecar.GeoDataExif} {
+		//Takeout uses 0,0 if not located
+		if geo.Latitude != 0 || geo.Longitude != 0 {
+			return &metadata.JsonLocation{Latitude: geo.Latitude, Longitude: geo.Longitude}
+		}
+	}
+	return nil
+}
+
+// Time converts the timestamp, zero if empty or invalid
+func (ts Timestamp) Time() time.Time {
+	seconds, err := strconv.ParseInt(ts.Timestamp, 10, 64)
+	if err != nil || seconds <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
+
+// Apply adds capture time, location and description of the sidecar to meta-data, if not set before
+// (from EXIF for example), see metadata.UpdateMetaData. Returns the meta-data (saved if changed).
+func Apply(hash string, sidecar *Sidecar) (*metadata.JsonAssetMetaData, error) {
+
+	return metadata.UpdateMetaDataWith(hash, func(meta *metadata.JsonAssetMetaData) map[string]json.RawMessage {
+
+		changes := make(map[string]json.RawMessage)
+		if t := sidecar.PhotoTakenTime.Time(); meta.CaptureTime.IsZero() && !t.IsZero() {
+			changes["CaptureTime"], _ = json.Marshal(t)
+		}
+		if location := sidecar.Location(); meta.Location == nil && location != nil {
+			changes["Location"], _ = json.Marshal(location)
+		}
+		if meta.Description == "" && sidecar.Description != "" {
+			changes["Description"], _ = json.Marshal(sidecar.Description)
+		}
+		return changes
+	}, metadata.SystemUser)
+}
+
+func load(path string) (*Sidecar, error) {
+
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sidecar := &Sidecar{}
+	if err = json.Unmarshal(buf, sidecar); err != nil {
+		return nil, err
+	}
+	return sidecar, nil
+}
@@ -0,0 +1,8 @@
+package text_extractor
+
+import "io"
+
+type Extractor interface {
+	//Extract reads the asset content and returns plain text
+	Extract(reader io.Reader, mimeType string) (string, error)
+}
@@ -0,0 +1,146 @@
+package text_extractor
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/c8121/asset-storage/internal/metadata"
+	"github.com/c8121/asset-storage/internal/storage"
+	"github.com/c8121/asset-storage/internal/util"
+)
+
+type AssetTextExtractor struct {
+	Name      string //Internal, descriptive name
+	Extractor Extractor
+	MimeTypes []string //Asset Mime-Type RegEx, which the extractor can handle
+}
+
+var (
+	AvailableExtractors = []AssetTextExtractor{}
+
+	MaxTextLength = 1024 * 1024 * 4 //Extracted text will be truncated
+
+	ErrNoExtractor = errors.New("no text extractor available")
+)
+
+// GetExtractorsByMimeType returns all extractors out of AvailableExtractors
+// which have a matching mime-type, in order of AvailableExtractors
+func GetExtractorsByMimeType(mimetype string) []AssetTextExtractor {
+
+	loadAvailableExtractors()
+
+	extractors := make([]AssetTextExtractor, 0)
+	for _, e := range AvailableExtractors {
+		for _, m := range e.MimeTypes {
+			match, _ := regexp.MatchString(m, mimetype)
+			if match {
+				extractors = append(extractors, e)
+				break
+			}
+		}
+	}
+	return extractors
+}
+
+// ExtractText reads the asset from storage and extracts text using the first
+// matching extractor which succeeds.
+func ExtractText(assetHash string, meta *metadata.JsonAssetMetaData) (string, error) {
+
+	extractors := GetExtractorsByMimeType(meta.MimeType)
+	if len(extractors) == 0 {
+		return "", ErrNoExtractor
+	}
+
+	var lastErr error
+	for _, e := range extractors {
+		reader, err := storage.Open(assetHash)
+		if err != nil {
+			return "", fmt.Errorf("failed to load asset: %w", err)
+		}
+
+		text, err := e.Extractor.Extract(reader, meta.MimeType)
+		util.CloseOrLog(reader)
+		if err == nil {
+			fmt.Printf("Extracted %d characters using '%s' from %s\n", len(text), e.Name, assetHash)
+			return truncate(text), nil
+		}
+		fmt.Printf("Text extractor '%s' failed: %s\n", e.Name, err)
+		lastErr = err
+	}
+
+	return "", lastErr
+}
+
+// ExtractIfNotExists extracts text and saves it next to the meta-data,
+// if no text was extracted before.
+func ExtractIfNotExists(meta *metadata.JsonAssetMetaData) error {
+
+	if metadata.TextExists(meta.Hash) {
+		return nil
+	}
+
+	text, err := ExtractText(meta.Hash, meta)
+	if errors.Is(err, ErrNoExtractor) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return metadata.SaveText(meta.Hash, text)
+}
+
+// truncate limits text to MaxTextLength, keeping valid UTF-8
+func truncate(text string) string {
+	text = strings.ToValidUTF8(text, "")
+	if len(text) <= MaxTextLength {
+		return text
+	}
+	return strings.ToValidUTF8(text[:MaxTextLength], "")
+}
+
+func loadAvailableExtractors() {
+
+	//TODO make mime-type->extractor mapping in configurable
+
+	if len(AvailableExtractors) > 0 {
+		return
+	}
+
+	AvailableExtractors = []AssetTextExtractor{
+		{
+			Name:      "PlainText",
+			Extractor: NewPlainTextExtractor(),
+			MimeTypes: []string{
+				"(?i)^text/plain",
+				"(?i)^text/markdown",
+				"(?i)^text/x-markdown",
+				"(?i)^text/csv",
+				"(?i)^text/tab-separated-values",
+			},
+		},
+		{
+			Name:      "PdfToText",
+			Extractor: NewPdfToTextExtractor(),
+			MimeTypes: []string{
+				"(?i)^application/pdf$",
+			},
+		},
+		{
+			Name:      "NativePdf",
+			Extractor: NewNativePdfExtractor(),
+			MimeTypes: []string{
+				"(?i)^application/pdf$",
+			},
+		},
+		{
+			Name:      "OfficeXml",
+			Extractor: NewOfficeXmlExtractor(),
+			MimeTypes: []string{
+				"(?i)^application/vnd\\.openxmlformats-officedocument\\.",
+				"(?i)^application/vnd\\.oasis\\.opendocument\\.",
+			},
+		},
+	}
+}
@@ -0,0 +1,127 @@
+package text_extractor
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+)
+
+// OfficeXmlExtractor reads text from zip-based office documents:
+// Office Open XML (DOCX, XLSX, PPTX) and OpenDocument (ODT, ODS, ODP).
+type OfficeXmlExtractor struct {
+	MaxFileSize int64
+}
+
+func NewOfficeXmlExtractor() *OfficeXmlExtractor {
+	e := &OfficeXmlExtractor{}
+	e.MaxFileSize = 1024 * 1024 * 100
+	return e
+}
+
+var (
+	// Elements which end a paragraph/cell/row, a line break is added after them
+	officeXmlBreakElements = map[string]bool{
+		"p":          true, //w:p, a:p, text:p
+		"h":          true, //text:h
+		"br":         true, //w:br
+		"tr":         true, //w:tr
+		"si":         true, //XLSX shared string item
+		"tab":        true,
+		"table-row":  true,
+		"line-break": true,
+	}
+)
+
+func (e OfficeXmlExtractor) Extract(reader io.Reader, mimeType string) (string, error) {
+
+	data, err := io.ReadAll(io.LimitReader(reader, e.MaxFileSize))
+	if err != nil {
+		return "", err
+	}
+
+	zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("not a zip container: %w", err)
+	}
+
+	var out strings.Builder
+	for _, name := range officeXmlTextParts(zipReader) {
+		file, err := zipReader.Open(name)
+		if err != nil {
+			return "", err
+		}
+		err = extractXmlText(file, &out)
+		file.Close()
+		if err != nil {
+			return "", fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+	}
+
+	if out.Len() == 0 {
+		return "", fmt.Errorf("no text content found")
+	}
+	return out.String(), nil
+}
+
+// officeXmlTextParts returns the names of the XML files containing document text, in document order
+func officeXmlTextParts(zipReader *zip.Reader) []string {
+
+	names := make([]string, 0)
+	for _, f := range zipReader.File {
+		name := f.Name
+		switch {
+		case name == "content.xml": //OpenDocument
+			names = append(names, name)
+		case name == "word/document.xml":
+			names = append(names, name)
+		case name == "xl/sharedStrings.xml":
+			names = append(names, name)
+		case strings.HasPrefix(name, "ppt/slides/") && path.Ext(name) == ".xml":
+			names = append(names, name)
+		}
+	}
+
+	slices.SortFunc(names, func(a, b string) int {
+		//ppt/slides/slide2.xml before ppt/slides/slide10.xml
+		if len(a) != len(b) {
+			return len(a) - len(b)
+		}
+		return strings.Compare(a, b)
+	})
+	return names
+}
+
+// extractXmlText writes all character data, adds line breaks after officeXmlBreakElements
+func extractXmlText(reader io.Reader, out *strings.Builder) error {
+
+	decoder := xml.NewDecoder(reader)
+	decoder.Strict = false
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.CharData:
+			out.Write(t)
+		case xml.EndElement:
+			if officeXmlBreakElements[t.Name.Local] {
+				if t.Name.Local == "tab" {
+					out.WriteByte('\t')
+				} else {
+					out.WriteByte('\n')
+				}
+			}
+		}
+	}
+}
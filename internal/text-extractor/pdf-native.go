@@ -0,0 +1,325 @@
+package text_extractor
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// NativePdfExtractor is a simple pure-Go text extractor.
+// It decodes uncompressed and FlateDecode content streams and collects text shown by Tj/TJ/'/" operators.
+// Fonts with custom encodings (CID fonts for example) are not supported, use PdfToTextExtractor if available.
+type NativePdfExtractor struct {
+	MaxFileSize int64
+}
+
+func NewNativePdfExtractor() *NativePdfExtractor {
+	e := &NativePdfExtractor{}
+	e.MaxFileSize = 1024 * 1024 * 100
+	return e
+}
+
+var (
+	pdfFontProgram        = regexp.MustCompile(`/Length[123]\s`)
+	pdfUnsupportedFilters = []string{"/DCTDecode", "/JPXDecode", "/CCITTFaxDecode", "/JBIG2Decode", "/LZWDecode", "/RunLengthDecode", "/ASCII85Decode", "/ASCIIHexDecode"}
+)
+
+func (e NativePdfExtractor) Extract(reader io.Reader, mimeType string) (string, error) {
+
+	data, err := io.ReadAll(io.LimitReader(reader, e.MaxFileSize))
+	if err != nil {
+		return "", err
+	}
+	if !bytes.HasPrefix(data, []byte("%PDF")) {
+		return "", fmt.Errorf("not a PDF file")
+	}
+
+	var out strings.Builder
+
+	pos := 0
+	for {
+		s := bytes.Index(data[pos:], []byte("stream"))
+		if s == -1 {
+			break
+		}
+		s += pos
+		pos = s + 6
+		if s >= 3 && string(data[s-3:s]) == "end" {
+			continue
+		}
+
+		dict := pdfStreamDictionary(data, s)
+
+		start := s + 6
+		if start < len(data) && data[start] == '\r' {
+			start++
+		}
+		if start < len(data) && data[start] == '\n' {
+			start++
+		}
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end == -1 {
+			break
+		}
+		pos = start + end + 9
+
+		content, ok := pdfDecodeStream(dict, data[start:start+end])
+		if !ok {
+			continue
+		}
+		if !bytes.Contains(content, []byte("BT")) {
+			continue
+		}
+
+		pdfExtractTextOperators(content, &out)
+	}
+
+	return out.String(), nil
+}
+
+// pdfStreamDictionary returns the object dictionary which precedes the stream keyword at pos
+func pdfStreamDictionary(data []byte, pos int) string {
+	from := pos - 2048
+	if from < 0 {
+		from = 0
+	}
+	dict := data[from:pos]
+	if p := bytes.LastIndex(dict, []byte("obj")); p > -1 {
+		dict = dict[p:]
+	}
+	return string(dict)
+}
+
+// pdfDecodeStream decodes stream data, returns false if encoding is not supported or stream is an image
+func pdfDecodeStream(dict string, raw []byte) ([]byte, bool) {
+
+	compact := strings.ReplaceAll(dict, " ", "")
+	if strings.Contains(compact, "/Subtype/Image") || strings.Contains(compact, "/Type/XRef") ||
+		pdfFontProgram.MatchString(dict) {
+		return nil, false
+	}
+	for _, f := range pdfUnsupportedFilters {
+		if strings.Contains(dict, f) {
+			return nil, false
+		}
+	}
+
+	if !strings.Contains(dict, "/FlateDecode") {
+		return raw, true
+	}
+
+	zr, err := zlib.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, false
+	}
+	defer zr.Close()
+
+	//Streams are often slightly broken at the end, use what was decoded so far
+	decoded, _ := io.ReadAll(zr)
+	return decoded, len(decoded) > 0
+}
+
+// pdfExtractTextOperators parses a content stream and writes text shown by text operators
+func pdfExtractTextOperators(content []byte, out *strings.Builder) {
+
+	var pending strings.Builder
+	numbers := make([]float64, 0, 8)
+	inArray := false
+
+	flush := func() {
+		if pending.Len() > 0 {
+			out.WriteString(pending.String())
+			pending.Reset()
+		}
+	}
+	newLine := func() {
+		s := out.String()
+		if len(s) > 0 && s[len(s)-1] != '\n' {
+			out.WriteByte('\n')
+		}
+	}
+	space := func() {
+		s := out.String()
+		if len(s) > 0 && s[len(s)-1] != '\n' && s[len(s)-1] != ' ' {
+			out.WriteByte(' ')
+		}
+	}
+
+	l := len(content)
+	for i := 0; i < l; {
+		c := content[i]
+		switch {
+		case c == '%':
+			for i < l && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case c == '(':
+			s, n := pdfLiteralString(content[i:])
+			pending.WriteString(pdfDecodeString(s))
+			i += n
+		case c == '<' && i+1 < l && content[i+1] == '<':
+			i += 2
+		case c == '>' && i+1 < l && content[i+1] == '>':
+			i += 2
+		case c == '<':
+			e := bytes.IndexByte(content[i:], '>')
+			if e == -1 {
+				return
+			}
+			pending.WriteString(pdfDecodeString(pdfHexString(content[i+1 : i+e])))
+			i += e + 1
+		case c == '[':
+			inArray = true
+			i++
+		case c == ']':
+			inArray = false
+			i++
+		case c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9'):
+			e := i + 1
+			for e < l && (content[e] == '.' || (content[e] >= '0' && content[e] <= '9')) {
+				e++
+			}
+			if f, err := strconv.ParseFloat(string(content[i:e]), 64); err == nil {
+				if inArray && f < -200 {
+					pending.WriteByte(' ')
+				}
+				numbers = append(numbers, f)
+				if len(numbers) > 6 {
+					numbers = numbers[1:]
+				}
+			}
+			i = e
+		case c == '\'' || c == '"':
+			newLine()
+			flush()
+			i++
+		case unicode.IsLetter(rune(c)) || c == '*':
+			e := i + 1
+			for e < l && (unicode.IsLetter(rune(content[e])) || content[e] == '*') {
+				e++
+			}
+			switch string(content[i:e]) {
+			case "Tj", "TJ":
+				flush()
+			case "Td", "TD":
+				if len(numbers) > 0 && numbers[len(numbers)-1] != 0 {
+					newLine()
+				} else {
+					space()
+				}
+			case "T*", "ET":
+				newLine()
+			default:
+				pending.Reset()
+			}
+			numbers = numbers[:0]
+			i = e
+		default:
+			i++
+		}
+	}
+	newLine()
+}
+
+// pdfLiteralString parses (...) with nested parentheses and escapes, returns bytes and length consumed
+func pdfLiteralString(data []byte) ([]byte, int) {
+
+	var buf bytes.Buffer
+	depth := 0
+	i := 0
+	for ; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c == '\\' && i+1 < len(data):
+			i++
+			n := data[i]
+			switch {
+			case n == 'n':
+				buf.WriteByte('\n')
+			case n == 'r':
+				buf.WriteByte('\r')
+			case n == 't':
+				buf.WriteByte('\t')
+			case n == 'b', n == 'f', n == '\r', n == '\n':
+			case n >= '0' && n <= '7':
+				e := i
+				for e < len(data) && e < i+3 && data[e] >= '0' && data[e] <= '7' {
+					e++
+				}
+				v, _ := strconv.ParseUint(string(data[i:e]), 8, 8)
+				buf.WriteByte(byte(v))
+				i = e - 1
+			default:
+				buf.WriteByte(n)
+			}
+		case c == '(':
+			if depth > 0 {
+				buf.WriteByte(c)
+			}
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return buf.Bytes(), i + 1
+			}
+			buf.WriteByte(c)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return buf.Bytes(), i
+}
+
+// pdfHexString decodes <...>
+func pdfHexString(data []byte) []byte {
+
+	hex := make([]byte, 0, len(data))
+	for _, c := range data {
+		if (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F') {
+			hex = append(hex, c)
+		}
+	}
+	if len(hex)%2 == 1 {
+		hex = append(hex, '0')
+	}
+
+	decoded := make([]byte, len(hex)/2)
+	for i := range decoded {
+		v, _ := strconv.ParseUint(string(hex[i*2:i*2+2]), 16, 8)
+		decoded[i] = byte(v)
+	}
+	return decoded
+}
+
+// pdfDecodeString converts UTF-16BE (with BOM) or PDFDocEncoding (approximated by ISO-8859-1),
+// removes non-printable characters
+func pdfDecodeString(data []byte) string {
+
+	var runes []rune
+	if len(data) >= 2 && data[0] == 0xfe && data[1] == 0xff {
+		u := make([]uint16, 0, len(data)/2)
+		for i := 2; i+1 < len(data); i += 2 {
+			u = append(u, uint16(data[i])<<8|uint16(data[i+1]))
+		}
+		runes = utf16.Decode(u)
+	} else {
+		runes = make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+	}
+
+	var sb strings.Builder
+	for _, r := range runes {
+		if unicode.IsPrint(r) || r == '\n' || r == '\t' {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,37 @@
+package text_extractor
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	filter_commands "github.com/c8121/asset-storage/internal/filter-commands"
+)
+
+type PdfToTextExtractor struct {
+}
+
+func NewPdfToTextExtractor() *PdfToTextExtractor {
+	return &PdfToTextExtractor{}
+}
+
+// Extract executes pdftotext (poppler-utils), reading from stdin and writing to stdout
+func (e PdfToTextExtractor) Extract(reader io.Reader, mimeType string) (string, error) {
+
+	binary := filter_commands.FindPdfToTextBin()
+	if binary == "" {
+		return "", fmt.Errorf("pdftotext not found (searching in %v)", filter_commands.PdfToTextBinPaths)
+	}
+
+	cmd := exec.Command(binary, "-q", "-enc", "UTF-8", "-", "-")
+	var o, errOut strings.Builder
+	cmd.Stdin = reader
+	cmd.Stdout = &o
+	cmd.Stderr = &errOut
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("pdftotext failed: %w %s", err, errOut.String())
+	}
+
+	return o.String(), nil
+}
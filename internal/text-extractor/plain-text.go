@@ -0,0 +1,49 @@
+package text_extractor
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+type PlainTextExtractor struct {
+}
+
+func NewPlainTextExtractor() *PlainTextExtractor {
+	return &PlainTextExtractor{}
+}
+
+func (e PlainTextExtractor) Extract(reader io.Reader, mimeType string) (string, error) {
+
+	buf, err := io.ReadAll(io.LimitReader(reader, int64(MaxTextLength)))
+	if err != nil {
+		return "", err
+	}
+
+	buf = bytes.TrimPrefix(buf, []byte("\xef\xbb\xbf"))
+	if text := trimIncompleteRune(buf); utf8.Valid(text) {
+		return string(text), nil
+	}
+
+	//Not UTF-8: Assume ISO-8859-1
+	var sb strings.Builder
+	sb.Grow(len(buf))
+	for _, b := range buf {
+		sb.WriteRune(rune(b))
+	}
+	return sb.String(), nil
+}
+
+// trimIncompleteRune removes the last rune if it is incomplete (cut by MaxTextLength)
+func trimIncompleteRune(buf []byte) []byte {
+	for i := len(buf) - 1; i >= 0 && i >= len(buf)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(buf[i]) {
+			if !utf8.FullRune(buf[i:]) {
+				return buf[:i]
+			}
+			break
+		}
+	}
+	return buf
+}
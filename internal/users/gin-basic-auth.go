@@ -59,3 +59,12 @@ func RespondBasicAuthRequired(c *gin.Context) {
 	c.Header("WWW-Authenticate", "Basic realm=\"Asset Storage\", charset=\"UTF-8\"")
 	c.String(http.StatusUnauthorized, "Unauthorized")
 }
+
+// GetUsername returns the name of the authenticated user, empty if not available
+func GetUsername(c *gin.Context) string {
+	username, _, ok := c.Request.BasicAuth()
+	if !ok {
+		return ""
+	}
+	return username
+}
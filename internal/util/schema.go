@@ -0,0 +1,58 @@
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// JsonUpgrade converts a JSON object (field name -> value) from one schema version to the next.
+// path is the file the object was read from.
+type JsonUpgrade func(doc map[string]json.RawMessage, path string) error
+
+// SchemaVersionField is the field of a JSON object containing its schema version, 0 if missing
+const SchemaVersionField = "SchemaVersion"
+
+var ErrJsonSchemaTooNew = errors.New("JSON schema version is newer than supported")
+
+// UnmarshalOrZero keeps the zero value if value is empty (field missing) or null
+func UnmarshalOrZero(value json.RawMessage, v any) error {
+	if len(value) == 0 {
+		return nil
+	}
+	return json.Unmarshal(value, v)
+}
+
+// UpgradeJson converts a JSON object to the latest version by applying all upgrades required,
+// upgrades[i] converts version i to i+1. Returns buf and false if the object is up to date.
+func UpgradeJson(buf []byte, path string, upgrades []JsonUpgrade) ([]byte, bool, error) {
+
+	var versioned struct {
+		SchemaVersion int
+	}
+	if err := json.Unmarshal(buf, &versioned); err != nil {
+		return nil, false, err
+	}
+
+	latest := len(upgrades)
+	if versioned.SchemaVersion > latest {
+		return nil, false, fmt.Errorf("%w: %d (supported: %d)", ErrJsonSchemaTooNew, versioned.SchemaVersion, latest)
+	} else if versioned.SchemaVersion == latest {
+		return buf, false, nil
+	}
+
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(buf, &doc); err != nil {
+		return nil, false, err
+	}
+
+	for version := versioned.SchemaVersion; version < latest; version++ {
+		if err := upgrades[version](doc, path); err != nil {
+			return nil, false, fmt.Errorf("upgrade to version %d failed: %w", version+1, err)
+		}
+	}
+	doc[SchemaVersionField] = json.RawMessage(fmt.Sprintf("%d", latest))
+
+	buf, err := json.Marshal(doc)
+	return buf, true, err
+}
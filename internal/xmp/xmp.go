@@ -0,0 +1,258 @@
+package xmp
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/c8121/asset-storage/internal/metadata"
+	"github.com/c8121/asset-storage/internal/util"
+)
+
+/*
+	XMP sidecars are written by photo tools (Lightroom, darktable, digiKam...) next to the image:
+	file.CR2 -> file.xmp (or file.CR2.xmp).
+*/
+
+// Xmp contains the XMP fields used by asset-storage
+type Xmp struct {
+	Title       string
+	Description string
+	Keywords    []string
+	Rating      int //0-metadata.MaxRating, 0 if not rated or rejected
+}
+
+const (
+	Extension = ".xmp"
+
+	nsRdf = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	nsDc  = "http://purl.org/dc/elements/1.1/"
+	nsXmp = "http://ns.adobe.com/xap/1.0/"
+)
+
+var ErrNoXmp = errors.New("no XMP data found")
+
+// FindSidecar returns the path of the XMP sidecar of a file, empty if not found or path is a XMP file
+func FindSidecar(path string) string {
+
+	if strings.EqualFold(filepath.Ext(path), Extension) {
+		return ""
+	}
+
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	for _, candidate := range []string{base + Extension, base + strings.ToUpper(Extension), path + Extension, path + strings.ToUpper(Extension)} {
+		if stat, err := os.Stat(candidate); err == nil && !stat.IsDir() {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// IsSidecar checks if the file is the XMP sidecar of another file (see FindSidecar), which should not be added as asset
+func IsSidecar(path string) bool {
+
+	if !strings.EqualFold(filepath.Ext(path), Extension) {
+		return false
+	}
+
+	//file.CR2.xmp
+	media := strings.TrimSuffix(path, filepath.Ext(path))
+	if stat, err := os.Stat(media); err == nil && !stat.IsDir() {
+		return true
+	}
+
+	//file.xmp
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return false
+	}
+	base := filepath.Base(media)
+	for _, e := range entries {
+		name := e.Name()
+		if !e.IsDir() && !strings.EqualFold(filepath.Ext(name), Extension) && strings.TrimSuffix(name, filepath.Ext(name)) == base {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadFile reads an XMP sidecar
+func ReadFile(path string) (*Xmp, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer util.CloseOrLog(file)
+	return Read(file)
+}
+
+// Read parses title, description, keywords and rating of XMP data
+func Read(reader io.Reader) (*Xmp, error) {
+
+	result := &Xmp{}
+	found := false
+
+	//Elements from document root to current element
+	var stack []xml.Name
+	var text strings.Builder
+
+	decoder := xml.NewDecoder(reader)
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			stack = append(stack, t.Name)
+			text.Reset()
+			if t.Name.Space == nsRdf && t.Name.Local == "Description" {
+				found = true
+				//Simple values can be attributes of rdf:Description
+				for _, attr := range t.Attr {
+					result.set(attr.Name, attr.Value)
+				}
+			}
+
+		case xml.CharData:
+			text.Write(t)
+
+		case xml.EndElement:
+			value := strings.TrimSpace(text.String())
+			text.Reset()
+			if t.Name.Space == nsRdf && t.Name.Local == "li" && len(stack) >= 3 {
+				//dc:title/rdf:Alt/rdf:li
+				result.set(stack[len(stack)-3], value)
+			} else {
+				result.set(t.Name, value)
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	if !found {
+		return nil, ErrNoXmp
+	}
+	return result, nil
+}
+
+// set a field by XMP property name, ignores unknown properties and empty values
+func (x *Xmp) set(name xml.Name, value string) {
+
+	if value == "" {
+		return
+	}
+
+	switch {
+	case name.Space == nsDc && name.Local == "title" && x.Title == "":
+		x.Title = value
+	case name.Space == nsDc && name.Local == "description" && x.Description == "":
+		x.Description = value
+	case name.Space == nsDc && name.Local == "subject":
+		x.Keywords = append(x.Keywords, value)
+	case name.Space == nsXmp && name.Local == "Rating":
+		//-1 is rejected
+		if rating, err := strconv.ParseFloat(value, 64); err == nil {
+			x.Rating = min(max(int(rating), 0), metadata.MaxRating)
+		}
+	}
+}
+
+// Write creates an XMP sidecar
+func Write(writer io.Writer, x *Xmp) error {
+
+	var sb strings.Builder
+	sb.WriteString("<?xpacket begin=\"\uFEFF\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	sb.WriteString("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n")
+	sb.WriteString(" <rdf:RDF xmlns:rdf=\"" + nsRdf + "\">\n")
+	sb.WriteString("  <rdf:Description rdf:about=\"\" xmlns:dc=\"" + nsDc + "\" xmlns:xmp=\"" + nsXmp + "\"")
+	if x.Rating > 0 {
+		sb.WriteString(fmt.Sprintf(" xmp:Rating=\"%d\"", x.Rating))
+	}
+	sb.WriteString(">\n")
+
+	if x.Title != "" {
+		sb.WriteString("   <dc:title><rdf:Alt><rdf:li xml:lang=\"x-default\">" + escape(x.Title) + "</rdf:li></rdf:Alt></dc:title>\n")
+	}
+	if x.Description != "" {
+		sb.WriteString("   <dc:description><rdf:Alt><rdf:li xml:lang=\"x-default\">" + escape(x.Description) + "</rdf:li></rdf:Alt></dc:description>\n")
+	}
+	if len(x.Keywords) > 0 {
+		sb.WriteString("   <dc:subject><rdf:Bag>")
+		for _, keyword := range x.Keywords {
+			sb.WriteString("<rdf:li>" + escape(keyword) + "</rdf:li>")
+		}
+		sb.WriteString("</rdf:Bag></dc:subject>\n")
+	}
+
+	sb.WriteString("  </rdf:Description>\n")
+	sb.WriteString(" </rdf:RDF>\n")
+	sb.WriteString("</x:xmpmeta>\n")
+	sb.WriteString("<?xpacket end=\"w\"?>\n")
+
+	_, err := io.WriteString(writer, sb.String())
+	return err
+}
+
+// FromMetaData creates XMP data of tags, rating, title and description
+func FromMetaData(meta *metadata.JsonAssetMetaData) *Xmp {
+	return &Xmp{
+		Title:       meta.Title,
+		Description: meta.Description,
+		Keywords:    meta.Tags,
+		Rating:      meta.Rating,
+	}
+}
+
+// Apply adds keywords to tags, sets title, description and rating if not set before,
+// see metadata.UpdateMetaData. Returns the meta-data (saved if changed).
+func Apply(hash string, x *Xmp) (*metadata.JsonAssetMetaData, error) {
+
+	return metadata.UpdateMetaDataWith(hash, func(meta *metadata.JsonAssetMetaData) map[string]json.RawMessage {
+
+		changes := make(map[string]json.RawMessage)
+		tags := append([]string{}, meta.Tags...)
+		for _, keyword := range x.Keywords {
+			if !containsFold(tags, keyword) {
+				tags = append(tags, keyword)
+			}
+		}
+		if len(tags) > len(meta.Tags) {
+			changes["Tags"], _ = json.Marshal(tags)
+		}
+		if meta.Title == "" && x.Title != "" {
+			changes["Title"], _ = json.Marshal(x.Title)
+		}
+		if meta.Description == "" && x.Description != "" {
+			changes["Description"], _ = json.Marshal(x.Description)
+		}
+		if meta.Rating == 0 && x.Rating > 0 && x.Rating <= metadata.MaxRating {
+			changes["Rating"], _ = json.Marshal(x.Rating)
+		}
+		return changes
+	}, metadata.SystemUser)
+}
+
+func escape(s string) string {
+	var sb strings.Builder
+	_ = xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package comments_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/c8121/asset-storage/internal/config"
+	"github.com/c8121/asset-storage/internal/metadata"
+	test_util "github.com/c8121/asset-storage/test/test-util"
+)
+
+const testHash = "ef0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcd"
+
+func TestComments(t *testing.T) {
+
+	config.AssetMetaDataBaseDir = t.TempDir()
+
+	test_util.CreateDb(t)
+
+	meta, err := metadata.AddMetaData(testHash, "image/jpeg", 1000, "a.jpg", "/home/anna", "anna", time.Now())
+	if err != nil {
+		t.Fatalf("AddMetaData failed: %s", err)
+	}
+	test_util.UpdateDb(t, meta)
+
+	//Title and description
+	meta, err = metadata.UpdateMetaData(testHash, map[string]json.RawMessage{
+		"Title":       json.RawMessage(`" Sunset "`),
+		"Description": json.RawMessage(`"Evening at the lighthouse"`),
+	}, "anna")
+	if err != nil || meta.Title != "Sunset" {
+		t.Fatalf("UpdateMetaData failed: %v, %v", meta, err)
+	}
+	test_util.UpdateDb(t, meta)
+	test_util.ExpectCount(t, "sunset", 1)
+	test_util.ExpectCount(t, "lighthouse", 1)
+
+	//Thread
+	_, first, err := metadata.AddComment(testHash, 0, "Where was this taken?", "bob")
+	if err != nil || first.Id != 1 || first.Author != "bob" {
+		t.Fatalf("AddComment failed: %v, %v", first, err)
+	}
+	meta, reply, err := metadata.AddComment(testHash, first.Id, "  Cabo da Roca  ", "anna")
+	if err != nil || reply.Id != 2 || reply.Parent != first.Id || reply.Text != "Cabo da Roca" {
+		t.Fatalf("AddComment failed: %v, %v", reply, err)
+	}
+	test_util.UpdateDb(t, meta)
+	test_util.ExpectCount(t, "cabo roca", 1)
+
+	if _, _, err = metadata.AddComment(testHash, 99, "Reply", "anna"); !errors.Is(err, metadata.ErrCommentNotFound) {
+		t.Errorf("Expected ErrCommentNotFound, got %v", err)
+	}
+	if _, _, err = metadata.AddComment(testHash, 0, " ", "anna"); err == nil {
+		t.Errorf("Expected error for empty comment")
+	}
+
+	//Only the author can change or delete
+	if _, _, err = metadata.UpdateComment(testHash, reply.Id, "Lisbon", "bob"); !errors.Is(err, metadata.ErrNotAuthor) {
+		t.Errorf("Expected ErrNotAuthor, got %v", err)
+	}
+	meta, changed, err := metadata.UpdateComment(testHash, reply.Id, "Near Lisbon", "anna")
+	if err != nil || changed.Text != "Near Lisbon" || changed.Edited.IsZero() {
+		t.Fatalf("UpdateComment failed: %v, %v", changed, err)
+	}
+	test_util.UpdateDb(t, meta)
+	test_util.ExpectCount(t, "cabo", 0)
+	test_util.ExpectCount(t, "lisbon", 1)
+
+	if _, err = metadata.DeleteComment(testHash, first.Id, "anna"); !errors.Is(err, metadata.ErrNotAuthor) {
+		t.Errorf("Expected ErrNotAuthor, got %v", err)
+	}
+
+	//Comment with reply is kept without text
+	meta, err = metadata.DeleteComment(testHash, first.Id, "bob")
+	if err != nil || len(meta.Comments) != 2 || !meta.Comments[0].Deleted || meta.Comments[0].Text != "" {
+		t.Fatalf("DeleteComment failed: %v, %v", meta, err)
+	}
+	meta, err = metadata.DeleteComment(testHash, reply.Id, "anna")
+	if err != nil || len(meta.Comments) != 1 {
+		t.Fatalf("DeleteComment failed: %v, %v", meta, err)
+	}
+	test_util.UpdateDb(t, meta)
+	test_util.ExpectCount(t, "lisbon", 0)
+
+	//Ids of deleted comments are not reused
+	if _, next, err := metadata.AddComment(testHash, 0, "Nice", "bob"); err != nil || next.Id != 3 {
+		t.Errorf("Unexpected comment: %v, %v", next, err)
+	}
+
+	history, err := metadata.LoadHistory(testHash)
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %s", err)
+	}
+	counts := make(map[string]int)
+	for _, entry := range history {
+		counts[entry.Action]++
+	}
+	if counts[metadata.HistoryCommentAdded] != 3 || counts[metadata.HistoryCommentChanged] != 1 || counts[metadata.HistoryCommentDeleted] != 2 {
+		t.Errorf("Unexpected history: %v", counts)
+	}
+}
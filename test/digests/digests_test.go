@@ -0,0 +1,96 @@
+package digests_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/c8121/asset-storage/internal/config"
+	"github.com/c8121/asset-storage/internal/metadata"
+	metadata_db "github.com/c8121/asset-storage/internal/metadata-db"
+	"github.com/c8121/asset-storage/internal/storage"
+	"github.com/c8121/asset-storage/internal/util"
+	test_util "github.com/c8121/asset-storage/test/test-util"
+)
+
+const (
+	content = "hello world\n"
+	md5     = "6f5902ac237024bdd0c176cb93063dc4"
+	sha1    = "22596363b3de40b06f981fb85d82312e8c0ed511"
+	sha256  = "a948904f2f0f479b8f8197694b30184b0d2ed1c1cd2a1ec0fb85d299a192a447"
+)
+
+func TestDigests(t *testing.T) {
+
+	config.AssetStorageBaseDir = t.TempDir()
+	config.AssetStorageTempDir = t.TempDir()
+	config.AssetMetaDataBaseDir = t.TempDir()
+	storage.CreateDirectories()
+
+	test_util.CreateDb(t)
+
+	path := filepath.Join(t.TempDir(), "hello.txt")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	//Digests are calculated while adding
+	infos, err := storage.AddFile(path)
+	if err != nil || len(infos) != 1 || infos[0].Hash != sha256 {
+		t.Fatalf("AddFile failed: %+v, %v", infos, err)
+	}
+	meta, err := metadata.AddFileMetaData(&infos[0], "hello.txt", "/tmp", "anna", time.Now())
+	if err != nil {
+		t.Fatalf("AddFileMetaData failed: %s", err)
+	}
+	if meta.Digests[storage.DigestMD5] != md5 || meta.Digests[storage.DigestSHA1] != sha1 || len(meta.Digests[storage.DigestBLAKE3]) != 64 {
+		t.Fatalf("Unexpected digests: %v", meta.Digests)
+	}
+	test_util.UpdateDb(t, meta)
+
+	//Stored content has the same digests
+	reader, err := storage.Open(sha256)
+	if err != nil {
+		t.Fatalf("Open failed: %s", err)
+	}
+	digests, err := storage.ComputeDigests(reader)
+	util.CloseOrLog(reader)
+	if err != nil || digests[storage.DigestBLAKE3] != meta.Digests[storage.DigestBLAKE3] {
+		t.Errorf("Unexpected digests of content: %v, %v", digests, err)
+	}
+
+	//Lookup by any digest, case-insensitive
+	for algorithm, digest := range map[string]string{
+		storage.DigestMD5:    strings.ToUpper(md5),
+		storage.DigestSHA1:   sha1,
+		storage.DigestSHA256: sha256,
+		storage.DigestBLAKE3: meta.Digests[storage.DigestBLAKE3],
+	} {
+		items, err := metadata_db.FindByDigest(algorithm, digest)
+		if err != nil || len(items) != 1 || items[0].Hash != sha256 || items[0].Name != "hello.txt" {
+			t.Errorf("FindByDigest(%s) failed: %+v, %v", algorithm, items, err)
+		}
+	}
+
+	if items, err := metadata_db.FindByDigest(storage.DigestMD5, "d41d8cd98f00b204e9800998ecf8427e"); err != nil || len(items) != 0 {
+		t.Errorf("Expected no result: %+v, %v", items, err)
+	}
+	if _, err = metadata_db.FindByDigest("crc32", "12345678"); err == nil {
+		t.Errorf("Expected error for unknown algorithm")
+	}
+	if _, err = metadata_db.FindByDigest(storage.DigestSHA1, md5); err == nil {
+		t.Errorf("Expected error for invalid digest")
+	}
+
+	//Meta-data without digests (added before) gets them when the file is added again
+	meta.Digests = nil
+	if err = meta.Save(metadata.GetMetaDataFilePath(sha256)); err != nil {
+		t.Fatal(err)
+	}
+	meta, err = metadata.AddFileMetaData(&infos[0], "hello.txt", "/home/anna", "anna", time.Now())
+	if err != nil || meta.Digests[storage.DigestMD5] != md5 {
+		t.Errorf("Expected digests to be added: %v, %v", meta, err)
+	}
+}
@@ -0,0 +1,241 @@
+package exif_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/c8121/asset-storage/internal/exif"
+)
+
+// createTiff creates a TIFF structure: IFD0 with DateTime and pointer to Exif-IFD with DateTimeOriginal
+func createTiff(order binary.ByteOrder, dateTime string, dateTimeOriginal string, offset string) []byte {
+
+	var buf bytes.Buffer
+	w := func(v any) { _ = binary.Write(&buf, order, v) }
+
+	if order == binary.LittleEndian {
+		buf.WriteString("II")
+	} else {
+		buf.WriteString("MM")
+	}
+	w(uint16(42))
+	w(uint32(8))
+
+	//IFD0 at 8: 2 entries -> 2 + 24 + 4 = 30 bytes, Exif-IFD at 38
+	exifIfd := uint32(38)
+	exifEntries := uint16(1)
+	if offset != "" {
+		exifEntries = 2
+	}
+	//Exif-IFD: 2 + n*12 + 4
+	data := exifIfd + 2 + uint32(exifEntries)*12 + 4
+
+	w(uint16(2))
+	w(uint16(0x0132))
+	w(uint16(2))
+	w(uint32(20))
+	w(data)
+	w(uint16(0x8769))
+	w(uint16(4))
+	w(uint32(1))
+	w(exifIfd)
+	w(uint32(0))
+
+	w(exifEntries)
+	w(uint16(0x9003))
+	w(uint16(2))
+	w(uint32(20))
+	w(data + 20)
+	if offset != "" {
+		w(uint16(0x9011))
+		w(uint16(2))
+		w(uint32(7))
+		w(data + 40)
+	}
+	w(uint32(0))
+
+	buf.WriteString(dateTime + "\x00")
+	buf.WriteString(dateTimeOriginal + "\x00")
+	if offset != "" {
+		buf.WriteString(offset + "\x00")
+	}
+	return buf.Bytes()
+}
+
+func createJpeg(tiff []byte) []byte {
+	var buf bytes.Buffer
+	buf.Write([]byte{0xff, 0xd8})
+	//Some other APP segment before
+	buf.Write([]byte{0xff, 0xe0, 0x00, 0x04, 'J', 'F'})
+	buf.Write([]byte{0xff, 0xe1})
+	_ = binary.Write(&buf, binary.BigEndian, uint16(2+6+len(tiff)))
+	buf.WriteString("Exif\x00\x00")
+	buf.Write(tiff)
+	buf.Write([]byte{0xff, 0xda, 0x00, 0x02, 0xff, 0xd9})
+	return buf.Bytes()
+}
+
+func createPng(tiff []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("\x89PNG\r\n\x1a\n")
+	_ = binary.Write(&buf, binary.BigEndian, uint32(len(tiff)))
+	buf.WriteString("eXIf")
+	buf.Write(tiff)
+	buf.Write([]byte{0, 0, 0, 0})
+	return buf.Bytes()
+}
+
+func createWebp(tiff []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(0))
+	buf.WriteString("WEBP")
+	buf.WriteString("VP8X")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(10))
+	buf.Write(make([]byte, 10))
+	buf.WriteString("EXIF")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(len(tiff)))
+	buf.Write(tiff)
+	return buf.Bytes()
+}
+
+func TestRead(t *testing.T) {
+
+	expected := time.Date(2019, 7, 14, 18, 30, 5, 0, time.Local)
+
+	tiffLE := createTiff(binary.LittleEndian, "2020:01:01 10:00:00", "2019:07:14 18:30:05", "")
+	tiffBE := createTiff(binary.BigEndian, "2020:01:01 10:00:00", "2019:07:14 18:30:05", "")
+
+	files := map[string][]byte{
+		"jpeg":    createJpeg(tiffLE),
+		"jpeg-be": createJpeg(tiffBE),
+		"tiff":    tiffLE,
+		"png":     createPng(tiffBE),
+		"webp":    createWebp(tiffLE),
+	}
+
+	for name, data := range files {
+		info, err := exif.Read(bytes.NewReader(data))
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+		if !info.DateTimeOriginal.Equal(expected) {
+			t.Errorf("%s: expected %s, got %s", name, expected, info.DateTimeOriginal)
+		}
+		if info.DateTime.Year() != 2020 {
+			t.Errorf("%s: unexpected DateTime %s", name, info.DateTime)
+		}
+	}
+}
+
+func TestReadWithOffset(t *testing.T) {
+
+	info, err := exif.Read(bytes.NewReader(createJpeg(
+		createTiff(binary.LittleEndian, "2020:01:01 10:00:00", "2019:07:14 18:30:05", "+02:00"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := time.Date(2019, 7, 14, 16, 30, 5, 0, time.UTC)
+	if !info.DateTimeOriginal.Equal(expected) {
+		t.Errorf("Expected %s, got %s", expected, info.DateTimeOriginal)
+	}
+}
+
+func TestNoExif(t *testing.T) {
+
+	inputs := [][]byte{
+		{0xff, 0xd8, 0xff, 0xda, 0x00, 0x02},
+		[]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x00IEND\x00\x00\x00\x00"),
+		[]byte("plain text"),
+		{0xff, 0xd8, 0xff, 0xe1, 0xff, 0xff, 'E'},
+	}
+
+	for i, data := range inputs {
+		if _, err := exif.Read(bytes.NewReader(data)); !errors.Is(err, exif.ErrNoExif) {
+			t.Errorf("Input %d: expected ErrNoExif, got %v", i, err)
+		}
+	}
+}
+
+// createGpsTiff creates a TIFF structure: IFD0 with pointer to GPS-IFD with latitude and longitude
+func createGpsTiff(order binary.ByteOrder, latRef string, lat [3]uint32, lonRef string, lon [3]uint32) []byte {
+
+	var buf bytes.Buffer
+	w := func(v any) { _ = binary.Write(&buf, order, v) }
+
+	if order == binary.LittleEndian {
+		buf.WriteString("II")
+	} else {
+		buf.WriteString("MM")
+	}
+	w(uint16(42))
+	w(uint32(8))
+
+	//IFD0 at 8: 1 entry -> 2 + 12 + 4 = 18 bytes, GPS-IFD at 26: 4 entries -> 2 + 48 + 4 = 54 bytes, rationals at 80
+	gpsIfd := uint32(26)
+	data := uint32(80)
+
+	w(uint16(1))
+	w(uint16(0x8825))
+	w(uint16(4))
+	w(uint32(1))
+	w(gpsIfd)
+	w(uint32(0))
+
+	w(uint16(4))
+	w(uint16(0x0001))
+	w(uint16(2))
+	w(uint32(2))
+	buf.WriteString(latRef + "\x00\x00\x00")
+	w(uint16(0x0002))
+	w(uint16(5))
+	w(uint32(3))
+	w(data)
+	w(uint16(0x0003))
+	w(uint16(2))
+	w(uint32(2))
+	buf.WriteString(lonRef + "\x00\x00\x00")
+	w(uint16(0x0004))
+	w(uint16(5))
+	w(uint32(3))
+	w(data + 24)
+	w(uint32(0))
+
+	//Degrees, minutes, seconds*100
+	for _, v := range [][3]uint32{lat, lon} {
+		w(v[0])
+		w(uint32(1))
+		w(v[1])
+		w(uint32(1))
+		w(v[2])
+		w(uint32(100))
+	}
+	return buf.Bytes()
+}
+
+func TestReadLocation(t *testing.T) {
+
+	//Lisbon: 38°43'20.28"N 9°8'21.48"W
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		info, err := exif.Read(bytes.NewReader(createJpeg(
+			createGpsTiff(order, "N", [3]uint32{38, 43, 2028}, "W", [3]uint32{9, 8, 2148}))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !info.HasLocation || math.Abs(info.Latitude-38.7223) > 0.0001 || math.Abs(info.Longitude+9.1393) > 0.0001 {
+			t.Errorf("Unexpected location %v %f %f", info.HasLocation, info.Latitude, info.Longitude)
+		}
+	}
+
+	info, err := exif.Read(bytes.NewReader(createJpeg(
+		createGpsTiff(binary.LittleEndian, "N", [3]uint32{0, 0, 0}, "E", [3]uint32{0, 0, 0}))))
+	if err != nil || info.HasLocation {
+		t.Errorf("Expected no location for 0,0, got %v (%v)", info, err)
+	}
+}
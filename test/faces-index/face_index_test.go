@@ -0,0 +1,214 @@
+package faces_index_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/c8121/asset-storage/internal/config"
+	"github.com/c8121/asset-storage/internal/faces"
+	"github.com/c8121/asset-storage/internal/metadata"
+	metadata_db "github.com/c8121/asset-storage/internal/metadata-db"
+	"github.com/c8121/asset-storage/internal/util"
+	test_util "github.com/c8121/asset-storage/test/test-util"
+)
+
+func TestSearchRecall(t *testing.T) {
+
+	random := rand.New(rand.NewSource(1))
+	embeddings := make([]faces.Embedding, 2000)
+	index := faces.NewFaceIndex()
+	for i := range embeddings {
+		embeddings[i] = randomEmbedding(random, 32)
+		index.Add(fmt.Sprintf("face/%d", i), embeddings[i])
+	}
+	if index.Add("face/0", embeddings[0]) || index.Len() != len(embeddings) {
+		t.Fatalf("Expected existing face to be skipped, got %d faces", index.Len())
+	}
+
+	//Compare with exact nearest neighbours
+	const k = 10
+	found := 0
+	for q := 0; q < 50; q++ {
+		query := randomEmbedding(random, 32)
+		exact := make([]int, len(embeddings))
+		for i := range exact {
+			exact[i] = i
+		}
+		sort.Slice(exact, func(a, b int) bool {
+			return faces.CosineSimilarity(query, embeddings[exact[a]]) > faces.CosineSimilarity(query, embeddings[exact[b]])
+		})
+
+		matches := index.Search(query, k)
+		if len(matches) != k {
+			t.Fatalf("Expected %d matches, got %d", k, len(matches))
+		}
+		for i := 1; i < k; i++ {
+			if matches[i].Similarity > matches[i-1].Similarity {
+				t.Fatalf("Expected most similar first, got %+v", matches)
+			}
+		}
+		for _, i := range exact[:k] {
+			for _, match := range matches {
+				if match.Face == fmt.Sprintf("face/%d", i) {
+					found++
+				}
+			}
+		}
+	}
+	if recall := float64(found) / (50 * k); recall < 0.9 {
+		t.Errorf("Expected recall of at least 0.9, got %f", recall)
+	}
+
+	matches := index.SearchFace("face/7", 5)
+	if len(matches) != 5 || matches[0].Face == "face/7" {
+		t.Errorf("Expected 5 matches without the face itself, got %+v", matches)
+	}
+	if index.SearchFace("unknown/0", 5) != nil {
+		t.Errorf("Expected no matches for unknown face")
+	}
+}
+
+func TestFaceIndexFiles(t *testing.T) {
+
+	config.AssetMetaDataBaseDir = t.TempDir()
+	config.AssetFacesBaseDir = t.TempDir()
+
+	db := test_util.CreateDb(t)
+
+	for i := 1; i <= 5; i++ {
+		meta, err := metadata.AddMetaData(hash(i), "image/jpeg", 1000, fmt.Sprintf("IMG_%d.jpg", i), "/photos", "anna", time.Now())
+		if err != nil {
+			t.Fatalf("AddMetaData failed: %s", err)
+		}
+		test_util.UpdateDb(t, meta)
+	}
+
+	//Without index, face similarities in database are used
+	if _, err := faces.FindSimilarFaces(face(1, 0), 10, 0.5); !errors.Is(err, faces.ErrNoFaceIndex) {
+		t.Errorf("Expected ErrNoFaceIndex, got %v", err)
+	}
+	expectAssets(t, &metadata_db.AssetListFilter{Face: face(1, 0)}, 1)
+
+	writeEmbedding(t, 1, 0, faces.Embedding{1, 0, 0})
+	writeEmbedding(t, 2, 0, faces.Embedding{0.9, 0.1, 0})
+	writeEmbedding(t, 2, 1, faces.Embedding{0, 1, 0})
+	writeEmbedding(t, 3, 0, faces.Embedding{0, 0, 1})
+
+	index, added, err := faces.UpdateFaceIndex()
+	if err != nil || added != 4 || index.Len() != 4 {
+		t.Fatalf("UpdateFaceIndex failed: %d added, %v", added, err)
+	}
+	if _, added, _ = faces.UpdateFaceIndex(); added != 0 {
+		t.Errorf("Expected no faces added again, got %d", added)
+	}
+	if _, err = os.Stat(filepath.Join(config.AssetFacesBaseDir, faces.FaceIndexFileName)); err != nil {
+		t.Errorf("Expected index file: %s", err)
+	}
+
+	matches, err := faces.FindSimilarFaces(face(1, 0), 10, 0.5)
+	if err != nil || len(matches) != 1 || matches[0].Face != face(2, 0) {
+		t.Errorf("Expected %s, got %+v, %v", face(2, 0), matches, err)
+	}
+	expectAssets(t, &metadata_db.AssetListFilter{Face: face(1, 0)}, 1, 2)
+
+	//Faces extracted by another process are logged and added when the index is used
+	writeEmbedding(t, 4, 0, faces.Embedding{1, 0.05, 0})
+	logFace(t, 4, 0, faces.Embedding{1, 0.05, 0})
+
+	matches, err = faces.FindSimilarFaces(face(1, 0), 10, 0.5)
+	if err != nil || len(matches) != 2 || matches[0].Face != face(4, 0) {
+		t.Errorf("Expected %s, %s, got %+v, %v", face(4, 0), face(2, 0), matches, err)
+	}
+	expectAssets(t, &metadata_db.AssetListFilter{Query: "face:" + face(1, 0)}, 1, 2, 4)
+
+	//Face not indexed yet, found by its embedding file
+	writeEmbedding(t, 5, 0, faces.Embedding{0, 0.1, 1})
+	matches, err = faces.FindSimilarFaces(face(5, 0), 10, 0.5)
+	if err != nil || len(matches) != 1 || matches[0].Face != face(3, 0) {
+		t.Errorf("Expected %s, got %+v, %v", face(3, 0), matches, err)
+	}
+	if _, err = faces.FindSimilarFaces(face(5, 9), 10, 0.5); err == nil {
+		t.Errorf("Expected error for unknown face")
+	}
+
+	//Snapshot includes logged faces, log is cleared
+	index, added, err = faces.UpdateFaceIndex()
+	if err != nil || added != 1 || index.Len() != 6 {
+		t.Fatalf("UpdateFaceIndex failed: %d added, %d faces, %v", added, index.Len(), err)
+	}
+	if stat, err := os.Stat(filepath.Join(config.AssetFacesBaseDir, faces.FaceIndexLogFileName)); err != nil || stat.Size() != 0 {
+		t.Errorf("Expected empty log, got %v", err)
+	}
+
+	//Similarities in database, from the index
+	faces.CalculateSimilarity(index, 0.5)
+	var count int
+	util.PanicOnError(db.QueryRow("SELECT COUNT(*) FROM faceSimilarity").Scan(&count), "Failed to count")
+	if count != 4 {
+		t.Errorf("Expected 4 face similarities, got %d", count)
+	}
+}
+
+func hash(i int) string {
+	return fmt.Sprintf("%02x0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcd", i)
+}
+
+func face(asset int, index int) string {
+	return fmt.Sprintf("%s/%d", hash(asset), index)
+}
+
+func randomEmbedding(random *rand.Rand, size int) faces.Embedding {
+	e := make(faces.Embedding, size)
+	for i := range e {
+		e[i] = random.NormFloat64()
+	}
+	return e
+}
+
+// writeEmbedding creates the embedding file like face extraction does
+func writeEmbedding(t *testing.T, asset int, index int, embedding faces.Embedding) {
+	dir := filepath.Join(config.AssetFacesBaseDir, hash(asset)[:2], hash(asset)[2:])
+	buf, _ := json.Marshal(embedding)
+	if err := os.MkdirAll(dir, 0744); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("%d.json", index)), buf, 0744); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// logFace appends to the index log like face extraction does
+func logFace(t *testing.T, asset int, index int, embedding faces.Embedding) {
+	file, err := os.OpenFile(filepath.Join(config.AssetFacesBaseDir, faces.FaceIndexLogFileName), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0744)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer util.CloseOrLog(file)
+	buf, _ := json.Marshal(map[string]any{"Face": face(asset, index), "Embedding": embedding})
+	if _, err = file.Write(append(buf, '\n')); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func expectAssets(t *testing.T, filter *metadata_db.AssetListFilter, assets ...int) {
+	filter.Sort = metadata_db.SortName
+	filter.Count = 10
+	list, err := metadata_db.ListAssets(filter)
+	if err != nil || len(list.Items) != len(assets) {
+		t.Errorf("Filter %+v: expected %v, got %+v, %v", filter, assets, list, err)
+		return
+	}
+	for i, asset := range assets {
+		if list.Items[i].Hash != hash(asset) {
+			t.Errorf("Filter %+v: expected %v, got %+v", filter, assets, list.Items)
+			return
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package filter_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/c8121/asset-storage/internal/filter"
+)
+
+// createImage creates a gray image with a diagonal gradient and a dark square
+func createImage(width int, height int, invert bool) image.Image {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := uint8((x*200/width + y*55/height) % 256)
+			if x > width/4 && x < width/2 && y > height/3 && y < height*2/3 {
+				v = 10
+			}
+			if invert {
+				v = 255 - v
+			}
+			img.SetGray(x, y, color.Gray{Y: v})
+		}
+	}
+	return img
+}
+
+func TestDHash(t *testing.T) {
+
+	original := filter.DHash(createImage(800, 600, false))
+	resized := filter.DHash(createImage(200, 150, false))
+	inverted := filter.DHash(createImage(800, 600, true))
+
+	if d := filter.HammingDistance(original, resized); d > 4 {
+		t.Errorf("Expected small distance of resized image, got %d", d)
+	}
+	if d := filter.HammingDistance(original, inverted); d < 32 {
+		t.Errorf("Expected large distance of inverted image, got %d", d)
+	}
+
+	s := filter.FormatImageHash(original)
+	if len(s) != 16 {
+		t.Errorf("Expected 16 hex digits, got '%s'", s)
+	}
+	if parsed, err := filter.ParseImageHash(s); err != nil || parsed != original {
+		t.Errorf("Failed to parse '%s': %v", s, err)
+	}
+}
@@ -0,0 +1,33 @@
+package geo_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/c8121/asset-storage/internal/geo"
+)
+
+func TestDistance(t *testing.T) {
+
+	//Lisbon - Porto, about 274 km
+	if d := geo.Distance(38.7223, -9.1393, 41.1579, -8.6291); math.Abs(d-274) > 5 {
+		t.Errorf("Unexpected distance %f", d)
+	}
+	if d := geo.Distance(10, 179.9, 10, -179.9); d > 25 {
+		t.Errorf("Unexpected distance across antimeridian %f", d)
+	}
+}
+
+func TestFindPlace(t *testing.T) {
+
+	//Belem, Lisbon
+	place := geo.FindPlace(38.6916, -9.2160)
+	if place == nil || place.Name != "Lisbon" || place.Country != "Portugal" || place.CountryCode != "PT" {
+		t.Errorf("Unexpected place %+v", place)
+	}
+
+	//Atlantic Ocean
+	if place = geo.FindPlace(40.0, -30.0); place != nil {
+		t.Errorf("Expected no place, got %+v", place)
+	}
+}
@@ -0,0 +1,130 @@
+package history_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/c8121/asset-storage/internal/config"
+	"github.com/c8121/asset-storage/internal/metadata"
+)
+
+const testHash = "ab0123456789abcdef0123456789abcdef"
+
+func TestHistory(t *testing.T) {
+
+	config.AssetMetaDataBaseDir = t.TempDir()
+
+	fileTime := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	if _, err := metadata.AddMetaData(testHash, "image/jpeg", 1000, "a.jpg", "/home/anna", "anna", fileTime); err != nil {
+		t.Fatalf("AddMetaData failed: %s", err)
+	}
+	if _, err := metadata.AddMetaData(testHash, "image/jpeg", 1000, "a.jpg", "/home/anna", "anna", fileTime); err != nil {
+		t.Fatalf("AddMetaData failed: %s", err)
+	}
+	if _, err := metadata.AddMetaData(testHash, "image/jpeg", 1000, "b.jpg", "/home/bob", "bob", fileTime); err != nil {
+		t.Fatalf("AddMetaData failed: %s", err)
+	}
+	expectActions(t, metadata.HistoryCreated, metadata.HistoryOriginAdded)
+
+	meta, err := metadata.UpdateMetaData(testHash, map[string]json.RawMessage{
+		"Rating": json.RawMessage(`4`),
+		"Tags":   json.RawMessage(`["beach"]`),
+	}, "anna")
+	if err != nil || meta.Rating != 4 || len(meta.Tags) != 1 {
+		t.Fatalf("UpdateMetaData failed: %v, %v", meta, err)
+	}
+	expectActions(t, metadata.HistoryCreated, metadata.HistoryOriginAdded, metadata.HistoryChanged, metadata.HistoryChanged)
+
+	//Unchanged values are not recorded
+	if _, err = metadata.UpdateMetaData(testHash, map[string]json.RawMessage{"Rating": json.RawMessage(`4`)}, "anna"); err != nil {
+		t.Fatalf("UpdateMetaData failed: %s", err)
+	}
+	if _, err = metadata.UpdateMetaData(testHash, map[string]json.RawMessage{"Deleted": json.RawMessage(`true`)}, "bob"); err != nil {
+		t.Fatalf("UpdateMetaData failed: %s", err)
+	}
+	if _, err = metadata.UpdateMetaData(testHash, map[string]json.RawMessage{"Deleted": json.RawMessage(`false`)}, "anna"); err != nil {
+		t.Fatalf("UpdateMetaData failed: %s", err)
+	}
+	history := expectActions(t, metadata.HistoryCreated, metadata.HistoryOriginAdded, metadata.HistoryChanged,
+		metadata.HistoryChanged, metadata.HistoryDeleted, metadata.HistoryRestored)
+	if history[4].User != "bob" || history[4].Field != "Deleted" {
+		t.Errorf("Unexpected entry: %+v", history[4])
+	}
+
+	if _, err = metadata.UpdateMetaData(testHash, map[string]json.RawMessage{"Rating": json.RawMessage(`6`)}, "anna"); err == nil {
+		t.Errorf("Expected error for rating out of range")
+	}
+	if _, err = metadata.UpdateMetaData(testHash, map[string]json.RawMessage{"Location": json.RawMessage(`{"Latitude": 91, "Longitude": 0}`)}, "anna"); err == nil {
+		t.Errorf("Expected error for location out of range")
+	}
+	if _, err = metadata.UpdateMetaData(testHash, map[string]json.RawMessage{"Hash": json.RawMessage(`"x"`)}, "anna"); !errors.Is(err, metadata.ErrUnknownField) {
+		t.Errorf("Expected ErrUnknownField, got %v", err)
+	}
+
+	//Roll back the rating change
+	ratingEntry := 2
+	if history[2].Field != "Rating" {
+		ratingEntry = 3
+	}
+	meta, err = metadata.RollbackField(testHash, ratingEntry, "anna")
+	if err != nil || meta.Rating != 0 || len(meta.Tags) != 1 {
+		t.Fatalf("RollbackField failed: %v, %v", meta, err)
+	}
+	history = expectActions(t, metadata.HistoryCreated, metadata.HistoryOriginAdded, metadata.HistoryChanged,
+		metadata.HistoryChanged, metadata.HistoryDeleted, metadata.HistoryRestored, metadata.HistoryRolledBack)
+	if string(history[6].OldValue) != "4" || string(history[6].NewValue) != "0" {
+		t.Errorf("Unexpected rollback entry: %+v", history[6])
+	}
+
+	if _, err = metadata.RollbackField(testHash, 0, "anna"); err == nil {
+		t.Errorf("Expected error rolling back creation")
+	}
+	if _, err = metadata.RollbackField(testHash, 99, "anna"); err == nil {
+		t.Errorf("Expected error for unknown entry")
+	}
+
+	meta, err = metadata.LoadByHash(testHash)
+	if err != nil || meta.Rating != 0 || meta.Deleted || len(meta.Origins) != 2 {
+		t.Errorf("Unexpected saved meta-data: %v, %v", meta, err)
+	}
+
+	//Fields calculated from content can be changed by SystemUser only
+	imageHash := map[string]json.RawMessage{"ImageHash": json.RawMessage(`"00ff00ff00ff00ff"`)}
+	if _, err = metadata.UpdateMetaData(testHash, imageHash, "anna"); !errors.Is(err, metadata.ErrUnknownField) {
+		t.Errorf("Expected ErrUnknownField, got %v", err)
+	}
+	meta, err = metadata.UpdateMetaData(testHash, imageHash, metadata.SystemUser)
+	if err != nil || meta.ImageHash != "00ff00ff00ff00ff" {
+		t.Errorf("UpdateMetaData as system failed: %v, %v", meta, err)
+	}
+}
+
+func TestEmptyHistory(t *testing.T) {
+
+	config.AssetMetaDataBaseDir = t.TempDir()
+
+	history, err := metadata.LoadHistory(testHash)
+	if err != nil || len(history) != 0 {
+		t.Errorf("Expected empty history: %v, %v", history, err)
+	}
+}
+
+// expectActions loads the history and compares actions
+func expectActions(t *testing.T, actions ...string) []metadata.JsonHistoryEntry {
+
+	history, err := metadata.LoadHistory(testHash)
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %s", err)
+	}
+	if len(history) != len(actions) {
+		t.Fatalf("Expected %d history entries, got %d: %+v", len(actions), len(history), history)
+	}
+	for i, action := range actions {
+		if history[i].Action != action {
+			t.Errorf("Entry %d: expected %s, got %s", i, action, history[i].Action)
+		}
+	}
+	return history
+}
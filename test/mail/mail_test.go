@@ -0,0 +1,185 @@
+package mail_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/c8121/asset-storage/internal/config"
+	"github.com/c8121/asset-storage/internal/metadata"
+	"github.com/c8121/asset-storage/internal/storage"
+)
+
+const (
+	attachment        = "Attached report\n"
+	attachmentEncoded = "QXR0YWNoZWQgcmVwb3J0Cg=="
+)
+
+func message(subject string, date string, forward string) string {
+
+	msg := "From: =?UTF-8?Q?J=C3=BCrgen?= <juergen@example.com>\r\n" +
+		"To: anna@example.com\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"Date: " + date + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/mixed; boundary=\"b1\"\r\n" +
+		"\r\n" +
+		"--b1\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" +
+		"See attachment.\r\n" +
+		"--b1\r\n" +
+		"Content-Type: text/plain; name=\"report.txt\"\r\n" +
+		"Content-Disposition: attachment; filename=\"report.txt\"\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"\r\n" +
+		attachmentEncoded + "\r\n"
+
+	if forward != "" {
+		msg += "--b1\r\n" +
+			"Content-Type: message/rfc822\r\n" +
+			"Content-Disposition: attachment; filename=\"forwarded.eml\"\r\n" +
+			"\r\n" +
+			forward + "\r\n"
+	}
+
+	return msg + "--b1--\r\n"
+}
+
+func setup(t *testing.T) {
+	config.AssetStorageBaseDir = t.TempDir()
+	config.AssetStorageTempDir = t.TempDir()
+	config.AssetMetaDataBaseDir = t.TempDir()
+	storage.CreateDirectories()
+}
+
+func TestMessage(t *testing.T) {
+
+	setup(t)
+
+	forwarded := strings.ReplaceAll(message("Forwarded", "Mon, 02 Jan 2006 15:04:05 +0000", ""), "\r\n", "\n")
+	path := filepath.Join(t.TempDir(), "mail.eml")
+	writeFile(t, path, message("Report", "Tue, 03 Jan 2006 10:00:00 +0100", forwarded))
+
+	infos, err := storage.AddFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 4 {
+		t.Fatalf("Expected message, attachment, forwarded message and its attachment, got %d", len(infos))
+	}
+
+	msg := infos[0]
+	if msg.MimeType != storage.MessageMimeType || msg.Subject != "Report" || msg.From != "Jürgen <juergen@example.com>" {
+		t.Errorf("Unexpected message info: %+v", msg)
+	}
+	if !msg.FileTime.Equal(time.Date(2006, 1, 3, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected message date: %s", msg.FileTime)
+	}
+
+	report := infos[1]
+	if report.Parent != msg.Hash || report.Relation != storage.RelationAttachmentOf || filepath.Base(report.SourcePath) != "report.txt" ||
+		!report.FileTime.Equal(msg.FileTime) {
+		t.Errorf("Unexpected attachment info: %+v", report)
+	}
+	assertContent(t, report.Hash, attachment)
+
+	fwd := infos[2]
+	if fwd.Parent != msg.Hash || fwd.Relation != storage.RelationAttachmentOf || fwd.Subject != "Forwarded" {
+		t.Errorf("Unexpected forwarded message info: %+v", fwd)
+	}
+	if infos[3].Hash != report.Hash || infos[3].Parent != fwd.Hash || infos[3].IsNewFile {
+		t.Errorf("Expected attachment of forwarded message to be stored once: %+v", infos[3])
+	}
+
+	//Attachment of forwarded message was stored already
+	infos, err = storage.AddFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, info := range infos {
+		if info.IsNewFile {
+			t.Errorf("Expected existing file: %+v", info)
+		}
+	}
+}
+
+func TestMbox(t *testing.T) {
+
+	setup(t)
+
+	first := strings.ReplaceAll(message("First", "Mon, 02 Jan 2006 15:04:05 +0000", ""), "\r\n", "\n")
+	second := strings.ReplaceAll(message("Second", "Tue, 03 Jan 2006 15:04:05 +0000", ""), "\r\n", "\n")
+	second = strings.Replace(second, "See attachment.", "See attachment.\n>From the archive", 1)
+
+	path := filepath.Join(t.TempDir(), "inbox")
+	writeFile(t, path, "From juergen@example.com Mon Jan  2 15:04:05 2006\n"+first+"\n"+
+		"From juergen@example.com Tue Jan  3 15:04:05 2006\n"+second+"\n")
+
+	infos, err := storage.AddFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if infos[0].MimeType != storage.MboxMimeType {
+		t.Fatalf("Expected mailbox, got %s", infos[0].MimeType)
+	}
+
+	//Mailbox, first message, its attachment, second message, same attachment
+	if len(infos) != 5 {
+		t.Fatalf("Expected 5 files, got %d", len(infos))
+	}
+	box := infos[0]
+	for i, subject := range map[int]string{1: "First", 3: "Second"} {
+		if infos[i].Subject != subject || infos[i].Parent != box.Hash || infos[i].Relation != storage.RelationPartOf {
+			t.Errorf("Unexpected message info: %+v", infos[i])
+		}
+		if infos[i+1].Parent != infos[i].Hash || infos[i+1].Hash != infos[2].Hash {
+			t.Errorf("Unexpected attachment info: %+v", infos[i+1])
+		}
+	}
+	if infos[4].IsNewFile {
+		t.Errorf("Expected attachment to be stored once")
+	}
+	assertContent(t, infos[3].Hash, strings.ReplaceAll(second, "\n>From", "\nFrom"))
+
+	//Meta-data of attachment relates to both messages
+	for _, i := range []int{2, 4} {
+		_, err = metadata.AddFileMetaData(&infos[i], filepath.Base(infos[i].SourcePath), filepath.Dir(infos[i].SourcePath), "", infos[i].FileTime)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	meta, err := metadata.LoadByHash(infos[2].Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(meta.Relations) != 2 || meta.Relations[0].Hash != infos[1].Hash || meta.Relations[1].Hash != infos[3].Hash {
+		t.Errorf("Unexpected relations: %v", meta.Relations)
+	}
+	if len(meta.Origins) != 2 || meta.Origins[1].Path != filepath.Join(path, "Second.eml") {
+		t.Errorf("Expected origin in each message, got %v", meta.Origins)
+	}
+}
+
+func assertContent(t *testing.T, hash string, expected string) {
+
+	path, err := storage.FindByHash(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != expected {
+		t.Errorf("Expected content '%s', got '%s'", expected, string(buf))
+	}
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
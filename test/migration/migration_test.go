@@ -0,0 +1,72 @@
+package migration_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	metadata_db_entity "github.com/c8121/asset-storage/internal/metadata-db-entity"
+	test_util "github.com/c8121/asset-storage/test/test-util"
+)
+
+func TestMigrateLegacyDatabase(t *testing.T) {
+
+	db := test_util.OpenDb(t)
+
+	//Asset table as created before migrations were introduced
+	fileTime := time.Date(2020, 5, 1, 10, 0, 0, 0, time.Local)
+	if _, err := db.Exec("CREATE TABLE asset(id integer PRIMARY KEY, hash TEXT(64), mimeType integer, fileTime DATETIME, name integer);"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO asset(hash, mimeType, fileTime, name) VALUES('aa01', 1, ?, 1);", fileTime); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := metadata_db_entity.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %s", err)
+	}
+
+	asset := &metadata_db_entity.Asset{Hash: "aa01"}
+	if err := asset.Load(); err != nil {
+		t.Fatalf("Failed to load migrated asset: %s", err)
+	}
+	if !asset.CaptureTime.Equal(fileTime) || asset.Size != 0 || asset.ImageHash.Valid || asset.Latitude.Valid {
+		t.Errorf("Unexpected migrated asset: %+v", asset)
+	}
+
+	version, err := metadata_db_entity.GetSchemaVersion(&metadata_db_entity.Asset{})
+	if err != nil || version != len((&metadata_db_entity.Asset{}).GetMigrations()) {
+		t.Errorf("Unexpected schema version %d (%v)", version, err)
+	}
+
+	//Second run does nothing
+	if err = metadata_db_entity.Migrate(); err != nil {
+		t.Fatalf("Second Migrate failed: %s", err)
+	}
+
+	//Database migrated by a newer program
+	if _, err = db.Exec("UPDATE schema_version SET version = 999 WHERE entity = 'Asset';"); err != nil {
+		t.Fatal(err)
+	}
+	if err = metadata_db_entity.Migrate(); !errors.Is(err, metadata_db_entity.ErrSchemaTooNew) {
+		t.Errorf("Expected ErrSchemaTooNew, got %v", err)
+	}
+}
+
+func TestCreateNewDatabase(t *testing.T) {
+
+	test_util.OpenDb(t)
+	if err := metadata_db_entity.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %s", err)
+	}
+
+	asset := &metadata_db_entity.Asset{Hash: "aa01", FileTime: time.Now(), CaptureTime: time.Now()}
+	if err := asset.Save(); err != nil {
+		t.Fatalf("Failed to save asset: %s", err)
+	}
+
+	version, err := metadata_db_entity.GetSchemaVersion(&metadata_db_entity.Origin{})
+	if err != nil || version != 0 {
+		t.Errorf("Unexpected schema version %d (%v)", version, err)
+	}
+}
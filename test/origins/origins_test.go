@@ -0,0 +1,124 @@
+package origins_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/c8121/asset-storage/internal/config"
+	"github.com/c8121/asset-storage/internal/metadata"
+	metadata_db "github.com/c8121/asset-storage/internal/metadata-db"
+	test_util "github.com/c8121/asset-storage/test/test-util"
+)
+
+const testHash = "cd0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcd"
+
+func TestOrigins(t *testing.T) {
+
+	config.AssetMetaDataBaseDir = t.TempDir()
+
+	test_util.CreateDb(t)
+
+	fileTime := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	add(t, "IMG_0001.jpg", "/home/anna/Photos", "anna", fileTime)
+	add(t, "IMG_0001 (1).jpg", "/tmp/xyz", "bob", fileTime.Add(time.Hour))
+
+	meta := expectName(t, "IMG_0001 (1).jpg")
+	origins := metadata_db.ListOrigins(meta)
+	if len(origins) != 2 || !origins[1].Latest || origins[0].Latest ||
+		origins[0].FullPath != "/home/anna/Photos/IMG_0001.jpg" || origins[1].Owner != "bob" || origins[0].PathId == 0 {
+		t.Errorf("Unexpected origins: %+v", origins)
+	}
+
+	//Hidden origin is not used as name, not listed in paths
+	meta, err := metadata.SetOriginHidden(testHash, 1, true, "anna")
+	if err != nil {
+		t.Fatalf("SetOriginHidden failed: %s", err)
+	}
+	test_util.UpdateDb(t, meta)
+	expectName(t, "IMG_0001.jpg")
+	test_util.ExpectCount(t, "under:/tmp", 0)
+	test_util.ExpectCount(t, "under:/home/anna", 1)
+
+	if _, err = metadata.SetOriginHidden(testHash, 0, true, "anna"); !errors.Is(err, metadata.ErrLastOrigin) {
+		t.Errorf("Expected ErrLastOrigin, got %v", err)
+	}
+	if _, err = metadata.RemoveOrigin(testHash, 0, "anna"); !errors.Is(err, metadata.ErrLastOrigin) {
+		t.Errorf("Expected ErrLastOrigin, got %v", err)
+	}
+	if _, err = metadata.RemoveOrigin(testHash, 2, "anna"); !errors.Is(err, metadata.ErrOriginNotFound) {
+		t.Errorf("Expected ErrOriginNotFound, got %v", err)
+	}
+
+	//Preferred name
+	meta, err = metadata.UpdateMetaData(testHash, map[string]json.RawMessage{"Name": json.RawMessage(`" Lisbon.jpg "`)}, "anna")
+	if err != nil {
+		t.Fatalf("UpdateMetaData failed: %s", err)
+	}
+	test_util.UpdateDb(t, meta)
+	expectName(t, "Lisbon.jpg")
+	test_util.ExpectCount(t, "name:lisbon.jpg", 1)
+	if _, err = metadata.UpdateMetaData(testHash, map[string]json.RawMessage{"Name": json.RawMessage(`"a/b.jpg"`)}, "anna"); err == nil {
+		t.Errorf("Expected error for name with path")
+	}
+
+	//Removed origin is not listed anymore, others keep their order
+	meta, err = metadata.RemoveOrigin(testHash, 1, "anna")
+	if err != nil || len(meta.Origins) != 1 || meta.Origins[0].Name != "IMG_0001.jpg" {
+		t.Fatalf("RemoveOrigin failed: %v, %s", meta, err)
+	}
+	test_util.UpdateDb(t, meta)
+	test_util.ExpectCount(t, "owner:bob", 0)
+
+	history, err := metadata.LoadHistory(testHash)
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %s", err)
+	}
+	actions := make([]string, 0, len(history))
+	for _, entry := range history {
+		actions = append(actions, entry.Action)
+	}
+	expected := []string{metadata.HistoryCreated, metadata.HistoryOriginAdded, metadata.HistoryOriginHidden,
+		metadata.HistoryChanged, metadata.HistoryOriginRemoved}
+	if len(actions) != len(expected) {
+		t.Fatalf("Expected history %v, got %v", expected, actions)
+	}
+	for i := range expected {
+		if actions[i] != expected[i] {
+			t.Errorf("Expected history %v, got %v", expected, actions)
+			break
+		}
+	}
+
+	var removed metadata.JsonAssetOrigin
+	if err = json.Unmarshal(history[4].OldValue, &removed); err != nil || removed.Path != "/tmp/xyz" || !removed.Hidden {
+		t.Errorf("Unexpected removed origin: %+v, %v", removed, err)
+	}
+}
+
+func add(t *testing.T, name string, path string, owner string, fileTime time.Time) {
+	meta, err := metadata.AddMetaData(testHash, "image/jpeg", 1000, name, path, owner, fileTime)
+	if err != nil {
+		t.Fatalf("AddMetaData failed: %s", err)
+	}
+	test_util.UpdateDb(t, meta)
+}
+
+// expectName checks the name in meta-data and database
+func expectName(t *testing.T, name string) *metadata.JsonAssetMetaData {
+
+	meta, err := metadata.LoadByHash(testHash)
+	if err != nil {
+		t.Fatalf("LoadByHash failed: %s", err)
+	}
+	if displayName := metadata.GetDisplayName(meta); displayName != name {
+		t.Errorf("Expected name '%s', got '%s'", name, displayName)
+	}
+
+	list, err := metadata_db.ListAssets(&metadata_db.AssetListFilter{Count: 10})
+	if err != nil || len(list.Items) != 1 || list.Items[0].Name != name {
+		t.Errorf("Expected name '%s' in database, got %+v, %v", name, list, err)
+	}
+	return meta
+}
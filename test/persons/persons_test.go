@@ -0,0 +1,173 @@
+package persons_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/c8121/asset-storage/internal/config"
+	"github.com/c8121/asset-storage/internal/faces"
+	"github.com/c8121/asset-storage/internal/metadata"
+	metadata_db "github.com/c8121/asset-storage/internal/metadata-db"
+	metadata_db_entity "github.com/c8121/asset-storage/internal/metadata-db-entity"
+	test_util "github.com/c8121/asset-storage/test/test-util"
+)
+
+func TestPersons(t *testing.T) {
+
+	config.AssetMetaDataBaseDir = t.TempDir()
+	config.AssetFacesBaseDir = t.TempDir()
+
+	test_util.CreateDb(t)
+
+	for i := 1; i <= 7; i++ {
+		meta, err := metadata.AddMetaData(hash(i), "image/jpeg", 1000, fmt.Sprintf("IMG_%d.jpg", i), "/photos", "anna", time.Now())
+		if err != nil {
+			t.Fatalf("AddMetaData failed: %s", err)
+		}
+		test_util.UpdateDb(t, meta)
+	}
+
+	//Two persons (x, y) and one face not similar to any other
+	x := faces.Embedding{1, 0, 0, 0}
+	y := faces.Embedding{0, 1, 0, 0}
+	embeddings := map[string]faces.Embedding{
+		face(1, 0): vary(x, 0.1), face(2, 0): vary(x, 0.2), face(3, 0): vary(x, 0.15),
+		face(1, 1): vary(y, 0.1), face(4, 0): vary(y, 0.05), face(5, 0): vary(y, 0.2),
+		face(6, 0): {0, 0, 1, 0},
+	}
+
+	persons := cluster(t, embeddings)
+	if len(persons.Persons) != 2 {
+		t.Fatalf("Expected 2 persons, got %+v", persons)
+	}
+	anna := personOf(t, persons, face(1, 0))
+	bob := personOf(t, persons, face(1, 1))
+	expectFaces(t, anna, face(1, 0), face(2, 0), face(3, 0))
+	expectFaces(t, bob, face(1, 1), face(4, 0), face(5, 0))
+
+	if _, err := faces.RenamePerson(anna.Id, " Anna "); err != nil {
+		t.Fatalf("RenamePerson failed: %s", err)
+	}
+	expectAssets(t, &metadata_db.AssetListFilter{Person: "anna"}, 1, 2, 3)
+	expectAssets(t, &metadata_db.AssetListFilter{Person: fmt.Sprint(bob.Id)}, 1, 4, 5)
+	expectAssets(t, &metadata_db.AssetListFilter{Query: "person:an* -person:" + fmt.Sprint(bob.Id)}, 2, 3)
+
+	//Clustering again keeps ids and names
+	embeddings[face(7, 0)] = vary(x, 0.1)
+	persons = cluster(t, embeddings)
+	if p := personOf(t, persons, face(7, 0)); p.Id != anna.Id || p.Name != "Anna" {
+		t.Errorf("Expected new face assigned to Anna, got %+v", p)
+	}
+
+	//Split faces are kept when clustering again
+	carl, err := faces.SplitPerson(anna.Id, []string{face(7, 0), face(3, 0)}, "Carl")
+	if err != nil {
+		t.Fatalf("SplitPerson failed: %s", err)
+	}
+	persons = cluster(t, embeddings)
+	expectFaces(t, personOf(t, persons, face(7, 0)), face(3, 0), face(7, 0))
+	expectFaces(t, personOf(t, persons, face(1, 0)), face(1, 0), face(2, 0))
+	expectAssets(t, &metadata_db.AssetListFilter{Person: "carl"}, 3, 7)
+
+	//Merged faces are kept together
+	merged, err := faces.MergePersons(bob.Id, anna.Id)
+	if err != nil || merged.Name != "Anna" {
+		t.Fatalf("MergePersons failed: %+v, %v", merged, err)
+	}
+	persons = cluster(t, embeddings)
+	if len(persons.Persons) != 2 {
+		t.Fatalf("Expected 2 persons, got %+v", persons)
+	}
+	expectFaces(t, personOf(t, persons, face(1, 0)), face(1, 0), face(1, 1), face(2, 0), face(4, 0), face(5, 0))
+	expectAssets(t, &metadata_db.AssetListFilter{Person: "anna"}, 1, 2, 4, 5)
+
+	if _, err = faces.RenamePerson(anna.Id, "Removed"); !errors.Is(err, faces.ErrPersonNotFound) {
+		t.Errorf("Expected ErrPersonNotFound, got %v", err)
+	}
+	if _, err = faces.SplitPerson(carl.Id, []string{face(1, 0)}, ""); !errors.Is(err, faces.ErrFaceNotFound) {
+		t.Errorf("Expected ErrFaceNotFound, got %v", err)
+	}
+	if _, err = faces.MergePersons(carl.Id, carl.Id); err == nil {
+		t.Errorf("Expected error when merging a person with itself")
+	}
+
+	//Database is restored from the file
+	if err = metadata_db_entity.ReplacePersons(nil, nil); err != nil {
+		t.Fatalf("ReplacePersons failed: %s", err)
+	}
+	expectAssets(t, &metadata_db.AssetListFilter{Person: "carl"})
+	if err = faces.SyncPersons(); err != nil {
+		t.Fatalf("SyncPersons failed: %s", err)
+	}
+	expectAssets(t, &metadata_db.AssetListFilter{Person: "carl"}, 3, 7)
+}
+
+func hash(i int) string {
+	return fmt.Sprintf("%02x0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcd", i)
+}
+
+func face(asset int, index int) string {
+	return fmt.Sprintf("%s/%d", hash(asset), index)
+}
+
+// vary returns a copy of e with some noise
+func vary(e faces.Embedding, amount float64) faces.Embedding {
+	v := append(faces.Embedding{}, e...)
+	v[3] += amount
+	return v
+}
+
+func cluster(t *testing.T, embeddings map[string]faces.Embedding) *faces.JsonPersons {
+	index := faces.NewFaceIndex()
+	for key, embedding := range embeddings {
+		index.Add(key, embedding)
+	}
+	persons, err := faces.ClusterPersons(index, 0.9, 2)
+	if err != nil {
+		t.Fatalf("ClusterPersons failed: %s", err)
+	}
+	return persons
+}
+
+func personOf(t *testing.T, persons *faces.JsonPersons, key string) *faces.JsonPerson {
+	for i, person := range persons.Persons {
+		for _, face := range person.Faces {
+			if face.Face == key {
+				return &persons.Persons[i]
+			}
+		}
+	}
+	t.Fatalf("Face %s not assigned: %+v", key, persons)
+	return nil
+}
+
+func expectFaces(t *testing.T, person *faces.JsonPerson, keys ...string) {
+	if len(person.Faces) != len(keys) {
+		t.Errorf("Expected faces %v, got %+v", keys, person)
+		return
+	}
+	for i, key := range keys {
+		if person.Faces[i].Face != key {
+			t.Errorf("Expected faces %v, got %+v", keys, person)
+			return
+		}
+	}
+}
+
+func expectAssets(t *testing.T, filter *metadata_db.AssetListFilter, assets ...int) {
+	filter.Sort = metadata_db.SortName
+	filter.Count = 10
+	list, err := metadata_db.ListAssets(filter)
+	if err != nil || len(list.Items) != len(assets) {
+		t.Errorf("Filter %+v: expected %v, got %+v, %v", filter, assets, list, err)
+		return
+	}
+	for i, asset := range assets {
+		if list.Items[i].Hash != hash(asset) {
+			t.Errorf("Filter %+v: expected %v, got %+v", filter, assets, list.Items)
+			return
+		}
+	}
+}
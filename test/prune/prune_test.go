@@ -0,0 +1,103 @@
+package prune_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/c8121/asset-storage/internal/collections"
+	"github.com/c8121/asset-storage/internal/metadata"
+	metadata_db "github.com/c8121/asset-storage/internal/metadata-db"
+	metadata_db_entity "github.com/c8121/asset-storage/internal/metadata-db-entity"
+	test_util "github.com/c8121/asset-storage/test/test-util"
+)
+
+func TestPrune(t *testing.T) {
+
+	db := test_util.CreateDb(t)
+
+	keep := metadata.CreateNew("aa01", "image/jpeg", 1000, "keep.jpg", "/home/anna/Photos", "anna", time.Now())
+	remove := metadata.CreateNew("aa02", "image/png", 1000, "remove.jpg", "/home/anna/Photos/Removed/Sub", "anna", time.Now())
+	remove.Tags = []string{"removed"}
+
+	for i, err := range metadata_db_entity.AddMetaDataBatch([]*metadata.JsonAssetMetaData{keep, remove}) {
+		if err != nil {
+			t.Fatalf("Failed to add %d: %s", i, err)
+		}
+	}
+	expectRows(t, db, "asset", 2)
+
+	result, err := metadata_db_entity.PruneAssets(func(hash string) bool {
+		return hash == "aa01"
+	})
+	if err != nil {
+		t.Fatalf("PruneAssets failed: %s", err)
+	}
+	if result.Assets != 1 || result.Origins != 1 || result.PathItems != 2 || result.FileNames != 1 || result.Tags != 1 || result.MimeTypes != 1 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	expectRows(t, db, "asset", 1)
+	expectRows(t, db, "assetSearch", 1)
+	expectRows(t, db, "pathItem", 3) //home, anna, Photos
+	expectRows(t, db, "pathItemClosure", 6)
+	expectRows(t, db, "mimeType", 1)
+
+	//Removed mime-type is created again (not taken from cache)
+	again := metadata.CreateNew("aa03", "image/png", 1000, "again.png", "/tmp", "anna", time.Now())
+	if err = metadata_db_entity.AddMetaData(again); err != nil {
+		t.Fatalf("Failed to add meta-data: %s", err)
+	}
+	if list, err := metadata_db.ListAssets(&metadata_db.AssetListFilter{Query: "mime:image/png", Count: 10}); err != nil || list.Total != 1 {
+		t.Errorf("Unexpected list: %+v, %v", list, err)
+	}
+
+	if list, err := metadata_db.ListAssets(&metadata_db.AssetListFilter{PathPrefix: "/home/anna", Count: 10}); err != nil || list.Total != 1 {
+		t.Errorf("Unexpected list: %+v, %v", list, err)
+	}
+
+	//Update existing collection, remove missing
+	for _, name := range []string{"a", "b"} {
+		collection := collections.CreateNew(name, "", "anna", []string{"aa01"})
+		if err = metadata_db.AddCollection(collection); err != nil {
+			t.Fatalf("Failed to add collection: %s", err)
+		}
+		if err = metadata_db.AddCollection(collection); err != nil {
+			t.Fatalf("Failed to update collection: %s", err)
+		}
+	}
+	count, err := metadata_db_entity.PruneCollections(func(hash string) bool {
+		return hash == collections.CreateNew("a", "", "anna", []string{"aa01"}).Hash
+	})
+	if err != nil || count != 1 {
+		t.Errorf("Unexpected pruned collections %d (%v)", count, err)
+	}
+	expectRows(t, db, "collection", 1)
+}
+
+func TestDbProperty(t *testing.T) {
+
+	test_util.CreateDb(t)
+
+	if value, err := metadata_db_entity.GetDbProperty("test"); err != nil || value != "" {
+		t.Errorf("Expected empty value, got '%s' (%v)", value, err)
+	}
+	for _, v := range []string{"1", "2"} {
+		if err := metadata_db_entity.SetDbProperty("test", v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if value, err := metadata_db_entity.GetDbProperty("test"); err != nil || value != "2" {
+		t.Errorf("Expected '2', got '%s' (%v)", value, err)
+	}
+}
+
+func expectRows(t *testing.T, db *sql.DB, table string, count int) {
+	var found int
+	if err := db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&found); err != nil {
+		t.Fatal(err)
+	}
+	if found != count {
+		t.Errorf("%s: expected %d rows, got %d", table, count, found)
+	}
+}
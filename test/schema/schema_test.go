@@ -0,0 +1,108 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/c8121/asset-storage/internal/collections"
+	"github.com/c8121/asset-storage/internal/config"
+	"github.com/c8121/asset-storage/internal/metadata"
+	"github.com/c8121/asset-storage/internal/util"
+)
+
+const testHash = "ab0123456789abcdef0123456789abcdef"
+
+func TestMetaDataUpgrade(t *testing.T) {
+
+	config.AssetMetaDataBaseDir = t.TempDir()
+	path := metadata.GetMetaDataFilePath(testHash)
+	writeFile(t, path, `{"MimeType":"image/jpeg","Size":1000,"Origins":[{"Name":"a.jpg"}],"Tags":["beach"]}`)
+
+	meta, err := metadata.LoadIfExists(path)
+	if err != nil || meta.Hash != testHash || meta.SchemaVersion != metadata.CurrentSchemaVersion || meta.Tags[0] != "beach" {
+		t.Fatalf("Unexpected upgraded meta-data: %+v, %v", meta, err)
+	}
+
+	buf, err := metadata.Upgrade(path)
+	if err != nil || buf == nil {
+		t.Fatalf("Expected upgraded JSON, got %s, %v", buf, err)
+	}
+	var upgraded metadata.JsonAssetMetaData
+	if err = json.Unmarshal(buf, &upgraded); err != nil || upgraded.Hash != testHash || upgraded.Size != 1000 {
+		t.Errorf("Unexpected upgraded JSON: %s, %v", buf, err)
+	}
+
+	//Saved files are up to date
+	util.PanicOnError(meta.Save(path), "Failed to save")
+	if buf, err = metadata.Upgrade(path); err != nil || buf != nil {
+		t.Errorf("Expected no upgrade, got %s, %v", buf, err)
+	}
+
+	writeFile(t, path, `{"SchemaVersion":99,"Hash":"`+testHash+`"}`)
+	if _, err = metadata.LoadIfExists(path); !errors.Is(err, util.ErrJsonSchemaTooNew) {
+		t.Errorf("Expected ErrJsonSchemaTooNew, got %v", err)
+	}
+	if _, err = metadata.Upgrade(path); !errors.Is(err, util.ErrJsonSchemaTooNew) {
+		t.Errorf("Expected ErrJsonSchemaTooNew, got %v", err)
+	}
+}
+
+func TestCollectionUpgrade(t *testing.T) {
+
+	config.AssetCollectionsBaseDir = t.TempDir()
+	path := collections.GetCollectionFilePath(testHash)
+	writeFile(t, path, `{"Name":"Holiday","Assets":["aa01"]}`)
+
+	collection, err := collections.LoadIfExists(path)
+	if err != nil || collection.Hash != testHash || collection.SchemaVersion != collections.CurrentSchemaVersion {
+		t.Fatalf("Unexpected upgraded collection: %+v, %v", collection, err)
+	}
+
+	created := collections.CreateNew("Holiday", "", "anna", []string{"aa01"})
+	util.PanicOnError(created.Save(path), "Failed to save")
+	if buf, err := collections.Upgrade(path); err != nil || buf != nil {
+		t.Errorf("Expected no upgrade, got %s, %v", buf, err)
+	}
+}
+
+func TestUpgradeJson(t *testing.T) {
+
+	calls := 0
+	upgrades := []util.JsonUpgrade{
+		func(doc map[string]json.RawMessage, path string) error {
+			calls++
+			doc["Name"] = doc["OldName"]
+			delete(doc, "OldName")
+			return nil
+		},
+		func(doc map[string]json.RawMessage, path string) error {
+			calls++
+			doc["Path"] = json.RawMessage(`"` + path + `"`)
+			return nil
+		},
+	}
+
+	buf, upgraded, err := util.UpgradeJson([]byte(`{"OldName":"a"}`), "p", upgrades)
+	if err != nil || !upgraded || calls != 2 || string(buf) != `{"Name":"a","Path":"p","SchemaVersion":2}` {
+		t.Errorf("Unexpected result: %s, %v, %v, %d", buf, upgraded, err, calls)
+	}
+
+	buf, upgraded, err = util.UpgradeJson([]byte(`{"SchemaVersion":1,"Name":"a"}`), "p", upgrades)
+	if err != nil || !upgraded || calls != 3 || string(buf) != `{"Name":"a","Path":"p","SchemaVersion":2}` {
+		t.Errorf("Unexpected result: %s, %v, %v, %d", buf, upgraded, err, calls)
+	}
+
+	if _, _, err = util.UpgradeJson([]byte(`[]`), "p", upgrades); err == nil {
+		t.Errorf("Expected error for JSON array")
+	}
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	util.PanicOnError(os.MkdirAll(filepath.Dir(path), 0700), "Failed to create dir")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write %s: %s", path, err)
+	}
+}
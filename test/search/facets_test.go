@@ -0,0 +1,52 @@
+package search_test
+
+import (
+	"testing"
+
+	metadata_db "github.com/c8121/asset-storage/internal/metadata-db"
+)
+
+// checkFacets expects the test assets of TestSearchIndex
+func checkFacets(t *testing.T) {
+
+	facets, err := metadata_db.ListFacets(&metadata_db.AssetListFilter{})
+	if err != nil {
+		t.Fatalf("ListFacets failed: %s", err)
+	}
+	if facets.Total != 3 {
+		t.Errorf("Expected total 3, got %d", facets.Total)
+	}
+	expectFacet(t, "MimeType", facets.MimeType, "image/jpeg", 3)
+	expectFacet(t, "Owner", facets.Owner, "anna", 3)
+	expectFacet(t, "PathRoot", facets.PathRoot, "home", 3)
+	expectFacet(t, "Tag", facets.Tag, "beach", 1)
+	if len(facets.Year) != 1 || len(facets.Month) != 1 {
+		t.Errorf("Expected one year and month, got %v, %v", facets.Year, facets.Month)
+	}
+
+	facets, err = metadata_db.ListFacets(&metadata_db.AssetListFilter{Query: "photos"})
+	if err != nil {
+		t.Fatalf("ListFacets failed: %s", err)
+	}
+	if facets.Total != 2 {
+		t.Errorf("Expected total 2, got %d", facets.Total)
+	}
+	expectFacet(t, "PathRoot", facets.PathRoot, "home", 2)
+	expectFacet(t, "Tag", facets.Tag, "holiday", 1)
+
+	if _, err = metadata_db.ListFacets(&metadata_db.AssetListFilter{Query: "tag:"}); err == nil {
+		t.Errorf("Expected error for invalid query")
+	}
+}
+
+func expectFacet(t *testing.T, name string, values []metadata_db.FacetValue, value string, count int) {
+	for _, v := range values {
+		if v.Value == value {
+			if v.Count != count {
+				t.Errorf("Facet %s: expected %d for '%s', got %d", name, count, value, v.Count)
+			}
+			return
+		}
+	}
+	t.Errorf("Facet %s: '%s' not found in %v", name, value, values)
+}
@@ -0,0 +1,63 @@
+package search_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/c8121/asset-storage/internal/metadata"
+	metadata_db "github.com/c8121/asset-storage/internal/metadata-db"
+	metadata_db_entity "github.com/c8121/asset-storage/internal/metadata-db-entity"
+)
+
+// checkGeo expects the test assets of TestSearchIndex, adds images located in Lisbon and Porto
+func checkGeo(t *testing.T) {
+
+	locations := map[string]*metadata.JsonLocation{
+		"aa08": {Latitude: 38.7139, Longitude: -9.1334},
+		"aa09": {Latitude: 41.1496, Longitude: -8.6110},
+	}
+	for hash, location := range locations {
+		meta := metadata.CreateNew(hash, "image/jpeg", 1000, hash+".jpg", "/home/anna/Travel", "anna", time.Now())
+		meta.Location = location
+		if err := metadata_db_entity.AddMetaData(meta); err != nil {
+			t.Fatalf("Failed to add meta-data: %s", err)
+		}
+	}
+
+	expectQueryCount(t, "place:Lisbon", 1)
+	expectQueryCount(t, "place:portugal", 2)
+	expectQueryCount(t, "place:Lis*", 1)
+	expectQueryCount(t, "near:38.72,-9.14,10", 1)
+	expectQueryCount(t, "near:38.72,-9.14,500m", 0)
+	expectQueryCount(t, "near:38.72,-9.14,500", 2)
+	expectQueryCount(t, "bbox:-10,36,-6,42", 2)
+	expectQueryCount(t, "bbox:170,-50,-170,50", 0)
+	expectQueryCount(t, "-place:Lisbon place:Portugal", 1)
+
+	expectFilterCount(t, &metadata_db.AssetListFilter{Near: "41.15,-8.61,5"}, 1)
+	expectFilterCount(t, &metadata_db.AssetListFilter{BoundingBox: "-10,36,-6,40", Place: "Portugal"}, 1)
+
+	if _, err := metadata_db.ListAssets(&metadata_db.AssetListFilter{BoundingBox: "1,2,3"}); err == nil {
+		t.Errorf("Expected error for invalid bounding box")
+	}
+
+	clusters, err := metadata_db.ListGeoClusters(&metadata_db.AssetListFilter{}, 0)
+	if err != nil || len(clusters.Features) != 1 || clusters.Features[0].Properties.Count != 2 {
+		t.Errorf("Unexpected clusters: %+v, %v", clusters, err)
+	}
+
+	clusters, err = metadata_db.ListGeoClusters(&metadata_db.AssetListFilter{}, 10)
+	if err != nil || len(clusters.Features) != 2 || clusters.Features[0].Properties.Count != 1 {
+		t.Errorf("Unexpected clusters: %+v, %v", clusters, err)
+	}
+
+	clusters, err = metadata_db.ListGeoClusters(&metadata_db.AssetListFilter{Place: "Lisbon"}, 0)
+	if err != nil || len(clusters.Features) != 1 || clusters.Features[0].Properties.Hash != "aa08" ||
+		clusters.Features[0].Geometry.Coordinates[0] != -9.1334 {
+		t.Errorf("Unexpected clusters: %+v, %v", clusters, err)
+	}
+
+	if _, err = metadata_db.ListGeoClusters(&metadata_db.AssetListFilter{}, 23); err == nil {
+		t.Errorf("Expected error for invalid zoom")
+	}
+}
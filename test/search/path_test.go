@@ -0,0 +1,72 @@
+package search_test
+
+import (
+	"testing"
+
+	metadata_db "github.com/c8121/asset-storage/internal/metadata-db"
+	metadata_db_entity "github.com/c8121/asset-storage/internal/metadata-db-entity"
+)
+
+// checkPathTree expects the test assets of TestSearchIndex
+func checkPathTree(t *testing.T) {
+
+	expectQueryCount(t, "under:/home/anna/Photos", 2)
+	expectQueryCount(t, "under:/home/anna", 3)
+	expectQueryCount(t, "under:/home/anna/Pho", 2)
+	expectQueryCount(t, "under:/home/anna/Photos/Lisbon", 1)
+	expectQueryCount(t, "under:/home/bob", 0)
+	expectQueryCount(t, "under:/nothing/here", 0)
+	expectQueryCount(t, "path:photos", 2)
+	expectQueryCount(t, "path:anna -under:/home/anna/Photos", 1)
+
+	photos, err := metadata_db_entity.GetPathItem("/home/anna/Photos", false)
+	if err != nil {
+		t.Fatalf("Path not found: %s", err)
+	}
+	expectFilterCount(t, &metadata_db.AssetListFilter{PathTree: photos.Id}, 2)
+	expectFilterCount(t, &metadata_db.AssetListFilter{PathId: photos.Id}, 1)
+
+	if err = metadata_db_entity.RebuildPathItemClosure(); err != nil {
+		t.Fatalf("Failed to rebuild closure: %s", err)
+	}
+	expectFilterCount(t, &metadata_db.AssetListFilter{PathTree: photos.Id}, 2)
+	expectFilterCount(t, &metadata_db.AssetListFilter{PathPrefix: "/home"}, 3)
+}
+
+// checkPathBrowsing expects the test assets of TestSearchIndex
+func checkPathBrowsing(t *testing.T) {
+
+	roots, err := metadata_db.ListPathItems(0, 0, 10)
+	if err != nil || len(roots) != 1 {
+		t.Fatalf("Expected one root item: %v, %v", roots, err)
+	}
+	if roots[0].Name != "home" || roots[0].Children != 1 || roots[0].AssetCount != 3 || roots[0].TotalSize != 3000 {
+		t.Errorf("Unexpected root item: %+v", roots[0])
+	}
+
+	anna, err := metadata_db_entity.GetPathItem("/home/anna", false)
+	if err != nil {
+		t.Fatalf("Path not found: %s", err)
+	}
+	page, err := metadata_db.ListPathItems(anna.Id, 1, 1)
+	if err != nil || len(page) != 1 {
+		t.Fatalf("Expected one item: %v, %v", page, err)
+	}
+	if page[0].Name != "Photos" || page[0].Children != 1 || page[0].AssetCount != 2 || page[0].TotalSize != 2000 {
+		t.Errorf("Unexpected item: %+v", page[0])
+	}
+
+	lisbon, err := metadata_db_entity.GetPathItem("/home/anna/Photos/Lisbon", false)
+	if err != nil {
+		t.Fatalf("Path not found: %s", err)
+	}
+	path, err := metadata_db.GetPathItemPath(lisbon.Id)
+	if err != nil || path.FullPath != "/home/anna/Photos/Lisbon" || len(path.Items) != 4 || path.Items[0].Name != "home" {
+		t.Errorf("Unexpected path: %+v, %v", path, err)
+	}
+
+	paths, err := metadata_db.ListAssetPaths(metadata_db_entity.GetAssetId("aa01"))
+	if err != nil || len(paths) != 1 || paths[0].Id != lisbon.Id {
+		t.Errorf("Unexpected asset paths: %+v, %v", paths, err)
+	}
+}
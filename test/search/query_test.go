@@ -0,0 +1,84 @@
+package search_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/c8121/asset-storage/internal/metadata"
+	metadata_db "github.com/c8121/asset-storage/internal/metadata-db"
+	metadata_db_entity "github.com/c8121/asset-storage/internal/metadata-db-entity"
+)
+
+func TestParseQuery(t *testing.T) {
+
+	tests := map[string]string{
+		"holiday": `text:"holiday"`,
+		"mime:image/* name:holiday -path:tmp after:2022-01-01 owner:anna (tag:beach OR tag:sea)": `AND(mime:"image/*", name:"holiday", NOT(path:"tmp"), ` +
+			`after:"2022-01-01", owner:"anna", OR(tag:"beach", tag:"sea"))`,
+		`"summer holiday" OR tag:"blue sky"`: `OR(text:"summer holiday", tag:"blue sky")`,
+		"a AND b OR c":                       `OR(AND(text:"a", text:"b"), text:"c")`,
+		"NOT a":                              `NOT(text:"a")`,
+		"--a":                                `NOT(NOT(text:"a"))`,
+		"Name:x 12:30":                       `AND(name:"x", text:"12:30")`,
+		"2022-01-01":                         `text:"2022-01-01"`,
+		"http://example.com note:":           `AND(text:"http://example.com", text:"note:")`,
+	}
+
+	for in, expected := range tests {
+		node, err := metadata_db.ParseQuery(in)
+		if err != nil {
+			t.Errorf("ParseQuery(%q) failed: %s", in, err)
+			continue
+		}
+		if node.String() != expected {
+			t.Errorf("ParseQuery(%q) = %s, expected %s", in, node, expected)
+		}
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+
+	tests := map[string]int{
+		"":                      1,
+		"a (b":                  3,
+		"a b)":                  4,
+		"\"open":                1,
+		"a OR":                  3,
+		"name:":                 1,
+		"x after:yesterday":     3,
+		"()":                    1,
+		"tag:a -":               7,
+		"pathid:abc":            1,
+		"(tag:a OR tag:b) AND ": 18,
+	}
+
+	for in, expectedPos := range tests {
+		_, err := metadata_db.ParseQuery(in)
+		var queryErr *metadata_db.QueryError
+		if !errors.As(err, &queryErr) {
+			t.Errorf("ParseQuery(%q): expected QueryError, got %v", in, err)
+			continue
+		}
+		if queryErr.Position != expectedPos {
+			t.Errorf("ParseQuery(%q): expected error at %d, got %s", in, expectedPos, queryErr)
+		}
+	}
+}
+
+// checkFileTimeZone expects the database of TestSearchIndex, it adds an asset and should be called last
+func checkFileTimeZone(t *testing.T) {
+
+	//Shortly after midnight in UTC+2 is the previous day in UTC
+	fileTime := time.Date(2020, 1, 1, 0, 30, 0, 0, time.FixedZone("UTC+2", 2*60*60))
+	meta := metadata.CreateNew("ab01", "image/jpeg", 1000, "IMG_0001.jpg", "/home/anna", "anna", fileTime)
+	if err := metadata_db_entity.AddMetaData(meta); err != nil {
+		t.Fatalf("Failed to add meta-data: %s", err)
+	}
+
+	localDate := fileTime.In(time.Local).Format("2006-01-02")
+	expectQueryCount(t, "date:"+localDate, 1)
+	if date := fileTime.Format("2006-01-02"); date != localDate {
+		expectQueryCount(t, "date:"+date, 0)
+	}
+}
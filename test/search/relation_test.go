@@ -0,0 +1,38 @@
+package search_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/c8121/asset-storage/internal/metadata"
+	metadata_db_entity "github.com/c8121/asset-storage/internal/metadata-db-entity"
+)
+
+// checkRelations expects the test assets of TestSearchIndex, adds an attachment of two messages
+func checkRelations(t *testing.T) {
+
+	meta := metadata.CreateNew("aa12", "application/pdf", 1000, "numbers.pdf", "/home/anna/mail.mbox/Numbers.eml", "anna", time.Now())
+	meta.Origins[0].Subject = "Quarterly numbers"
+	meta.Origins[0].From = "Jürgen <juergen@example.com>"
+	meta.AddRelation(metadata.RelationAttachmentOf, "aa02")
+	meta.AddRelation(metadata.RelationAttachmentOf, "aa03")
+	if meta.AddRelation(metadata.RelationAttachmentOf, "aa03") {
+		t.Errorf("Expected relation to be added once")
+	}
+	if err := metadata_db_entity.AddMetaData(meta); err != nil {
+		t.Fatalf("Failed to add meta-data: %s", err)
+	}
+
+	expectQueryCount(t, "related:aa02", 1)
+	expectQueryCount(t, "related:AA03 name:numbers.pdf", 1)
+	expectQueryCount(t, "related:aa01", 0)
+	expectQueryCount(t, "quarterly", 1)
+	expectQueryCount(t, "juergen", 1)
+
+	//Relations are replaced on update
+	meta.Relations = meta.Relations[:1]
+	if err := metadata_db_entity.AddMetaData(meta); err != nil {
+		t.Fatalf("Failed to add meta-data: %s", err)
+	}
+	expectQueryCount(t, "related:aa03", 0)
+}
@@ -0,0 +1,191 @@
+package search_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/c8121/asset-storage/internal/metadata"
+	metadata_db "github.com/c8121/asset-storage/internal/metadata-db"
+	metadata_db_entity "github.com/c8121/asset-storage/internal/metadata-db-entity"
+	test_util "github.com/c8121/asset-storage/test/test-util"
+)
+
+func TestFtsQuery(t *testing.T) {
+
+	tests := map[string]string{
+		"":                        "",
+		"holiday":                 "\"holiday\"",
+		"holi*":                   "\"holi\"*",
+		"holiday beach":           "\"holiday\" \"beach\"",
+		"\"summer holiday\" 2019": "\"summer holiday\" \"2019\"",
+		"beach OR sea":            "\"beach\" OR \"sea\"",
+		"beach NOT sea":           "\"beach\" NOT \"sea\"",
+		"OR beach NOT":            "\"beach\"",
+		"a(b) \"open":             "\"a(b)\" \"open\"",
+	}
+
+	for in, expected := range tests {
+		result, err := metadata_db.ToFtsQuery(in)
+		if err != nil || result != expected {
+			t.Errorf("ToFtsQuery(%q) = %q (%v), expected %q", in, result, err, expected)
+		}
+	}
+
+	//FTS5 has no unary NOT, must not be dropped (would search the excluded term)
+	for _, in := range []string{"NOT sea", "beach OR NOT sea", "beach NOT NOT sea", "beach NOT OR sea"} {
+		result, err := metadata_db.ToFtsQuery(in)
+		var queryErr *metadata_db.QueryError
+		if !errors.As(err, &queryErr) {
+			t.Errorf("ToFtsQuery(%q) = %q, expected QueryError, got %v", in, result, err)
+		}
+	}
+}
+
+func TestSearchIndex(t *testing.T) {
+
+	test_util.CreateDb(t)
+
+	addTestAsset(t, "aa01", "IMG_0001.jpg", "/home/anna/Photos/Lisbon", "", nil)
+	addTestAsset(t, "aa02", "report.pdf", "/home/anna/Documents", "Yearly report about the holiday budget", nil)
+	addTestAsset(t, "aa03", "IMG_0002.jpg", "/home/anna/Photos", "", []string{"beach", "holiday"})
+
+	expectCount(t, "lisbon", 1)
+	expectCount(t, "holiday", 2)
+	expectCount(t, "photos", 2)
+	expectCount(t, "img*", 2)
+	expectCount(t, "\"holiday budget\"", 1)
+	expectCount(t, "lisbon OR beach", 2)
+	expectCount(t, "unknown", 0)
+	expectCount(t, "holiday NOT beach", 1)
+	if _, err := (metadata_db.FinderByQuery{}).Find("NOT beach"); err == nil {
+		t.Errorf("Expected error for leading NOT")
+	}
+
+	expectQueryCount(t, "holiday", 2)
+	expectQueryCount(t, "tag:beach", 1)
+	expectQueryCount(t, "tag:beach OR name:report.pdf", 2)
+	expectQueryCount(t, "-tag:beach", 2)
+	expectQueryCount(t, "NOT holiday", 1)
+	expectQueryCount(t, "report OR NOT holiday", 2)
+	expectQueryCount(t, "photos -tag:holiday", 1)
+	expectQueryCount(t, "owner:anna (lisbon OR report)", 2)
+	expectQueryCount(t, "owner:bob", 0)
+	expectQueryCount(t, "after:2000 before:2100-01", 3)
+	expectQueryCount(t, "before:2000", 0)
+	expectQueryCount(t, "http://example.com", 0)
+	expectQueryCount(t, "size:>500", 3)
+	expectQueryCount(t, "size:2K-3K", 0)
+
+	expectFilterCount(t, &metadata_db.AssetListFilter{Owner: "ann*"}, 3)
+	expectFilterCount(t, &metadata_db.AssetListFilter{FileTimeFrom: "2000", FileTimeTo: "2099-12"}, 3)
+	expectFilterCount(t, &metadata_db.AssetListFilter{FileTimeTo: "1999"}, 0)
+	expectFilterCount(t, &metadata_db.AssetListFilter{MinSize: 1000, MaxSize: 1000}, 3)
+	expectFilterCount(t, &metadata_db.AssetListFilter{MinSize: 1001}, 0)
+
+	list, err := metadata_db.ListAssets(&metadata_db.AssetListFilter{Query: "anna", Sort: metadata_db.SortName, Count: 10})
+	if err != nil || len(list.Items) != 3 || list.Items[0].Name != "IMG_0001.jpg" || list.Items[2].Name != "report.pdf" {
+		t.Errorf("Unexpected result sorted by name: %v, %v", list, err)
+	}
+
+	list, err = metadata_db.ListAssets(&metadata_db.AssetListFilter{Query: "holiday", Offset: 1, Count: 1})
+	if err != nil || len(list.Items) != 1 || list.Total != 2 {
+		t.Errorf("Unexpected page: %v, %v", list, err)
+	}
+
+	list, err = metadata_db.ListAssets(&metadata_db.AssetListFilter{Offset: 10, Count: 10})
+	if err != nil || len(list.Items) != 0 || list.Total != 3 {
+		t.Errorf("Unexpected page behind last item: %v, %v", list, err)
+	}
+
+	list, err = metadata_db.ListAssets(&metadata_db.AssetListFilter{Query: "\"holiday budget\" OR holiday", Count: 10})
+	if err != nil || len(list.Items) != 2 || list.Items[0].Name != "report.pdf" {
+		t.Errorf("Expected best match first: %v, %v", list, err)
+	}
+
+	_, err = metadata_db.ListAssets(&metadata_db.AssetListFilter{Sort: "unknown", Count: 10})
+	var filterErr *metadata_db.FilterError
+	if !errors.As(err, &filterErr) || filterErr.Field != "Sort" {
+		t.Errorf("Expected FilterError for unknown sort order, got %v", err)
+	}
+
+	checkFacets(t)
+	checkPathTree(t)
+	checkPathBrowsing(t)
+	checkTimeline(t)
+	checkSimilarImages(t)
+	checkGeo(t)
+	checkTrash(t)
+	checkRelations(t)
+	checkFileTimeZone(t)
+}
+
+func TestParseSize(t *testing.T) {
+
+	tests := map[string]int64{
+		"1024":  1024,
+		"500K":  500 * 1024,
+		"10mb":  10 * 1024 * 1024,
+		"1.5G":  1536 * 1024 * 1024,
+		"200 B": 200,
+	}
+
+	for in, expected := range tests {
+		result, err := metadata_db.ParseSize(in)
+		if err != nil || result != expected {
+			t.Errorf("ParseSize(%q) = %d (%v), expected %d", in, result, err, expected)
+		}
+	}
+
+	if _, err := metadata_db.ParseSize("big"); err == nil {
+		t.Errorf("Expected error for invalid size")
+	}
+}
+
+func expectFilterCount(t *testing.T, filter *metadata_db.AssetListFilter, count int) {
+	filter.Count = 10
+	list, err := metadata_db.ListAssets(filter)
+	if err != nil {
+		t.Errorf("Filter %+v failed: %s", filter, err)
+		return
+	}
+	if len(list.Items) != count {
+		t.Errorf("Filter %+v: expected %d results, got %d", filter, count, len(list.Items))
+	}
+}
+
+func expectQueryCount(t *testing.T, query string, count int) {
+	list, err := metadata_db.ListAssets(&metadata_db.AssetListFilter{Query: query, Count: 10})
+	if err != nil {
+		t.Errorf("Query %q failed: %s", query, err)
+		return
+	}
+	if len(list.Items) != count || list.Total != count {
+		t.Errorf("Query %q: expected %d results, got %d (total %d)", query, count, len(list.Items), list.Total)
+	}
+}
+
+func addTestAsset(t *testing.T, hash string, name string, path string, description string, tags []string) {
+	meta := metadata.CreateNew(hash, "image/jpeg", 1000, name, path, "anna", time.Now())
+	meta.Description = description
+	meta.Tags = tags
+	if err := metadata_db_entity.AddMetaData(meta); err != nil {
+		t.Fatalf("Failed to add meta-data: %s", err)
+	}
+}
+
+func expectCount(t *testing.T, query string, count int) {
+	finderQuery, err := metadata_db.FinderByQuery{}.Find(query)
+	if err != nil {
+		t.Errorf("Query %q failed: %s", query, err)
+		return
+	}
+	found, err := finderQuery.Count()
+	if err != nil {
+		t.Errorf("Query %q failed: %s", query, err)
+		return
+	}
+	if found != count {
+		t.Errorf("Query %q: expected %d results, got %d", query, count, found)
+	}
+}
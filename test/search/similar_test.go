@@ -0,0 +1,51 @@
+package search_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/c8121/asset-storage/internal/metadata"
+	metadata_db "github.com/c8121/asset-storage/internal/metadata-db"
+	metadata_db_entity "github.com/c8121/asset-storage/internal/metadata-db-entity"
+)
+
+// checkSimilarImages expects the test assets of TestSearchIndex, adds three images with image hashes
+func checkSimilarImages(t *testing.T) {
+
+	imageHashes := map[string]string{
+		"aa05": "f0f0f0f0f0f0f0f0",
+		"aa06": "f0f0f0f0f0f0f0f7", //3 bits different
+		"aa07": "0f0f0f0f0f0f0f0f",
+	}
+	for hash, imageHash := range imageHashes {
+		meta := metadata.CreateNew(hash, "image/jpeg", 1000, hash+".jpg", "/home/anna/Similar", "anna", time.Now())
+		meta.ImageHash = imageHash
+		if err := metadata_db_entity.AddMetaData(meta); err != nil {
+			t.Fatalf("Failed to add meta-data: %s", err)
+		}
+	}
+
+	expectQueryCount(t, "similar:aa05", 2)
+	expectQueryCount(t, "similar:aa05/2", 1)
+	expectQueryCount(t, "similar:aa07", 1)
+	expectQueryCount(t, "similar:aa01", 0) //No image hash
+
+	list, err := metadata_db.ListAssets(&metadata_db.AssetListFilter{SimilarTo: "aa06", Count: 10})
+	if err != nil || list.Total != 2 || list.Items[0].Hash != "aa06" {
+		t.Errorf("Unexpected similar images: %+v, %v", list, err)
+	}
+
+	if _, err = metadata_db.ListAssets(&metadata_db.AssetListFilter{SimilarTo: "aa06/x"}); err == nil {
+		t.Errorf("Expected error for invalid distance")
+	}
+
+	groups, err := metadata_db.FindSimilarImageGroups(metadata_db.DefaultImageHashDistance)
+	if err != nil || len(groups) != 1 || len(groups[0].Items) != 2 {
+		t.Errorf("Unexpected groups: %+v, %v", groups, err)
+	}
+
+	groups, err = metadata_db.FindSimilarImageGroups(64)
+	if err != nil || len(groups) != 1 || len(groups[0].Items) != 3 {
+		t.Errorf("Unexpected groups: %+v, %v", groups, err)
+	}
+}
@@ -0,0 +1,52 @@
+package search_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/c8121/asset-storage/internal/metadata"
+	metadata_db "github.com/c8121/asset-storage/internal/metadata-db"
+	metadata_db_entity "github.com/c8121/asset-storage/internal/metadata-db-entity"
+)
+
+// checkTimeline expects the test assets of TestSearchIndex, adds an asset captured in 2015
+func checkTimeline(t *testing.T) {
+
+	//Capture times are compared in UTC
+	now := time.Now().UTC()
+	meta := metadata.CreateNew("aa04", "image/jpeg", 1000, "IMG_0003.jpg", "/home/anna/Photos/2015", "anna", time.Now())
+	meta.CaptureTime = time.Date(2015, now.Month(), now.Day(), 12, 0, 0, 0, time.UTC)
+	if err := metadata_db_entity.AddMetaData(meta); err != nil {
+		t.Fatalf("Failed to add meta-data: %s", err)
+	}
+
+	buckets, err := metadata_db.ListTimeline(&metadata_db.AssetListFilter{}, metadata_db.GranularityYear)
+	if err != nil {
+		t.Fatalf("ListTimeline failed: %s", err)
+	}
+	if len(buckets) != 2 || buckets[0].Count != 3 || buckets[1].Period != "2015" || buckets[1].Count != 1 {
+		t.Errorf("Unexpected timeline: %v", buckets)
+	}
+
+	buckets, err = metadata_db.ListTimeline(&metadata_db.AssetListFilter{Query: "tag:beach"}, metadata_db.GranularityDay)
+	if err != nil || len(buckets) != 1 || len(buckets[0].Period) != 10 || buckets[0].Count != 1 {
+		t.Errorf("Unexpected timeline: %v, %v", buckets, err)
+	}
+
+	if _, err = metadata_db.ListTimeline(&metadata_db.AssetListFilter{}, "week"); err == nil {
+		t.Errorf("Expected error for unknown granularity")
+	}
+
+	list, err := metadata_db.ListOnThisDay(&metadata_db.AssetListFilter{Count: 10}, time.Now())
+	if err != nil || list.Total != 1 || list.Items[0].Hash != "aa04" || list.Items[0].CaptureTime.Year() != 2015 {
+		t.Errorf("Unexpected on this day: %+v, %v", list, err)
+	}
+
+	list, err = metadata_db.ListOnThisDay(&metadata_db.AssetListFilter{Owner: "bob", Count: 10}, time.Now())
+	if err != nil || list.Total != 0 {
+		t.Errorf("Unexpected on this day: %+v, %v", list, err)
+	}
+
+	expectQueryCount(t, "taken:2015", 1)
+	expectQueryCount(t, "date:2015", 0)
+}
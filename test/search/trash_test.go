@@ -0,0 +1,71 @@
+package search_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/c8121/asset-storage/internal/metadata"
+	metadata_db "github.com/c8121/asset-storage/internal/metadata-db"
+	metadata_db_entity "github.com/c8121/asset-storage/internal/metadata-db-entity"
+)
+
+// checkTrash expects the test assets of TestSearchIndex (nine until now), adds a rated and a deleted image
+func checkTrash(t *testing.T) {
+
+	rated := metadata.CreateNew("aa10", "image/jpeg", 1000, "rated.jpg", "/home/anna/Photos", "anna", time.Now())
+	rated.Rating = 4
+	deleted := metadata.CreateNew("aa11", "image/jpeg", 1000, "deleted.jpg", "/home/anna/Photos", "anna", time.Now())
+	deleted.Rating = 5
+	deleted.Deleted = true
+	for _, meta := range []*metadata.JsonAssetMetaData{rated, deleted} {
+		if err := metadata_db_entity.AddMetaData(meta); err != nil {
+			t.Fatalf("Failed to add meta-data: %s", err)
+		}
+	}
+
+	expectQueryCount(t, "rating:4", 1)
+	expectQueryCount(t, "rating:5", 0)
+	expectQueryCount(t, "name:deleted.jpg", 0)
+	expectQueryCount(t, "-rating:1", 9)
+	expectFilterCount(t, &metadata_db.AssetListFilter{MinRating: 1}, 1)
+	expectFilterCount(t, &metadata_db.AssetListFilter{Deleted: true}, 1)
+	expectFilterCount(t, &metadata_db.AssetListFilter{Deleted: true, Query: "rating:5"}, 1)
+	expectFilterCount(t, &metadata_db.AssetListFilter{Deleted: true, Query: "-rating:5"}, 0)
+
+	list, err := metadata_db.ListAssets(&metadata_db.AssetListFilter{Count: 100})
+	if err != nil || list.Total != 10 {
+		t.Errorf("Expected deleted asset to be hidden: %v, %v", list, err)
+	}
+
+	facets, err := metadata_db.ListFacets(&metadata_db.AssetListFilter{})
+	if err != nil || facets.Total != 10 {
+		t.Errorf("Expected deleted asset not to be counted: %v, %v", facets, err)
+	}
+
+	//Paths do not count deleted assets
+	anna, err := metadata_db_entity.GetPathItem("/home/anna", false)
+	if err != nil {
+		t.Fatalf("Path not found: %s", err)
+	}
+	photos, err := metadata_db_entity.GetPathItem("/home/anna/Photos", false)
+	if err != nil {
+		t.Fatalf("Path not found: %s", err)
+	}
+	below, err := metadata_db.ListAssets(&metadata_db.AssetListFilter{PathTree: photos.Id, Count: 100})
+	if err != nil {
+		t.Fatalf("ListAssets failed: %s", err)
+	}
+	items, err := metadata_db.ListPathItems(anna.Id, 0, 100)
+	if err != nil {
+		t.Fatalf("ListPathItems failed: %s", err)
+	}
+	for _, item := range items {
+		if item.Id == photos.Id && item.AssetCount != below.Total {
+			t.Errorf("Expected %d assets below %s, got %d", below.Total, item.Name, item.AssetCount)
+		}
+	}
+
+	if _, err := metadata_db.ListAssets(&metadata_db.AssetListFilter{Query: "rating:high", Count: 10}); err == nil {
+		t.Errorf("Expected error for invalid rating")
+	}
+}
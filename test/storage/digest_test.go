@@ -0,0 +1,81 @@
+package storage_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/c8121/asset-storage/internal/storage"
+)
+
+func TestComputeDigests(t *testing.T) {
+
+	digests, err := storage.ComputeDigests(strings.NewReader(""))
+	if err != nil {
+		t.Fatalf("ComputeDigests failed: %s", err)
+	}
+
+	expected := map[string]string{
+		storage.DigestMD5:    "d41d8cd98f00b204e9800998ecf8427e",
+		storage.DigestSHA1:   "da39a3ee5e6b4b0d3255bfef95601890afd80709",
+		storage.DigestSHA256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		storage.DigestBLAKE3: "af1349b9f5f9a1a6a0404dea36dcc9499bcb25c9adc112b7cc9a93cae41f3262",
+	}
+	for algorithm, digest := range expected {
+		if digests[algorithm] != digest {
+			t.Errorf("%s: expected %s, got %s", algorithm, digest, digests[algorithm])
+		}
+	}
+}
+
+func TestParseManifest(t *testing.T) {
+
+	manifest := "# Delivery 42\n" +
+		"6F5902AC237024BDD0C176CB93063DC4  hello.txt\n" +
+		"22596363b3de40b06f981fb85d82312e8c0ed511 *images/photo 1.jpg\r\n" +
+		"\n" +
+		"a948904f2f0f479b8f8197694b30184b0d2ed1c1cd2a1ec0fb85d299a192a447  a.bin\n" +
+		"BLAKE3 (b.bin) = af1349b9f5f9a1a6a0404dea36dcc9499bcb25c9adc112b7cc9a93cae41f3262\n" +
+		"\\d41d8cd98f00b204e9800998ecf8427e  back\\\\slash\n"
+
+	entries, err := storage.ParseManifest(strings.NewReader(manifest), "")
+	if err != nil {
+		t.Fatalf("ParseManifest failed: %s", err)
+	}
+
+	expected := []struct {
+		line       int
+		algorithms string
+		name       string
+	}{
+		{2, "md5", "hello.txt"},
+		{3, "sha1", "images/photo 1.jpg"},
+		{5, "sha256,blake3", "a.bin"},
+		{6, "blake3", "b.bin"},
+		{7, "md5", "back\\slash"},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("Expected %d entries, got %+v", len(expected), entries)
+	}
+	for i, e := range expected {
+		entry := entries[i]
+		if entry.Line != e.line || strings.Join(entry.Algorithms, ",") != e.algorithms || entry.Name != e.name ||
+			entry.Digest != strings.ToLower(entry.Digest) {
+			t.Errorf("Expected %+v, got %+v", e, entry)
+		}
+	}
+
+	//Given algorithm is used for untagged lines
+	entries, err = storage.ParseManifest(strings.NewReader("a948904f2f0f479b8f8197694b30184b0d2ed1c1cd2a1ec0fb85d299a192a447  a.bin\n"), storage.DigestBLAKE3)
+	if err != nil || len(entries) != 1 || len(entries[0].Algorithms) != 1 || entries[0].Algorithms[0] != storage.DigestBLAKE3 {
+		t.Errorf("Unexpected entries: %+v, %v", entries, err)
+	}
+
+	for _, invalid := range []string{"xyz  a.txt\n", "abc  a.txt\n", "6f5902ac237024bdd0c176cb93063dc4 a.txt\n"} {
+		if _, err = storage.ParseManifest(strings.NewReader(invalid), ""); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+	if _, err = storage.ParseManifest(strings.NewReader("6f5902ac237024bdd0c176cb93063dc4  a.txt\n"), storage.DigestSHA1); err == nil {
+		t.Errorf("Expected error for digest length not matching algorithm")
+	}
+}
@@ -0,0 +1,75 @@
+package storage_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/c8121/asset-storage/internal/storage"
+)
+
+const (
+	docxMimeType = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+)
+
+func TestDetectMimeType(t *testing.T) {
+
+	plainZip := zipFile(t, map[string]int{"readme.txt": 100})
+	tests := []struct {
+		head     []byte
+		name     string
+		expected string
+	}{
+		{plainZip, "archive.zip", "application/zip"},
+		{plainZip, "letter.docx", docxMimeType},
+		{plainZip, "photo.jpg", "application/zip"},
+		{plainZip, "", "application/zip"},
+		{[]byte("a,b\n1,2\n"), "table.csv", "text/csv"},
+		{[]byte("hello world\n"), "notes.txt", "text/plain; charset=utf-8"},
+		{[]byte("hello world\n"), "notes.zip", "text/plain; charset=utf-8"},
+		{[]byte{0x01, 0x02, 0x03, 0x04}, "movie.mkv", "video/x-matroska"},
+		{[]byte{0x01, 0x02, 0x03, 0x04}, "", "application/octet-stream"},
+		{[]byte("From: anna@example.com\n\nHello\n"), "mail.eml", storage.MessageMimeType},
+		{[]byte("From anna@example.com Mon Jan  2 15:04:05 2006\nFrom: anna@example.com\n\nHello\n"), "Inbox", storage.MboxMimeType},
+	}
+
+	for _, test := range tests {
+		if result := storage.DetectMimeType(test.head, test.name); result != test.expected {
+			t.Errorf("DetectMimeType(..., %q) = %q, expected %q", test.name, result, test.expected)
+		}
+	}
+
+	//Office documents are detected without extension, even if entries are not in the first kilobytes
+	docx := zipFile(t, map[string]int{"[Content_Types].xml": 20000, "word/document.xml": 100})
+	if result := storage.DetectMimeType(docx, ""); result != docxMimeType {
+		t.Errorf("Expected docx to be detected from content, got %q", result)
+	}
+}
+
+// zipFile creates an uncompressed zip file with files of given sizes, in order of names
+func zipFile(t *testing.T, files map[string]int) []byte {
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names) //"[Content_Types].xml" before "word/..."
+
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for _, name := range names {
+		w, err := writer.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = w.Write([]byte(strings.Repeat("x", files[name]))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,123 @@
+package takeout_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/c8121/asset-storage/internal/config"
+	"github.com/c8121/asset-storage/internal/metadata"
+	"github.com/c8121/asset-storage/internal/takeout"
+)
+
+func TestFindSidecar(t *testing.T) {
+
+	dir := t.TempDir()
+	longName := strings.Repeat("x", 50) + ".jpg"
+	supplementalName := strings.Repeat("e", 30) + ".jpg"
+
+	//Media file -> sidecar file
+	tests := map[string]string{
+		"a.jpg":          "a.jpg.json",
+		"b.jpg":          "b.jpg.supplemental-metadata.json",
+		"c(1).jpg":       "c.jpg(1).json",
+		"d-edited.jpg":   "d.jpg.json",
+		longName:         longName[:takeout.MaxSidecarNameLength] + ".json",
+		supplementalName: (supplementalName + takeout.SupplementalSuffix)[:takeout.MaxSidecarNameLength] + ".json",
+		"no-sidecar.jpg": "",
+	}
+
+	for media, sidecar := range tests {
+		writeFile(t, filepath.Join(dir, media), "media")
+		if sidecar != "" {
+			writeFile(t, filepath.Join(dir, sidecar), `{"title":"`+media+`","photoTakenTime":{"timestamp":"1593000000"}}`)
+		}
+	}
+
+	for media, sidecar := range tests {
+		found, err := takeout.FindSidecar(filepath.Join(dir, media))
+		if err != nil {
+			t.Errorf("FindSidecar(%q) failed: %s", media, err)
+		} else if sidecar == "" && found != nil {
+			t.Errorf("FindSidecar(%q): expected no sidecar, got %+v", media, found)
+		} else if sidecar != "" && (found == nil || found.Title != media) {
+			t.Errorf("FindSidecar(%q): expected %q, got %+v", media, sidecar, found)
+		}
+		if sidecar != "" && !takeout.IsSidecar(filepath.Join(dir, sidecar)) {
+			t.Errorf("IsSidecar(%q) = false", sidecar)
+		}
+		if takeout.IsSidecar(filepath.Join(dir, media)) {
+			t.Errorf("IsSidecar(%q) = true", media)
+		}
+	}
+
+	writeFile(t, filepath.Join(dir, "other.json"), `{"title":"Not a sidecar"}`)
+	if takeout.IsSidecar(filepath.Join(dir, "other.json")) {
+		t.Errorf("Expected other JSON not to be a sidecar")
+	}
+}
+
+func TestAlbum(t *testing.T) {
+
+	dir := t.TempDir()
+	if album, err := takeout.LoadAlbum(dir); album != nil || err != nil {
+		t.Errorf("Expected no album: %+v, %v", album, err)
+	}
+
+	writeFile(t, filepath.Join(dir, takeout.AlbumFileName), `{"title":"Summer Trip","description":"Lisbon"}`)
+	album, err := takeout.LoadAlbum(dir)
+	if err != nil || album == nil || album.Title != "Summer Trip" || album.Description != "Lisbon" {
+		t.Errorf("Unexpected album: %+v, %v", album, err)
+	}
+	if !takeout.IsSidecar(filepath.Join(dir, takeout.AlbumFileName)) {
+		t.Errorf("Expected album meta-data not to be added")
+	}
+}
+
+func TestApply(t *testing.T) {
+
+	config.AssetMetaDataBaseDir = t.TempDir()
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.jpg.json"), `{"title":"a.jpg","description":"Beach",
+		"photoTakenTime":{"timestamp":"1593000000"},"creationTime":{"timestamp":"1600000000"},
+		"geoData":{"latitude":0.0,"longitude":0.0},"geoDataExif":{"latitude":38.71,"longitude":-9.13}}`)
+	sidecar, err := takeout.FindSidecar(filepath.Join(dir, "a.jpg"))
+	if err != nil || sidecar == nil {
+		t.Fatalf("FindSidecar failed: %v, %v", sidecar, err)
+	}
+	if !sidecar.TakenTime().Equal(time.Unix(1593000000, 0)) {
+		t.Errorf("Unexpected taken time: %s", sidecar.TakenTime())
+	}
+
+	meta := metadata.CreateNew("ab0123456789", "image/jpeg", 1000, "a.jpg", dir, "anna", time.Now())
+	meta.Description = "From EXIF"
+	if err = meta.Save(metadata.GetMetaDataFilePath(meta.Hash)); err != nil {
+		t.Fatalf("Save failed: %s", err)
+	}
+	if meta, err = takeout.Apply(meta.Hash, sidecar); err != nil {
+		t.Fatalf("Apply failed: %s", err)
+	}
+	if !meta.CaptureTime.Equal(time.Unix(1593000000, 0)) || meta.Location == nil || meta.Location.Latitude != 38.71 || meta.Description != "From EXIF" {
+		t.Errorf("Unexpected meta-data: %+v", meta)
+	}
+
+	saved, err := metadata.LoadByHash(meta.Hash)
+	if err != nil || saved.Location == nil {
+		t.Errorf("Expected meta-data to be saved: %+v, %v", saved, err)
+	}
+
+	//Changes are recorded in history
+	history, err := metadata.LoadHistory(meta.Hash)
+	if err != nil || len(history) != 2 || history[0].User != metadata.SystemUser {
+		t.Errorf("Expected 2 history entries by %s, got %+v, %v", metadata.SystemUser, history, err)
+	}
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write %s: %s", path, err)
+	}
+}
@@ -0,0 +1,31 @@
+package test_util
+
+import (
+	"database/sql"
+	"testing"
+
+	metadata_db "github.com/c8121/asset-storage/internal/metadata-db"
+	metadata_db_entity "github.com/c8121/asset-storage/internal/metadata-db-entity"
+	"github.com/c8121/asset-storage/internal/util"
+	_ "modernc.org/sqlite"
+)
+
+// CreateDb opens an in-memory database with all entities created, closed when the test ends
+func CreateDb(t *testing.T) *sql.DB {
+
+	db := OpenDb(t)
+	metadata_db_entity.AutoCreateEntities()
+	return db
+}
+
+// OpenDb opens an empty in-memory database (without entities), closed when the test ends
+func OpenDb(t *testing.T) *sql.DB {
+
+	db, err := sql.Open("sqlite", "file::memory:")
+	util.PanicOnError(err, "Failed to open sqlite database")
+	t.Cleanup(func() { util.CloseOrLog(db) })
+	db.SetMaxOpenConns(1) //Each connection would open its own in-memory database
+
+	metadata_db.SetDatabase(db)
+	return db
+}
@@ -0,0 +1,24 @@
+package test_util
+
+import (
+	"testing"
+
+	"github.com/c8121/asset-storage/internal/metadata"
+	metadata_db "github.com/c8121/asset-storage/internal/metadata-db"
+	metadata_db_entity "github.com/c8121/asset-storage/internal/metadata-db-entity"
+)
+
+// UpdateDb adds or updates meta-data in the database, fails the test on error
+func UpdateDb(t *testing.T, meta *metadata.JsonAssetMetaData) {
+	if err := metadata_db_entity.AddMetaData(meta); err != nil {
+		t.Fatalf("Failed to add meta-data to database: %s", err)
+	}
+}
+
+// ExpectCount checks the number of assets listed for a query
+func ExpectCount(t *testing.T, query string, count int) {
+	list, err := metadata_db.ListAssets(&metadata_db.AssetListFilter{Query: query, Count: 10})
+	if err != nil || len(list.Items) != count {
+		t.Errorf("Query %q: expected %d results, got %+v, %v", query, count, list, err)
+	}
+}
@@ -0,0 +1,94 @@
+package text_extractor_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"testing"
+
+	text_extractor "github.com/c8121/asset-storage/internal/text-extractor"
+)
+
+func TestPlainText(t *testing.T) {
+
+	e := text_extractor.NewPlainTextExtractor()
+
+	text, err := e.Extract(bytes.NewReader([]byte("\xef\xbb\xbfHello, World")), "text/plain")
+	expectText(t, text, err, "Hello, World")
+
+	text, err = e.Extract(bytes.NewReader([]byte("Gr\xfc\xdfe")), "text/plain")
+	expectText(t, text, err, "Grüße")
+
+	//UTF-8 cut within the last rune is still UTF-8
+	defer func(max int) { text_extractor.MaxTextLength = max }(text_extractor.MaxTextLength)
+	text_extractor.MaxTextLength = 4
+	text, err = e.Extract(bytes.NewReader([]byte("Grüße")), "text/plain")
+	expectText(t, text, err, "Grü")
+	text_extractor.MaxTextLength = 3
+	text, err = e.Extract(bytes.NewReader([]byte("Grüße")), "text/plain")
+	expectText(t, text, err, "Gr")
+}
+
+func TestOfficeXml(t *testing.T) {
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	f, _ := w.Create("word/document.xml")
+	_, _ = f.Write([]byte(`<?xml version="1.0"?>` +
+		`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		`<w:p><w:r><w:t>Holiday</w:t></w:r><w:r><w:t xml:space="preserve"> report</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>Lisbon 2019</w:t></w:r></w:p>` +
+		`</w:body></w:document>`))
+	_ = w.Close()
+
+	e := text_extractor.NewOfficeXmlExtractor()
+	text, err := e.Extract(bytes.NewReader(buf.Bytes()),
+		"application/vnd.openxmlformats-officedocument.wordprocessingml.document")
+	expectText(t, text, err, "Holiday report\nLisbon 2019")
+}
+
+func TestNativePdf(t *testing.T) {
+
+	content := "BT /F1 12 Tf 72 712 Td (Holiday report) Tj 0 -14 Td [(Lis) -10 (bon) -300 (2019)] TJ ET"
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	_, _ = zw.Write([]byte(content))
+	_ = zw.Close()
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n")
+	pdf.WriteString("1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj\n")
+	pdf.WriteString(fmt.Sprintf("4 0 obj << /Length %d /Filter /FlateDecode >>\nstream\n", compressed.Len()))
+	pdf.Write(compressed.Bytes())
+	pdf.WriteString("\nendstream\nendobj\n%%EOF\n")
+
+	e := text_extractor.NewNativePdfExtractor()
+	text, err := e.Extract(bytes.NewReader(pdf.Bytes()), "application/pdf")
+	expectText(t, text, err, "Holiday report\nLisbon 2019")
+}
+
+func TestExtractorsByMimeType(t *testing.T) {
+
+	if len(text_extractor.GetExtractorsByMimeType("application/pdf")) < 2 {
+		t.Errorf("Expected at least two PDF extractors")
+	}
+	if len(text_extractor.GetExtractorsByMimeType("text/plain; charset=utf-8")) != 1 {
+		t.Errorf("Expected one plain text extractor")
+	}
+	if len(text_extractor.GetExtractorsByMimeType("image/jpeg")) != 0 {
+		t.Errorf("Expected no extractor for images")
+	}
+}
+
+func expectText(t *testing.T, text string, err error, expected string) {
+	if err != nil {
+		t.Errorf("Extract failed: %s", err)
+		return
+	}
+	if strings.TrimSpace(text) != expected {
+		t.Errorf("Extracted %q, expected %q", text, expected)
+	}
+}
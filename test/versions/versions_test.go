@@ -0,0 +1,147 @@
+package versions_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/c8121/asset-storage/internal/config"
+	"github.com/c8121/asset-storage/internal/metadata"
+	metadata_db "github.com/c8121/asset-storage/internal/metadata-db"
+	test_util "github.com/c8121/asset-storage/test/test-util"
+)
+
+const (
+	hashA = "a10123456789abcdef0123456789abcdef0123456789abcdef0123456789abcd" //Original
+	hashB = "b20123456789abcdef0123456789abcdef0123456789abcdef0123456789abcd" //Derived from A
+	hashC = "c30123456789abcdef0123456789abcdef0123456789abcdef0123456789abcd" //Derived from B
+	hashD = "d40123456789abcdef0123456789abcdef0123456789abcdef0123456789abcd" //Derived from A
+	hashE = "e50123456789abcdef0123456789abcdef0123456789abcdef0123456789abcd" //Unrelated
+)
+
+func TestVersions(t *testing.T) {
+
+	config.AssetMetaDataBaseDir = t.TempDir()
+
+	test_util.CreateDb(t)
+
+	fileTime := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, hash := range []string{hashA, hashB, hashC, hashD, hashE} {
+		meta, err := metadata.AddMetaData(hash, "image/jpeg", 1000, "IMG.jpg", "/home/anna", "anna", fileTime.Add(time.Duration(i)*time.Hour))
+		if err != nil {
+			t.Fatalf("AddMetaData failed: %s", err)
+		}
+		test_util.UpdateDb(t, meta)
+	}
+
+	setDerivedFrom(t, hashB, hashA)
+	setDerivedFrom(t, hashC, hashB)
+	setDerivedFrom(t, hashD, strings.ToUpper(hashA)) //Not case-sensitive
+
+	//No cycles, original must exist
+	for _, invalid := range [][2]string{{hashA, hashC}, {hashB, hashB}, {hashB, "ff0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcd"}, {hashB, "xyz"}} {
+		if _, err := metadata.UpdateMetaData(invalid[0], derivedFrom(invalid[1]), "anna"); err == nil {
+			t.Errorf("Expected error for %s derived from %s", invalid[0], invalid[1])
+		}
+	}
+
+	//Same lineage from any version
+	for _, hash := range []string{hashA, hashC, hashD} {
+		expectVersions(t, hash, []string{hashA, hashB, hashD, hashC}, []string{hashD, hashC})
+	}
+	expectVersions(t, hashE, []string{hashE}, []string{hashE})
+
+	expectList(t, false, hashA, hashB, hashC, hashD, hashE)
+	expectList(t, true, hashC, hashD, hashE)
+
+	//Version derived from a deleted version supersedes the original
+	setDeleted(t, hashB, true)
+	setDeleted(t, hashD, true)
+	expectVersions(t, hashA, []string{hashA, hashB, hashD, hashC}, []string{hashC})
+	expectList(t, true, hashC, hashE)
+	setDeleted(t, hashB, false)
+	setDeleted(t, hashD, false)
+
+	//Deleted version does not supersede its original
+	setDeleted(t, hashC, true)
+	expectVersions(t, hashA, []string{hashA, hashB, hashD, hashC}, []string{hashB, hashD})
+	expectList(t, true, hashB, hashD, hashE)
+
+	//Removing the relation splits the lineage
+	setDerivedFrom(t, hashD, "")
+	expectVersions(t, hashD, []string{hashD}, []string{hashD})
+	expectList(t, true, hashB, hashD, hashE)
+
+	history, err := metadata.LoadHistory(hashD)
+	if err != nil || len(history) != 5 || history[1].Field != "DerivedFrom" || string(history[4].OldValue) != `"`+hashA+`"` {
+		t.Errorf("Unexpected history: %+v, %v", history, err)
+	}
+}
+
+func derivedFrom(hash string) map[string]json.RawMessage {
+	value, _ := json.Marshal(hash)
+	return map[string]json.RawMessage{"DerivedFrom": value}
+}
+
+func setDerivedFrom(t *testing.T, hash string, original string) {
+	meta, err := metadata.UpdateMetaData(hash, derivedFrom(original), "anna")
+	if err != nil {
+		t.Fatalf("UpdateMetaData failed: %s", err)
+	}
+	test_util.UpdateDb(t, meta)
+}
+
+func setDeleted(t *testing.T, hash string, deleted bool) {
+	value, _ := json.Marshal(deleted)
+	meta, err := metadata.UpdateMetaData(hash, map[string]json.RawMessage{"Deleted": value}, "anna")
+	if err != nil {
+		t.Fatalf("UpdateMetaData failed: %s", err)
+	}
+	test_util.UpdateDb(t, meta)
+}
+
+// expectVersions checks lineage order and latest versions
+func expectVersions(t *testing.T, hash string, hashes []string, latest []string) {
+
+	versions, err := metadata_db.ListVersions(hash)
+	if err != nil || len(versions) != len(hashes) {
+		t.Fatalf("ListVersions(%s): expected %d versions, got %+v, %v", hash, len(hashes), versions, err)
+	}
+
+	found := make([]string, 0)
+	for i, version := range versions {
+		if version.Hash != hashes[i] {
+			t.Errorf("ListVersions(%s): expected %v, got %+v", hash, hashes, versions)
+			return
+		}
+		if version.Latest {
+			found = append(found, version.Hash)
+		}
+	}
+	if len(found) != len(latest) {
+		t.Errorf("ListVersions(%s): expected latest %v, got %v", hash, latest, found)
+		return
+	}
+	for i := range latest {
+		if found[i] != latest[i] {
+			t.Errorf("ListVersions(%s): expected latest %v, got %v", hash, latest, found)
+			return
+		}
+	}
+}
+
+// expectList checks the asset list (oldest first)
+func expectList(t *testing.T, collapse bool, hashes ...string) {
+
+	list, err := metadata_db.ListAssets(&metadata_db.AssetListFilter{CollapseVersions: collapse, Sort: metadata_db.SortTimeAsc, Count: 10})
+	if err != nil || len(list.Items) != len(hashes) || list.Total != len(hashes) {
+		t.Fatalf("Expected %v, got %+v, %v", hashes, list, err)
+	}
+	for i, item := range list.Items {
+		if item.Hash != hashes[i] {
+			t.Errorf("Expected %v, got %+v", hashes, list.Items)
+			return
+		}
+	}
+}
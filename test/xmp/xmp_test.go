@@ -0,0 +1,164 @@
+package xmp_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/c8121/asset-storage/internal/config"
+	"github.com/c8121/asset-storage/internal/metadata"
+	"github.com/c8121/asset-storage/internal/xmp"
+)
+
+const lightroomXmp = "<?xpacket begin=\"\uFEFF\"" + ` id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about=""
+    xmlns:xmp="http://ns.adobe.com/xap/1.0/"
+    xmlns:dc="http://purl.org/dc/elements/1.1/"
+    xmlns:crs="http://ns.adobe.com/camera-raw-settings/1.0/"
+   xmp:Rating="3" crs:Exposure2012="+0.50">
+   <dc:title><rdf:Alt><rdf:li xml:lang="x-default">Sunset &amp; Sea</rdf:li></rdf:Alt></dc:title>
+   <dc:description><rdf:Alt><rdf:li xml:lang="x-default">Evening at the beach</rdf:li></rdf:Alt></dc:description>
+   <dc:subject>
+    <rdf:Bag>
+     <rdf:li>beach</rdf:li>
+     <rdf:li>Sunset</rdf:li>
+    </rdf:Bag>
+   </dc:subject>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>`
+
+const elementXmp = `<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about="" xmlns:xmp="http://ns.adobe.com/xap/1.0/">
+   <xmp:Rating>-1</xmp:Rating>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>`
+
+func TestRead(t *testing.T) {
+
+	x, err := xmp.Read(strings.NewReader(lightroomXmp))
+	if err != nil {
+		t.Fatalf("Read failed: %s", err)
+	}
+	if x.Title != "Sunset & Sea" || x.Description != "Evening at the beach" || x.Rating != 3 ||
+		len(x.Keywords) != 2 || x.Keywords[1] != "Sunset" {
+		t.Errorf("Unexpected XMP: %+v", x)
+	}
+
+	//Rejected
+	if x, err = xmp.Read(strings.NewReader(elementXmp)); err != nil || x.Rating != 0 {
+		t.Errorf("Unexpected XMP: %+v, %v", x, err)
+	}
+
+	if _, err = xmp.Read(strings.NewReader("<html></html>")); err != xmp.ErrNoXmp {
+		t.Errorf("Expected ErrNoXmp, got %v", err)
+	}
+}
+
+func TestWrite(t *testing.T) {
+
+	written := &xmp.Xmp{Title: "<Title>", Description: "Line 1\nLine 2", Keywords: []string{"a & b", "c"}, Rating: 5}
+
+	var buf bytes.Buffer
+	if err := xmp.Write(&buf, written); err != nil {
+		t.Fatalf("Write failed: %s", err)
+	}
+
+	read, err := xmp.Read(&buf)
+	if err != nil {
+		t.Fatalf("Read failed: %s", err)
+	}
+	if read.Title != written.Title || read.Description != written.Description || read.Rating != 5 ||
+		len(read.Keywords) != 2 || read.Keywords[0] != "a & b" {
+		t.Errorf("Unexpected XMP: %+v", read)
+	}
+}
+
+func TestSidecar(t *testing.T) {
+
+	config.AssetMetaDataBaseDir = t.TempDir()
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "IMG_1.CR2"), "raw")
+	writeFile(t, filepath.Join(dir, "IMG_1.xmp"), lightroomXmp)
+	writeFile(t, filepath.Join(dir, "IMG_2.jpg"), "jpeg")
+	writeFile(t, filepath.Join(dir, "IMG_2.jpg.xmp"), lightroomXmp)
+	writeFile(t, filepath.Join(dir, "IMG_3.jpg"), "jpeg")
+	writeFile(t, filepath.Join(dir, "other.xmp"), lightroomXmp)
+
+	tests := map[string]string{
+		"IMG_1.CR2": "IMG_1.xmp",
+		"IMG_2.jpg": "IMG_2.jpg.xmp",
+		"IMG_3.jpg": "",
+		"IMG_1.xmp": "",
+	}
+	for file, expected := range tests {
+		found := xmp.FindSidecar(filepath.Join(dir, file))
+		if (expected == "" && found != "") || (expected != "" && found != filepath.Join(dir, expected)) {
+			t.Errorf("FindSidecar(%q) = %q, expected %q", file, found, expected)
+		}
+	}
+
+	sidecars := map[string]bool{
+		"IMG_1.xmp":     true,
+		"IMG_2.jpg.xmp": true,
+		"other.xmp":     false,
+		"IMG_1.CR2":     false,
+	}
+	for file, expected := range sidecars {
+		if xmp.IsSidecar(filepath.Join(dir, file)) != expected {
+			t.Errorf("IsSidecar(%q) != %v", file, expected)
+		}
+	}
+
+	x, err := xmp.ReadFile(filepath.Join(dir, "IMG_1.xmp"))
+	if err != nil {
+		t.Fatalf("ReadFile failed: %s", err)
+	}
+
+	meta := metadata.CreateNew("ab0123456789", "image/x-canon-cr2", 1000, "IMG_1.CR2", dir, "anna", time.Now())
+	meta.Tags = []string{"sunset", "holiday"}
+	meta.Rating = 5
+	if err = meta.Save(metadata.GetMetaDataFilePath(meta.Hash)); err != nil {
+		t.Fatalf("Save failed: %s", err)
+	}
+	if meta, err = xmp.Apply(meta.Hash, x); err != nil {
+		t.Fatalf("Apply failed: %s", err)
+	}
+	if len(meta.Tags) != 3 || meta.Rating != 5 || meta.Title != "Sunset & Sea" || meta.Description != "Evening at the beach" {
+		t.Errorf("Unexpected meta-data: %+v", meta)
+	}
+
+	saved, err := metadata.LoadByHash(meta.Hash)
+	if err != nil || saved.Title != meta.Title {
+		t.Errorf("Expected meta-data to be saved: %+v, %v", saved, err)
+	}
+
+	//Changes are recorded in history
+	history, err := metadata.LoadHistory(meta.Hash)
+	if err != nil || len(history) != 3 || history[0].User != metadata.SystemUser {
+		t.Errorf("Expected 3 history entries by %s, got %+v, %v", metadata.SystemUser, history, err)
+	}
+
+	//Nothing changed when applied again
+	if _, err = xmp.Apply(meta.Hash, x); err != nil {
+		t.Fatalf("Apply failed: %s", err)
+	}
+	if history, _ = metadata.LoadHistory(meta.Hash); len(history) != 3 {
+		t.Errorf("Expected no more history entries, got %+v", history)
+	}
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write %s: %s", path, err)
+	}
+}
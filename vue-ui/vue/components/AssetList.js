@@ -43,6 +43,7 @@
                                     <input id="page" v-model="page" class="form-control w-auto border-light-subtle text-center" style="width: 50px !important" @changed="pageChanged" @keyup.enter="pageChanged">
                                     <button v-if="page>1" class="btn btn-outline-secondary border-light-subtle" @click="page-=(page>1?1:0);pageChanged()">&lt;</button>
                                     <button v-if="showLoadMore" class="btn btn-outline-secondary border-light-subtle" @click="page++;pageChanged()">&gt;</button>
+                                    <span class="input-group-text border-light-subtle">{{ total }} assets</span>
                                 </div>
                             </div>
                             <div class="col-auto">
@@ -59,13 +60,14 @@
                                         <option value="video/*">Video</option>
                                         <option value="application/pdf">PDF</option>
                                         <option :value="null"> - </option>
-                                        <option v-for="t in mimeTypes" :value="t.Id.toString()">{{t.Name}}</option>
+                                        <option v-for="t in mimeTypes" :value="t.Id.toString()">{{t.Value}} ({{t.Count}})</option>
                                     </select>
                                 </div>
                             </div>
                             <div class="col-auto">
                                 <div class="input-group input-group-sm">
                                     <input id="findName" v-model="findName" class="form-control w-auto border-light-subtle border-end-0"
+                                            placeholder="e.g. tag:beach -path:tmp"
                                             @keyup.enter="findByName">
                                     <button v-if="findName" class="btn btn-sm btn-outline-secondary border-light-subtle border-start-0"
                                             @click="clearFindFilter">x</button>
@@ -139,6 +141,7 @@
         data() {
             return {
                 list: [],
+                total: 0,
 
                 offset: 0,
                 count: 30,
@@ -164,7 +167,8 @@
             loadDataObject() {
                 const self = this;
                 self.loadAssetList();
-                self.loadMimeTypes();
+                if (offset === 0)
+                    self.loadMimeTypes();
             },
 
             loadAssetList() {
@@ -173,8 +177,9 @@
 
                 client.post('/assets/list', self.getListFilter()).then((json) => {
                     if (json) {
-                        self.list = json;
-                        self.showLoadMore = json.length >= self.count
+                        self.list = json.Items;
+                        self.total = json.Total;
+                        self.showLoadMore = self.offset + json.Items.length < json.Total
                         self.page = (self.offset + self.count) / self.count;
                         self.createGroupKeys(self.list);
                     }
@@ -188,6 +193,8 @@
                 self.list.splice(0, self.list.length);
                 self.offset = offset;
                 self.loadAssetList();
+                if (offset === 0)
+                    self.loadMimeTypes();
             },
 
             loadMore() {
@@ -199,10 +206,11 @@
 
                 client.post('/assets/list', filter).then((json) => {
                     if (json) {
-                        self.showLoadMore = json.length > 0
+                        self.total = json.Total;
+                        self.showLoadMore = self.offset + json.Items.length < json.Total
                         self.page = (self.offset + self.count) / self.count;
-                        self.createGroupKeys(json);
-                        for (const item of json)
+                        self.createGroupKeys(json.Items);
+                        for (const item of json.Items)
                             self.list.push(item)
                     }
                     self.loading = false;
@@ -237,9 +245,9 @@
                     Offset: self.offset,
                     Count: self.count,
                     MimeType: self.type,
-                    FileName: self.findName,
+                    Query: self.findName,
                     //PathName: self.findName,
-                    PathId: self.pathItem,
+                    PathTree: self.pathItem,
                     Face: self.face
                 };
             },
@@ -264,6 +272,10 @@
                 self.pathItem = item.Id;
                 self.reloadAssetList(0);
                 self.pathButtonText = item.Name;
+                client.get('/pathitems/path/' + item.Id).then((json) => {
+                    if (json && self.pathItem === json.Id)
+                        self.pathButtonText = json.FullPath;
+                });
             },
 
             clearPathFilter() {
@@ -362,13 +374,17 @@
                 }
             },
 
+            //Mime-types with counts of assets matching the current filter (ignoring the selected type)
             loadMimeTypes() {
                 const self = this;
 
-                client.get("/mimetypes/list").then((json) => {
+                const filter = self.getListFilter();
+                filter.MimeType = null;
+
+                client.post("/assets/facets", filter).then((json) => {
                     if (json) {
                         self.mimeTypes.splice(0, self.mimeTypes.length);
-                        for (const item of json) {
+                        for (const item of json.MimeType) {
                             self.mimeTypes.push(item);
                         }
                     }
@@ -11,7 +11,7 @@
                     <div v-if="value">
 
                         <div>
-                            <span class="text-primary">{{ value.Origins[0].Name }}</span>
+                            <span class="text-primary">{{ value.Name || value.Origins[0].Name }}</span>
                             <span class="text-secondary ps-3 text-nowrap">{{ value.MimeType }}</span>
                         </div>
 
@@ -10,12 +10,12 @@
                 <div class="toast-body">
                     <div v-if="value">
                         <div @click="onFileClick()" role="button">
-                            <span class="text-primary">{{ value.Origins[0].Name }}</span>
+                            <span class="text-primary">{{ value.Name || value.Origins[0].Name }}</span>
                             <span class="text-secondary ps-3 text-nowrap">{{ value.MimeType }}</span>
                         </div>
 
                         <div class="mt-3">
-                            <div v-for="origin in value.Origins" class="border-top border-light-subtle p-1 mb-1">
+                            <div v-for="origin in value.Origins.filter(o => !o.Hidden)" class="border-top border-light-subtle p-1 mb-1">
                                 <a
                                         @click="onFileClick()"
                                         role="button">
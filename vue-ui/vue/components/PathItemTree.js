@@ -13,11 +13,15 @@
                         <button @click="itemClicked(item)">
                             <span v-if="!item.Name">({{ item.Id }})</span>{{ item.Name }}
                         </button>
+                        <small class="text-secondary">{{ item.AssetCount }}</small>
                     </div>
                     
                     <PathItemTree ref="childTrees" v-if="item.showChildren" :value="item.Id"
                             @click="itemClicked"></PathItemTree>
                 </li>
+                <li v-if="showLoadMore">
+                    <button @click="loadMore">...</button>
+                </li>
             </ul>`,
 
         props: {
@@ -27,7 +31,9 @@
         data() {
             return {
                 parentId: 0,
-                list: []
+                list: [],
+                count: 1000,
+                showLoadMore: false
             }
         },
         methods: {
@@ -42,12 +48,13 @@
                         showChildren.push(item.Id);
                 }
 
-                client.get('/pathitems/list/' + self.parentId).then((json) => {
+                client.get('/pathitems/list/' + self.parentId + '?count=' + self.count).then((json) => {
                     self.list.splice(0, self.list.length);
                     for (const item of json) {
                         item.showChildren = false;
                         self.list.push(item);
                     }
+                    self.showLoadMore = json.length >= self.count;
 
                     //Update showChildren after populating list to trigger reload
                     Vue.nextTick(() => {
@@ -56,6 +63,17 @@
                     });
                 });
             },
+            loadMore() {
+                const self = this;
+
+                client.get('/pathitems/list/' + self.parentId + '?count=' + self.count + '&offset=' + self.list.length).then((json) => {
+                    for (const item of json) {
+                        item.showChildren = false;
+                        self.list.push(item);
+                    }
+                    self.showLoadMore = json.length >= self.count;
+                });
+            },
             treeItemChanged(item) {
                 const self = this;
 